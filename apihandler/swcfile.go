@@ -0,0 +1,75 @@
+package apihandler
+
+import (
+	"DBMS/SwcDbmsCommon/Generated/go/proto/message"
+	"DBMS/SwcDbmsCommon/Generated/go/proto/request"
+	"DBMS/bll"
+	"DBMS/config"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// exportUserToken takes the user token from an "Authorization: Bearer" header, a UserToken cookie or the UserToken
+// query parameter, in that order.
+func exportUserToken(httpRequest *http.Request) string {
+	if token, ok := strings.CutPrefix(httpRequest.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if cookie, err := httpRequest.Cookie("UserToken"); err == nil {
+		return cookie.Value
+	}
+	return httpRequest.URL.Query().Get("UserToken")
+}
+
+// ExportSwcFileHandler serves an swc as a downloadable file. Browsers can't attach the grpc metadata, so the user is
+// given by the UserName query parameter and a token from exportUserToken, ApiVersion is optional. Passwords are
+// refused, a URL ends up in browser history and access logs.
+func ExportSwcFileHandler(fileFormat string) runtime.HandlerFunc {
+	return func(writer http.ResponseWriter, httpRequest *http.Request, pathParams map[string]string) {
+		query := httpRequest.URL.Query()
+		if query.Has("UserPassword") {
+			http.Error(writer, "UserPassword is not accepted in the url, login first and pass the UserToken!", http.StatusBadRequest)
+			return
+		}
+		userToken := exportUserToken(httpRequest)
+		if userToken == "" {
+			http.Error(writer, "UserToken is required!", http.StatusUnauthorized)
+			return
+		}
+
+		apiVersion := query.Get("ApiVersion")
+		if apiVersion == "" {
+			apiVersion = config.ApiVersion
+		}
+
+		exportRequest := request.ExportSwcFileRequest{
+			MetaInfo: &message.RequestMetaInfoV1{
+				ApiVersion: apiVersion,
+			},
+			UserVerifyInfo: &message.UserVerifyInfoV1{
+				UserName:  query.Get("UserName"),
+				UserToken: userToken,
+			},
+			SwcUuid:    pathParams["SwcUuid"],
+			FileFormat: fileFormat,
+		}
+
+		exportResponse, err := bll.DBMSServerController{}.ExportSwcFile(context.Background(), &exportRequest)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !exportResponse.GetMetaInfo().GetStatus() {
+			http.Error(writer, exportResponse.GetMetaInfo().GetMessage(), http.StatusBadRequest)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writer.Header().Set("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(exportResponse.GetFileName()))
+		_, _ = writer.Write([]byte(exportResponse.GetFileContent()))
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"DBMS/SwcDbmsCommon/Generated/go/proto/service"
 	"DBMS/dal"
 	"DBMS/dbmodel"
+	"DBMS/errcode"
 	"DBMS/logger"
 	"context"
+	"math"
 	"reflect"
 	"strconv"
 	"time"
@@ -17,6 +19,7 @@ import (
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type DBMSServerController struct {
@@ -1001,7 +1004,11 @@ func (D DBMSServerController) DeleteProject(ctx context.Context, request *reques
 		}, nil
 	}
 
-	result = dal.DeleteProject(projectMetaInfo, dal.GetDbInstance())
+	projectMetaInfo = queryProjectMetaInfo
+	projectMetaInfo.IsDeleted = true
+	projectMetaInfo.DeletedBy = executorUserMetaInfo.Name
+	projectMetaInfo.DeleteTime = time.Now()
+	result = dal.SetProjectDeleted(projectMetaInfo.Base.Uuid, true, projectMetaInfo.DeletedBy, projectMetaInfo.DeleteTime, dal.GetDbInstance())
 	if !result.Status {
 		return &response.DeleteProjectResponse{
 			MetaInfo: &message.ResponseMetaInfoV1{
@@ -1012,7 +1019,7 @@ func (D DBMSServerController) DeleteProject(ctx context.Context, request *reques
 			ProjectInfo: ProjectMetaInfoV1DbmodelToProtobuf(&projectMetaInfo),
 		}, nil
 	}
-	logger.GetLogger().Println("Project " + request.GetProjectUuid() + " Deleted")
+	logger.GetLogger().Println("Project " + request.GetProjectUuid() + " Moved to recycle bin by " + executorUserMetaInfo.Name)
 	DailyStatisticsInfo.DeletedProjectNumber += 1
 	return &response.DeleteProjectResponse{
 		MetaInfo: &message.ResponseMetaInfoV1{
@@ -1282,6 +1289,9 @@ func (D DBMSServerController) GetAllProject(ctx context.Context, request *reques
 		}, nil
 	}
 	for _, projectMetaInfo := range projectMetaInfoList {
+		if projectMetaInfo.IsDeleted {
+			continue
+		}
 		if PermissionVerify(&executorUserMetaInfo, &projectMetaInfo.Permission, "ReadPerimissionQueryProject") || PermissionGroupVerify(&executorUserMetaInfo, "AllProjectManagementPermission") {
 			protoMessage = append(protoMessage, ProjectMetaInfoV1DbmodelToProtobuf(&projectMetaInfo))
 		}
@@ -1423,30 +1433,7 @@ func (D DBMSServerController) CreateSwc(ctx context.Context, request *request.Cr
 	hour, minute, second := timePoint.Clock()
 	_ = strconv.Itoa(year) + "-" + mouth.String() + "-" + strconv.Itoa(day-1) + "_" + strconv.Itoa(hour) + ":" + strconv.Itoa(minute) + "-" + strconv.Itoa(second)
 
-	createTime := time.Now()
-	var swcSnapshotMetaInfo dbmodel.SwcSnapshotMetaInfoV1
-	swcSnapshotMetaInfo.Base.Id = primitive.NewObjectID()
-	swcSnapshotMetaInfo.Base.Uuid = uuid.NewString()
-	swcSnapshotMetaInfo.Base.DataAccessModelVersion = "V1"
-	swcSnapshotMetaInfo.CreateTime = createTime
-	swcSnapshotMetaInfo.Creator = request.GetUserVerifyInfo().GetUserName()
-	swcSnapshotMetaInfo.SwcSnapshotCollectionName = "Snapshot_" + uuid.NewString()
-	swcMetaInfo.SwcSnapshotList = append(swcMetaInfo.SwcSnapshotList, swcSnapshotMetaInfo)
-
-	var swcIncrementOperationMetaInfo dbmodel.SwcIncrementOperationMetaInfoV1
-	swcIncrementOperationMetaInfo.Base.Id = primitive.NewObjectID()
-	swcIncrementOperationMetaInfo.Base.Uuid = uuid.NewString()
-	swcIncrementOperationMetaInfo.Base.DataAccessModelVersion = "V1"
-	swcIncrementOperationMetaInfo.CreateTime = createTime
-	swcIncrementOperationMetaInfo.StartSnapshot = swcSnapshotMetaInfo.SwcSnapshotCollectionName
-	swcIncrementOperationMetaInfo.IncrementOperationCollectionName = "IncrementOperation_" + uuid.NewString()
-	swcMetaInfo.SwcIncrementOperationList = append(swcMetaInfo.SwcIncrementOperationList, swcIncrementOperationMetaInfo)
-
-	swcMetaInfo.CurrentIncrementOperationCollectionName = swcIncrementOperationMetaInfo.IncrementOperationCollectionName
-
-	resultvs := dal.CreateSnapshot(swcMetaInfo.Base.Uuid, swcSnapshotMetaInfo.SwcSnapshotCollectionName, dal.GetDbInstance())
-	resultms := dal.ModifySwc(*swcMetaInfo, dal.GetDbInstance())
-	if resultvs.Status && resultms.Status {
+	if result := CreateAndSaveSwcSnapshot(swcMetaInfo, request.GetUserVerifyInfo().GetUserName()); result.Status {
 		logger.GetLogger().Println("Version Control Enabled Successfully for Swc " + swcMetaInfo.Base.Uuid)
 	} else {
 		logger.GetLogger().Println("Version Control Enabled Failed for Swc " + swcMetaInfo.Base.Uuid)
@@ -1529,36 +1516,16 @@ func (D DBMSServerController) DeleteSwc(ctx context.Context, request *request.De
 		}, nil
 	}
 
-	result = dal.DeleteSwc(swcMetaInfo, dal.GetDbInstance())
-
-	var projectMetaInfoList []dbmodel.ProjectMetaInfoV1
-	dal.QueryAllProject(&projectMetaInfoList, dal.GetDbInstance())
-	for _, projectMetaInfo := range projectMetaInfoList {
-		var bFind = false
-		for idx, swcUuid := range projectMetaInfo.SwcList {
-			if swcUuid == swcMetaInfo.Base.Uuid {
-				projectMetaInfo.SwcList = append(projectMetaInfo.SwcList[:idx], projectMetaInfo.SwcList[idx:]...)
-				bFind = true
-				dal.ModifyProject(projectMetaInfo, dal.GetDbInstance())
-				break
-			}
-			if bFind {
-				break
-			}
+	swcMetaInfo.IsDeleted = true
+	swcMetaInfo.DeletedBy = executorUserMetaInfo.Name
+	swcMetaInfo.DeleteTime = time.Now()
+	result = dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		if result := dal.SetSwcDeletedWithContext(sessionContext, swcMetaInfo.Base.Uuid, true, swcMetaInfo.DeletedBy, swcMetaInfo.DeleteTime, dal.GetDbInstance()); !result.Status {
+			return result
 		}
-	}
-
-	if !result.Status {
-		return &response.DeleteSwcResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: result.Message,
-			},
-			SwcInfo: SwcMetaInfoV1DbmodelToProtobuf(&swcMetaInfo),
-		}, nil
-	}
-	result = dal.DeleteSwcDataCollection(swcMetaInfo.Base.Uuid, dal.GetDbInstance())
+		// BelongingProjectUuid is kept so RestoreSwc can put the swc back into its project
+		return dal.PullSwcFromAllProjectWithContext(sessionContext, swcMetaInfo.Base.Uuid, dal.GetDbInstance())
+	})
 	if !result.Status {
 		return &response.DeleteSwcResponse{
 			MetaInfo: &message.ResponseMetaInfoV1{
@@ -1570,25 +1537,13 @@ func (D DBMSServerController) DeleteSwc(ctx context.Context, request *request.De
 		}, nil
 	}
 
-	_, err := dal.GetDbInstance().MetaInfoDb.Collection(dal.ProjectMetaInfoCollectionString).UpdateMany(context.TODO(), bson.M{"SwcList": swcMetaInfo.Base.Uuid}, bson.M{"$pull": bson.M{"SwcList": swcMetaInfo.Base.Uuid}})
-	if err != nil {
-		return &response.DeleteSwcResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: err.Error(),
-			},
-			SwcInfo: SwcMetaInfoV1DbmodelToProtobuf(&swcMetaInfo),
-		}, nil
-	}
-
-	logger.GetLogger().Println("User " + request.UserVerifyInfo.GetUserName() + "Delete Swc " + swcMetaInfo.Base.Uuid)
+	logger.GetLogger().Println("User " + request.UserVerifyInfo.GetUserName() + " Move Swc " + swcMetaInfo.Base.Uuid + " to recycle bin")
 	DailyStatisticsInfo.DeletedSwcNumber += 1
 	return &response.DeleteSwcResponse{
 		MetaInfo: &message.ResponseMetaInfoV1{
 			Status:  true,
 			Id:      "",
-			Message: result.Message,
+			Message: "Move swc to recycle bin successfully!",
 		},
 		SwcInfo: SwcMetaInfoV1DbmodelToProtobuf(&swcMetaInfo),
 	}, nil
@@ -1673,6 +1628,11 @@ func (D DBMSServerController) UpdateSwc(ctx context.Context, request *request.Up
 	newSwcMetaInfo := SwcMetaInfoV1ProtobufToDbmodel(request.SwcInfo)
 
 	newSwcMetaInfo.LastModifiedTime = time.Now()
+	// ModifySwc keeps the stored revision, report that one back
+	newSwcMetaInfo.Revision = swcMetaInfo.Revision
+	// the clone provenance is set by CloneSwc only
+	newSwcMetaInfo.SourceSwcUuid = swcMetaInfo.SourceSwcUuid
+	newSwcMetaInfo.SourceSwcTime = swcMetaInfo.SourceSwcTime
 
 	result = dal.ModifySwc(*newSwcMetaInfo, dal.GetDbInstance())
 	if !result.Status {
@@ -1819,6 +1779,9 @@ func (D DBMSServerController) GetAllSwcMetaInfo(ctx context.Context, request *re
 	logger.GetLogger().Println("User " + request.UserVerifyInfo.GetUserName() + " Query All SwcMetaInfo ")
 
 	for _, dbMessage := range dbmodelMessage {
+		if dbMessage.IsDeleted {
+			continue
+		}
 		if PermissionVerify(&executorUserMetaInfo, &dbMessage.Permission, "ReadPerimissionQuerySwc") || PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
 			protoMessage = append(protoMessage, SwcMetaInfoV1DbmodelToProtobuf(&dbMessage))
 		}
@@ -1897,44 +1860,7 @@ func (D DBMSServerController) CreateSwcSnapshot(ctx context.Context, request *re
 		}, nil
 	}
 
-	timePoint := time.Now()
-	year, mouth, day := timePoint.Date()
-	hour, minute, second := timePoint.Clock()
-	_ = strconv.Itoa(year) + "-" + mouth.String() + "-" + strconv.Itoa(day-1) + "_" + strconv.Itoa(hour) + ":" + strconv.Itoa(minute) + "-" + strconv.Itoa(second)
-
-	createTime := time.Now()
-	var swcSnapshotMetaInfo dbmodel.SwcSnapshotMetaInfoV1
-	swcSnapshotMetaInfo.Base.Id = primitive.NewObjectID()
-	swcSnapshotMetaInfo.Base.Uuid = uuid.NewString()
-	swcSnapshotMetaInfo.Base.DataAccessModelVersion = "V1"
-	swcSnapshotMetaInfo.CreateTime = createTime
-	swcSnapshotMetaInfo.Creator = request.GetUserVerifyInfo().GetUserName()
-	swcSnapshotMetaInfo.SwcSnapshotCollectionName = "Snapshot_" + uuid.NewString()
-	swcMetaInfo.SwcSnapshotList = append(swcMetaInfo.SwcSnapshotList, swcSnapshotMetaInfo)
-
-	var swcIncrementOperationMetaInfo dbmodel.SwcIncrementOperationMetaInfoV1
-	swcIncrementOperationMetaInfo.Base.Id = primitive.NewObjectID()
-	swcIncrementOperationMetaInfo.Base.Uuid = uuid.NewString()
-	swcIncrementOperationMetaInfo.Base.DataAccessModelVersion = "V1"
-	swcIncrementOperationMetaInfo.CreateTime = createTime
-	swcIncrementOperationMetaInfo.StartSnapshot = swcSnapshotMetaInfo.SwcSnapshotCollectionName
-	swcIncrementOperationMetaInfo.IncrementOperationCollectionName = "IncrementOperation_" + uuid.NewString()
-	swcMetaInfo.SwcIncrementOperationList = append(swcMetaInfo.SwcIncrementOperationList, swcIncrementOperationMetaInfo)
-
-	swcMetaInfo.CurrentIncrementOperationCollectionName = swcIncrementOperationMetaInfo.IncrementOperationCollectionName
-
-	result = dal.CreateSnapshot(swcMetaInfo.Base.Uuid, swcSnapshotMetaInfo.SwcSnapshotCollectionName, dal.GetDbInstance())
-	if !result.Status {
-		return &response.CreateSwcSnapshotResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: result.Message,
-			},
-		}, nil
-	}
-
-	result = dal.ModifySwc(swcMetaInfo, dal.GetDbInstance())
+	result = CreateAndSaveSwcSnapshot(&swcMetaInfo, request.GetUserVerifyInfo().GetUserName())
 	if result.Status {
 		return &response.CreateSwcSnapshotResponse{
 			MetaInfo: &message.ResponseMetaInfoV1{
@@ -2168,15 +2094,36 @@ func (D DBMSServerController) GetIncrementOperation(ctx context.Context, request
 		}, nil
 	}
 
+	var after *dal.SwcIncrementOperationPosition
+	if request.GetPageToken() != "" {
+		var err error
+		if after, err = DecodeSwcIncrementOperationPageToken(request.GetPageToken()); err != nil {
+			return &response.GetIncrementOperationResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: err.Error(),
+				},
+			}, nil
+		}
+	}
+
 	var dbmodelMessage dbmodel.SwcIncrementOperationListV1
 	var protoMessage message.SwcIncrementOperationListV1
 
-	result := dal.QuerySwcIncrementOperation(request.GetIncrementOperationCollectionName(), &dbmodelMessage, dal.GetDbInstance())
+	// without a page size every matching operation is returned as before
+	filter := SwcIncrementOperationFilterFromRequest(request)
+	result := dal.QuerySwcIncrementOperationByFilter(request.GetIncrementOperationCollectionName(), filter, after, int64(max(request.GetPageSize(), 0)), &dbmodelMessage, dal.GetDbInstance())
 	if result.Status {
 		for _, swcNodeData := range dbmodelMessage {
 			protoMessage.SwcIncrementOperation = append(protoMessage.SwcIncrementOperation, SwcIncrementOperationListV1DbmodelToProtobuf(&swcNodeData))
 		}
 
+		nextPageToken := ""
+		if request.GetPageSize() > 0 && len(dbmodelMessage) == int(request.GetPageSize()) {
+			nextPageToken = EncodeSwcIncrementOperationPageToken(&dbmodelMessage[len(dbmodelMessage)-1])
+		}
+
 		return &response.GetIncrementOperationResponse{
 			MetaInfo: &message.ResponseMetaInfoV1{
 				Status:  true,
@@ -2184,6 +2131,7 @@ func (D DBMSServerController) GetIncrementOperation(ctx context.Context, request
 				Message: result.Message,
 			},
 			SwcIncrementOperationList: &protoMessage,
+			NextPageToken:             nextPageToken,
 		}, nil
 	}
 
@@ -2280,34 +2228,55 @@ func (D DBMSServerController) CreateSwcNodeData(ctx context.Context, request *re
 
 	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Create Swc nodes " + strconv.Itoa(len(swcData)) + " at " + querySwcMetaInfo.Base.Uuid)
 
-	result := dal.CreateSwcData(querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance())
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var topologyIssues []SwcTopologyIssue
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		var result dal.ReturnWrapper
+		if result, topologyIssues = VerifySwcTopologyEdit(sessionContext, &querySwcMetaInfo, func(currentSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+			return ApplySwcNodeCreate(currentSwcData, swcData)
+		}); !result.Status {
+			return result
+		}
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, []string{}, func(newRevision int64) dal.ReturnWrapper {
+			for idx := range swcData {
+				swcData[idx].Version = newRevision
+			}
+			result := dal.CreateSwcDataWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			operationRecord := dbmodel.SwcIncrementOperationV1{}
+			operationRecord.Base.Id = primitive.NewObjectID()
+			operationRecord.Base.Uuid = uuid.NewString()
+			operationRecord.Base.DataAccessModelVersion = "V1"
+			operationRecord.IncrementOperation = dal.IncrementOp_Create
+			operationRecord.SwcData = swcData
+			operationRecord.CreateTime = createTime
+			if incrementResult := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !incrementResult.Status {
+				return incrementResult
+			}
+			return result
+		})
+		return result
+	})
 	if !result.Status {
 		return &response.CreateSwcNodeDataResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: result.Message,
-			},
+			MetaInfo:         SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
 		}, nil
 	}
 	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Create Swc nodes " + strconv.Itoa(len(swcData)) + " at " + querySwcMetaInfo.Base.Uuid)
 	DailyStatisticsInfo.CreateSwcNodeNumber += 1
 
-	operationRecord := dbmodel.SwcIncrementOperationV1{}
-	operationRecord.Base.Id = primitive.NewObjectID()
-	operationRecord.Base.Uuid = uuid.NewString()
-	operationRecord.Base.DataAccessModelVersion = "V1"
-	operationRecord.IncrementOperation = dal.IncrementOp_Create
-	operationRecord.SwcData = swcData
-	operationRecord.CreateTime = createTime
-	dal.CreateIncrementOperation(querySwcMetaInfo.CurrentIncrementOperationCollectionName, operationRecord, dal.GetDbInstance())
-
 	return &response.CreateSwcNodeDataResponse{
 		MetaInfo: &message.ResponseMetaInfoV1{
 			Status:  true,
 			Id:      "",
 			Message: result.Message,
 		},
+		Revision:         revision,
 		CreatedNodesUuid: nodesUuid,
 	}, nil
 }
@@ -2381,34 +2350,46 @@ func (D DBMSServerController) DeleteSwcNodeData(ctx context.Context, request *re
 
 	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Delete Swc nodes " + strconv.Itoa(len(swcData)) + " at " + querySwcMetaInfo.Base.Uuid)
 
-	result := dal.DeleteSwcData(querySwcMetaInfo.Base.Uuid, swcData, dal.GetDbInstance())
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		var result dal.ReturnWrapper
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, SwcNodeUuids(swcData), func(newRevision int64) dal.ReturnWrapper {
+			result := dal.DeleteSwcDataWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, swcData, dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			operationRecord := dbmodel.SwcIncrementOperationV1{}
+			operationRecord.Base.Id = primitive.NewObjectID()
+			operationRecord.Base.Uuid = uuid.NewString()
+			operationRecord.Base.DataAccessModelVersion = "V1"
+			operationRecord.IncrementOperation = dal.IncrementOp_Delete
+			operationRecord.SwcData = swcData
+			operationRecord.CreateTime = createTime
+			if incrementResult := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !incrementResult.Status {
+				return incrementResult
+			}
+			return result
+		})
+		return result
+	})
 	if result.Status {
 		logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Delete Swc nodes " + strconv.Itoa(len(swcData)) + " at " + querySwcMetaInfo.Base.Uuid)
 		DailyStatisticsInfo.DeletedSwcNodeNumber += 1
 
-		operationRecord := dbmodel.SwcIncrementOperationV1{}
-		operationRecord.Base.Id = primitive.NewObjectID()
-		operationRecord.Base.Uuid = uuid.NewString()
-		operationRecord.Base.DataAccessModelVersion = "V1"
-		operationRecord.IncrementOperation = dal.IncrementOp_Delete
-		operationRecord.SwcData = swcData
-		operationRecord.CreateTime = createTime
-		dal.CreateIncrementOperation(querySwcMetaInfo.CurrentIncrementOperationCollectionName, operationRecord, dal.GetDbInstance())
-
 		return &response.DeleteSwcNodeDataResponse{
 			MetaInfo: &message.ResponseMetaInfoV1{
 				Status:  true,
 				Id:      "",
 				Message: result.Message,
 			},
+			Revision: revision,
 		}, nil
 	}
 	return &response.DeleteSwcNodeDataResponse{
-		MetaInfo: &message.ResponseMetaInfoV1{
-			Status:  false,
-			Id:      "",
-			Message: result.Message,
-		},
+		MetaInfo:         SwcWriteFailedMetaInfo(result, revisionConflict),
+		RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
 	}, nil
 }
 
@@ -2487,34 +2468,52 @@ func (D DBMSServerController) UpdateSwcNodeData(ctx context.Context, request *re
 
 	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Update Swc nodes " + strconv.Itoa(len(swcData)) + " at " + querySwcMetaInfo.Base.Uuid)
 
-	result := dal.ModifySwcData(querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance())
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var topologyIssues []SwcTopologyIssue
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		var result dal.ReturnWrapper
+		if result, topologyIssues = VerifySwcTopologyEdit(sessionContext, &querySwcMetaInfo, func(currentSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+			return ApplySwcNodeUpdate(currentSwcData, swcData)
+		}); !result.Status {
+			return result
+		}
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, SwcNodeUuids(swcData), func(newRevision int64) dal.ReturnWrapper {
+			result := dal.ModifySwcDataWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			operationRecord := dbmodel.SwcIncrementOperationV1{}
+			operationRecord.Base.Id = primitive.NewObjectID()
+			operationRecord.Base.Uuid = uuid.NewString()
+			operationRecord.Base.DataAccessModelVersion = "V1"
+			operationRecord.IncrementOperation = dal.IncrementOp_Update
+			operationRecord.SwcData = swcData
+			operationRecord.CreateTime = modifiedTime
+			if incrementResult := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !incrementResult.Status {
+				return incrementResult
+			}
+			return result
+		})
+		return result
+	})
 	if result.Status {
 		logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Update Swc nodes " + strconv.Itoa(len(swcData)) + " at " + querySwcMetaInfo.Base.Uuid)
 		DailyStatisticsInfo.ModifiedSwcNodeNumber += 1
 
-		operationRecord := dbmodel.SwcIncrementOperationV1{}
-		operationRecord.Base.Id = primitive.NewObjectID()
-		operationRecord.Base.Uuid = uuid.NewString()
-		operationRecord.Base.DataAccessModelVersion = "V1"
-		operationRecord.IncrementOperation = dal.IncrementOp_Update
-		operationRecord.SwcData = swcData
-		operationRecord.CreateTime = modifiedTime
-		dal.CreateIncrementOperation(querySwcMetaInfo.CurrentIncrementOperationCollectionName, operationRecord, dal.GetDbInstance())
-
 		return &response.UpdateSwcNodeDataResponse{
 			MetaInfo: &message.ResponseMetaInfoV1{
 				Status:  true,
 				Id:      "",
 				Message: result.Message,
 			},
+			Revision: revision,
 		}, nil
 	} else {
 		return &response.UpdateSwcNodeDataResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: result.Message,
-			},
+			MetaInfo:         SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
 		}, nil
 	}
 }
@@ -3865,7 +3864,7 @@ func (D DBMSServerController) GetSwcAttachmentApo(ctx context.Context, request *
 	}, nil
 }
 
-func (D DBMSServerController) RevertSwcVersion(context context.Context, request *request.RevertSwcVersionRequest) (*response.RevertSwcVersionResponse, error) {
+func (D DBMSServerController) RevertSwcVersion(ctx context.Context, request *request.RevertSwcVersionRequest) (*response.RevertSwcVersionResponse, error) {
 	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
 	if !apiVersionVerifyResult.Status {
 		return &response.RevertSwcVersionResponse{
@@ -3925,65 +3924,30 @@ func (D DBMSServerController) RevertSwcVersion(context context.Context, request
 	endTime := request.GetVersionEndTime().AsTime()
 
 	status := dal.QuerySwc(&swcMetaInfo, dal.GetDbInstance())
+	if status.Status && IsSwcHistoryRemovedAt(&swcMetaInfo, endTime) {
+		return &response.RevertSwcVersionResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      errcode.ErrorSwcHistoryNotAvailable,
+				Message: "History of swc " + swcMetaInfo.Base.Uuid + " at " + endTime.String() + " is no longer available, it was removed by snapshot compaction!",
+			},
+		}, nil
+	}
 	if status.Status {
-		// Process SwcSnapshotMetaInfoV1 items
-		var latestSnapshot dbmodel.SwcSnapshotMetaInfoV1
-		var newSnapshotList []dbmodel.SwcSnapshotMetaInfoV1
-		for _, snapshot := range swcMetaInfo.SwcSnapshotList {
-			if snapshot.CreateTime.Before(endTime) || snapshot.CreateTime.Equal(endTime) {
-				newSnapshotList = append(newSnapshotList, snapshot)
-				if snapshot.CreateTime.After(latestSnapshot.CreateTime) {
-					latestSnapshot = snapshot
-				}
-			}
-		}
-
-		// Process SwcIncrementOperationMetaInfoV1 items
-		var latestIncrementOperation dbmodel.SwcIncrementOperationMetaInfoV1
-		var newIncrementOperationList []dbmodel.SwcIncrementOperationMetaInfoV1
-		for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
-			if incrementOperation.CreateTime.Before(endTime) || incrementOperation.CreateTime.Equal(endTime) {
-				newIncrementOperationList = append(newIncrementOperationList, incrementOperation)
-				if incrementOperation.CreateTime.After(latestIncrementOperation.CreateTime) {
-					latestIncrementOperation = incrementOperation
-				}
-			}
-		}
-
-		if latestIncrementOperation.StartSnapshot == latestSnapshot.SwcSnapshotCollectionName && latestIncrementOperation.StartSnapshot != "" {
-
-			status = dal.RevertSwcNodeData(request.GetSwcUuid(), latestSnapshot.SwcSnapshotCollectionName, latestIncrementOperation.IncrementOperationCollectionName, endTime, dal.GetDbInstance())
-			if status.Status {
-
-				swcMetaInfo.SwcSnapshotList = newSnapshotList
-				swcMetaInfo.SwcIncrementOperationList = newIncrementOperationList
-				swcMetaInfo.CurrentIncrementOperationCollectionName = latestIncrementOperation.IncrementOperationCollectionName
-
-				dal.ModifySwc(swcMetaInfo, dal.GetDbInstance())
-
-				return &response.RevertSwcVersionResponse{
-					MetaInfo: &message.ResponseMetaInfoV1{
-						Status:  true,
-						Id:      "",
-						Message: "Revert Successfully!",
-					},
-				}, nil
-			} else {
-				return &response.RevertSwcVersionResponse{
-					MetaInfo: &message.ResponseMetaInfoV1{
-						Status:  false,
-						Id:      "",
-						Message: status.Message,
-					},
-				}, nil
-			}
-		} else {
+		revision, revisionConflict, status := RevertSwcToTime(request.GetSwcUuid(), endTime, request.ExpectedRevision)
+		if status.Status {
 			return &response.RevertSwcVersionResponse{
 				MetaInfo: &message.ResponseMetaInfoV1{
-					Status:  false,
+					Status:  true,
 					Id:      "",
-					Message: "Critical! Dbms cannot decided which increment operation list can be used to revert swc version to " + endTime.String() + "!",
+					Message: "Revert Successfully!",
 				},
+				Revision: revision,
+			}, nil
+		} else {
+			return &response.RevertSwcVersionResponse{
+				MetaInfo:         SwcWriteFailedMetaInfo(status, revisionConflict),
+				RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
 			}, nil
 		}
 	}
@@ -4653,7 +4617,7 @@ func (D DBMSServerController) GetProjectSwcNamesByProjectUuid(context context.Co
 	}, nil
 }
 
-func (D DBMSServerController) UpdateSwcNParentInfo(context context.Context, request *request.UpdateSwcNParentInfoRequest) (*response.UpdateSwcNParentInfoResponse, error) {
+func (D DBMSServerController) UpdateSwcNParentInfo(ctx context.Context, request *request.UpdateSwcNParentInfoRequest) (*response.UpdateSwcNParentInfoResponse, error) {
 	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
 	if !apiVersionVerifyResult.Status {
 		return &response.UpdateSwcNParentInfoResponse{
@@ -4715,28 +4679,47 @@ func (D DBMSServerController) UpdateSwcNParentInfo(context context.Context, requ
 		}
 	}
 
-	var result, updateCount, noUpdateCount, incomingNotExistCount, dbNotExistCount, _, _, _ = dal.UpdateSwcNParent(request.GetSwcUuid(), &nodeNParent, dal.GetDbInstance())
+	var updateCount, noUpdateCount, incomingNotExistCount, dbNotExistCount int
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var topologyIssues []SwcTopologyIssue
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		var result dal.ReturnWrapper
+		if result, topologyIssues = VerifySwcTopologyEdit(sessionContext, &querySwcMetaInfo, func(currentSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+			return ApplySwcNParentUpdate(currentSwcData, nodeNParent)
+		}); !result.Status {
+			return result
+		}
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, NodeNParentUuids(nodeNParent), func(newRevision int64) dal.ReturnWrapper {
+			var result dal.ReturnWrapper
+			result, updateCount, noUpdateCount, incomingNotExistCount, dbNotExistCount, _, _, _ = dal.UpdateSwcNParentWithContext(sessionContext, request.GetSwcUuid(), &nodeNParent, dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			if querySwcMetaInfo.CurrentIncrementOperationCollectionName != "" {
+				operationRecord := dbmodel.SwcIncrementOperationV1{}
+				operationRecord.Base.Id = primitive.NewObjectID()
+				operationRecord.Base.Uuid = uuid.NewString()
+				operationRecord.Base.DataAccessModelVersion = "V1"
+				operationRecord.IncrementOperation = dal.IncrementOp_UpdateNParent
+				operationRecord.NodeNParent = nodeNParent
+				operationRecord.CreateTime = time.Now()
+				if incrementResult := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !incrementResult.Status {
+					return incrementResult
+				}
+			}
+			return result
+		})
+		return result
+	})
 
 	if !result.Status {
 		return &response.UpdateSwcNParentInfoResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: result.Message,
-			},
+			MetaInfo:         SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
 		}, nil
 	}
-
-	if querySwcMetaInfo.CurrentIncrementOperationCollectionName != "" {
-		operationRecord := dbmodel.SwcIncrementOperationV1{}
-		operationRecord.Base.Id = primitive.NewObjectID()
-		operationRecord.Base.Uuid = uuid.NewString()
-		operationRecord.Base.DataAccessModelVersion = "V1"
-		operationRecord.IncrementOperation = dal.IncrementOp_UpdateNParent
-		operationRecord.NodeNParent = nodeNParent
-		operationRecord.CreateTime = time.Now()
-		dal.CreateIncrementOperation(querySwcMetaInfo.CurrentIncrementOperationCollectionName, operationRecord, dal.GetDbInstance())
-	}
 	logger.GetLogger().Println("Update Swc NParent Info Successfully! ", "Swc Uuid: ", querySwcMetaInfo.Base.Uuid, "Update Number: ", updateCount, " Same Number: ", noUpdateCount, " Diff Incoming Missing: ", incomingNotExistCount, " Diff DB Missing: ", dbNotExistCount)
 
 	return &response.UpdateSwcNParentInfoResponse{
@@ -4745,6 +4728,7 @@ func (D DBMSServerController) UpdateSwcNParentInfo(context context.Context, requ
 			Id:      "",
 			Message: "Update Swc NParent Info Successfully!",
 		},
+		Revision:            revision,
 		UpdateNumber:        int32(updateCount),
 		SameNumber:          int32(noUpdateCount),
 		DiffIncomingMissing: int32(incomingNotExistCount),
@@ -4752,7 +4736,7 @@ func (D DBMSServerController) UpdateSwcNParentInfo(context context.Context, requ
 	}, nil
 }
 
-func (D DBMSServerController) ClearAllNodes(context context.Context, request *request.ClearAllNodesRequest) (*response.ClearAllNodesResponse, error) {
+func (D DBMSServerController) ClearAllNodes(ctx context.Context, request *request.ClearAllNodesRequest) (*response.ClearAllNodesResponse, error) {
 	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
 	if !apiVersionVerifyResult.Status {
 		return &response.ClearAllNodesResponse{
@@ -4802,26 +4786,37 @@ func (D DBMSServerController) ClearAllNodes(context context.Context, request *re
 		}, nil
 	}
 
-	var result = dal.ClearAllNode(request.GetSwcUuid(), dal.GetDbInstance())
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var result = dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		var result dal.ReturnWrapper
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, nil, func(newRevision int64) dal.ReturnWrapper {
+			result := dal.ClearAllNodeWithContext(sessionContext, request.GetSwcUuid(), dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			if querySwcMetaInfo.CurrentIncrementOperationCollectionName != "" {
+				operationRecord := dbmodel.SwcIncrementOperationV1{}
+				operationRecord.Base.Id = primitive.NewObjectID()
+				operationRecord.Base.Uuid = uuid.NewString()
+				operationRecord.Base.DataAccessModelVersion = "V1"
+				operationRecord.IncrementOperation = dal.IncrementOp_ClearAll
+				operationRecord.CreateTime = time.Now()
+				if incrementResult := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !incrementResult.Status {
+					return incrementResult
+				}
+			}
+			return result
+		})
+		return result
+	})
 	if !result.Status {
 		return &response.ClearAllNodesResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: result.Message,
-			},
+			MetaInfo:         SwcWriteFailedMetaInfo(result, revisionConflict),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
 		}, nil
 	}
-
-	if querySwcMetaInfo.CurrentIncrementOperationCollectionName != "" {
-		operationRecord := dbmodel.SwcIncrementOperationV1{}
-		operationRecord.Base.Id = primitive.NewObjectID()
-		operationRecord.Base.Uuid = uuid.NewString()
-		operationRecord.Base.DataAccessModelVersion = "V1"
-		operationRecord.IncrementOperation = dal.IncrementOp_ClearAll
-		operationRecord.CreateTime = time.Now()
-		dal.CreateIncrementOperation(querySwcMetaInfo.CurrentIncrementOperationCollectionName, operationRecord, dal.GetDbInstance())
-	}
 	logger.GetLogger().Println("User " + executorUserMetaInfo.Name + " Clear All Nodes at " + querySwcMetaInfo.Base.Uuid)
 
 	return &response.ClearAllNodesResponse{
@@ -4830,10 +4825,11 @@ func (D DBMSServerController) ClearAllNodes(context context.Context, request *re
 			Id:      "",
 			Message: "Clear All Nodes Successfully!",
 		},
+		Revision: revision,
 	}, nil
 }
 
-func (D DBMSServerController) OverwriteSwcNodeData(context context.Context, request *request.OverwriteSwcNodeDataRequest) (*response.OverwriteSwcNodeDataResponse, error) {
+func (D DBMSServerController) OverwriteSwcNodeData(ctx context.Context, request *request.OverwriteSwcNodeDataRequest) (*response.OverwriteSwcNodeDataResponse, error) {
 	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
 	if !apiVersionVerifyResult.Status {
 		return &response.OverwriteSwcNodeDataResponse{
@@ -4883,27 +4879,6 @@ func (D DBMSServerController) OverwriteSwcNodeData(context context.Context, requ
 		}, nil
 	}
 
-	var result = dal.ClearAllNode(request.GetSwcUuid(), dal.GetDbInstance())
-	if !result.Status {
-		return &response.OverwriteSwcNodeDataResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: result.Message,
-			},
-		}, nil
-	}
-
-	if querySwcMetaInfo.CurrentIncrementOperationCollectionName != "" {
-		operationRecord := dbmodel.SwcIncrementOperationV1{}
-		operationRecord.Base.Id = primitive.NewObjectID()
-		operationRecord.Base.Uuid = uuid.NewString()
-		operationRecord.Base.DataAccessModelVersion = "V1"
-		operationRecord.IncrementOperation = dal.IncrementOp_ClearAll
-		operationRecord.CreateTime = time.Now()
-		dal.CreateIncrementOperation(querySwcMetaInfo.CurrentIncrementOperationCollectionName, operationRecord, dal.GetDbInstance())
-	}
-
 	var swcData dbmodel.SwcDataV1
 	for _, swcNodeData := range request.SwcData.SwcData {
 		swcData = append(swcData, *SwcNodeDataV1ProtobufToDbmodel(swcNodeData))
@@ -4925,80 +4900,29 @@ func (D DBMSServerController) OverwriteSwcNodeData(context context.Context, requ
 		swcData[idx].CheckerUserUuid = ""
 	}
 
-	if len(swcData) == 0 {
-		return &response.OverwriteSwcNodeDataResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  true,
-				Id:      "",
-				Message: "Empty Swc Data",
-			},
-			CreatedNodesUuid: nodesUuid,
-		}, nil
-	}
-
 	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Overwrite swc data " + strconv.Itoa(len(swcData)) + " at " + querySwcMetaInfo.Base.Uuid)
 
-	result = dal.CreateSwcData(request.GetSwcUuid(), &swcData, dal.GetDbInstance())
+	revision, revisionConflict, topologyIssues, result := OverwriteSwcNodes(&querySwcMetaInfo, swcData, request.ExpectedRevision, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), request.GetUserVerifyInfo().GetUserName())
 	if !result.Status {
+		logger.GetLogger().Println("Overwrite Swc Node Data and create new snapshot Failed for Swc " + querySwcMetaInfo.Base.Uuid)
 		return &response.OverwriteSwcNodeDataResponse{
-			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
-				Id:      "",
-				Message: result.Message,
-			},
-			CreatedNodesUuid: nodesUuid,
+			MetaInfo:         SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
 		}, nil
 	}
 
-	if querySwcMetaInfo.CurrentIncrementOperationCollectionName != "" {
-		operationRecord := dbmodel.SwcIncrementOperationV1{}
-		operationRecord.Base.Id = primitive.NewObjectID()
-		operationRecord.Base.Uuid = uuid.NewString()
-		operationRecord.Base.DataAccessModelVersion = "V1"
-		operationRecord.IncrementOperation = dal.IncrementOp_OverwriteAll
-		operationRecord.SwcData = swcData
-		operationRecord.CreateTime = createTime
-		dal.CreateIncrementOperation(querySwcMetaInfo.CurrentIncrementOperationCollectionName, operationRecord, dal.GetDbInstance())
-	}
-
-	var swcSnapshotMetaInfo dbmodel.SwcSnapshotMetaInfoV1
-	swcSnapshotMetaInfo.Base.Id = primitive.NewObjectID()
-	swcSnapshotMetaInfo.Base.Uuid = uuid.NewString()
-	swcSnapshotMetaInfo.Base.DataAccessModelVersion = "V1"
-	swcSnapshotMetaInfo.CreateTime = createTime
-	swcSnapshotMetaInfo.Creator = request.GetUserVerifyInfo().GetUserName()
-	swcSnapshotMetaInfo.SwcSnapshotCollectionName = "Snapshot_" + uuid.NewString()
-	querySwcMetaInfo.SwcSnapshotList = append(querySwcMetaInfo.SwcSnapshotList, swcSnapshotMetaInfo)
-
-	var swcIncrementOperationMetaInfo dbmodel.SwcIncrementOperationMetaInfoV1
-	swcIncrementOperationMetaInfo.Base.Id = primitive.NewObjectID()
-	swcIncrementOperationMetaInfo.Base.Uuid = uuid.NewString()
-	swcIncrementOperationMetaInfo.Base.DataAccessModelVersion = "V1"
-	swcIncrementOperationMetaInfo.CreateTime = createTime
-	swcIncrementOperationMetaInfo.StartSnapshot = swcSnapshotMetaInfo.SwcSnapshotCollectionName
-	swcIncrementOperationMetaInfo.IncrementOperationCollectionName = "IncrementOperation_" + uuid.NewString()
-	querySwcMetaInfo.SwcIncrementOperationList = append(querySwcMetaInfo.SwcIncrementOperationList, swcIncrementOperationMetaInfo)
-
-	querySwcMetaInfo.CurrentIncrementOperationCollectionName = swcIncrementOperationMetaInfo.IncrementOperationCollectionName
-
-	resultcs := dal.CreateSnapshot(querySwcMetaInfo.Base.Uuid, swcSnapshotMetaInfo.SwcSnapshotCollectionName, dal.GetDbInstance())
-	resultms := dal.ModifySwc(querySwcMetaInfo, dal.GetDbInstance())
-	if resultcs.Status && resultms.Status {
-		logger.GetLogger().Println("Overwrite Swc Node Data and create new snapshot Successfully for Swc " + querySwcMetaInfo.Base.Uuid)
-	} else {
-		logger.GetLogger().Println("Overwrite Swc Node Data and create new snapshot Failed for Swc " + querySwcMetaInfo.Base.Uuid)
-	}
-
-	if !result.Status {
+	if len(swcData) == 0 {
 		return &response.OverwriteSwcNodeDataResponse{
 			MetaInfo: &message.ResponseMetaInfoV1{
-				Status:  false,
+				Status:  true,
 				Id:      "",
-				Message: result.Message,
+				Message: "Empty Swc Data",
 			},
+			Revision:         revision,
 			CreatedNodesUuid: nodesUuid,
 		}, nil
 	}
+	logger.GetLogger().Println("Overwrite Swc Node Data and create new snapshot Successfully for Swc " + querySwcMetaInfo.Base.Uuid)
 
 	return &response.OverwriteSwcNodeDataResponse{
 		MetaInfo: &message.ResponseMetaInfoV1{
@@ -5006,6 +4930,7 @@ func (D DBMSServerController) OverwriteSwcNodeData(context context.Context, requ
 			Id:      "",
 			Message: "Overwrite Swc Node Data Successfully!",
 		},
+		Revision:         revision,
 		CreatedNodesUuid: nodesUuid,
 	}, nil
 }
@@ -5156,3 +5081,2926 @@ func (D DBMSServerController) GetProjectsDefinedSomaSwc(ctx context.Context, req
 		SwcUuids: swcUuids,
 	}, nil
 }
+
+func (D DBMSServerController) ImportSwcFile(ctx context.Context, request *request.ImportSwcFileRequest) (*response.ImportSwcFileResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.ImportSwcFileResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.ImportSwcFileResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ImportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ImportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "WritePermissionAddSwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.ImportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	fileFormat, err := NormalizeSwcFileFormat(request.GetFileFormat())
+	if err != nil {
+		return &response.ImportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: err.Error(),
+			},
+		}, nil
+	}
+
+	swcData, err := ParseSwcFileContent(request.GetFileContent(), fileFormat)
+	if err != nil {
+		return &response.ImportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Parse " + fileFormat + " file failed! " + err.Error(),
+			},
+		}, nil
+	}
+
+	if len(swcData) == 0 {
+		return &response.ImportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "No swc node found in file!",
+			},
+		}, nil
+	}
+
+	createTime := time.Now()
+
+	var nodesUuid []string
+
+	for idx := range swcData {
+		swcData[idx].Creator = executorUserMetaInfo.Name
+		swcData[idx].Base.Id = primitive.NewObjectID()
+		newUuid := uuid.NewString()
+		nodesUuid = append(nodesUuid, newUuid)
+		swcData[idx].Base.Uuid = newUuid
+		swcData[idx].Base.DataAccessModelVersion = "V1"
+		swcData[idx].CreateTime = createTime
+		swcData[idx].LastModifiedTime = createTime
+		swcData[idx].CheckerUserUuid = ""
+	}
+
+	// appended nodes are numbered 1..len(swcData) parents first here, then shifted after the largest n of the swc
+	// inside the write so they never collide with the existing nodes
+	if !request.GetOverwrite() {
+		nodeNParent, _, err := TopologicalRenumberSwcNodes(swcData)
+		if err != nil {
+			return &response.ImportSwcFileResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: "Cannot append file to swc! " + err.Error(),
+				},
+			}, nil
+		}
+		swcData = ApplySwcNParentUpdate(swcData, nodeNParent)
+	}
+
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var topologyIssues []SwcTopologyIssue
+	var result dal.ReturnWrapper
+	if request.GetOverwrite() {
+		// the same path as OverwriteSwcNodeData, the imported nodes start a new snapshot and increment operation list
+		revision, revisionConflict, topologyIssues, result = OverwriteSwcNodes(&querySwcMetaInfo, swcData, request.ExpectedRevision, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), request.GetUserVerifyInfo().GetUserName())
+	} else {
+		result = dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+			var result dal.ReturnWrapper
+			if result, topologyIssues = VerifySwcTopologyEdit(sessionContext, &querySwcMetaInfo, func(currentSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+				return ApplySwcNodeCreate(currentSwcData, OffsetSwcNodeN(swcData, MaxSwcNodeN(currentSwcData)))
+			}); !result.Status {
+				return result
+			}
+			revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, []string{}, func(newRevision int64) dal.ReturnWrapper {
+				var maxN int32
+				if result := dal.QuerySwcMaxNWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &maxN, dal.GetDbInstance()); !result.Status {
+					return result
+				}
+				appendedSwcData := OffsetSwcNodeN(swcData, maxN)
+				for idx := range appendedSwcData {
+					appendedSwcData[idx].Version = newRevision
+				}
+
+				result := dal.CreateSwcDataWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &appendedSwcData, dal.GetDbInstance())
+				if !result.Status {
+					return result
+				}
+
+				operationRecord := dbmodel.SwcIncrementOperationV1{}
+				operationRecord.Base.Id = primitive.NewObjectID()
+				operationRecord.Base.Uuid = uuid.NewString()
+				operationRecord.Base.DataAccessModelVersion = "V1"
+				operationRecord.IncrementOperation = dal.IncrementOp_Create
+				operationRecord.SwcData = appendedSwcData
+				operationRecord.CreateTime = createTime
+				if incrementResult := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !incrementResult.Status {
+					return incrementResult
+				}
+				return result
+			})
+			return result
+		})
+	}
+	if !result.Status {
+		return &response.ImportSwcFileResponse{
+			MetaInfo:         SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
+		}, nil
+	}
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Import " + fileFormat + " file with " + strconv.Itoa(len(swcData)) + " nodes at " + querySwcMetaInfo.Base.Uuid)
+	DailyStatisticsInfo.CreateSwcNodeNumber += 1
+
+	return &response.ImportSwcFileResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Import " + fileFormat + " file successfully!",
+		},
+		Revision:         revision,
+		CreatedNodesUuid: nodesUuid,
+	}, nil
+}
+
+func (D DBMSServerController) ExportSwcFile(ctx context.Context, request *request.ExportSwcFileRequest) (*response.ExportSwcFileResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.ExportSwcFileResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.ExportSwcFileResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ExportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ExportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.ExportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	fileFormat, err := NormalizeSwcFileFormat(request.GetFileFormat())
+	if err != nil {
+		return &response.ExportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: err.Error(),
+			},
+		}, nil
+	}
+
+	var swcData dbmodel.SwcDataV1
+	result := dal.QueryAllSwcData(querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance())
+	if !result.Status {
+		return &response.ExportSwcFileResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + executorUserMetaInfo.Name + " Export " + fileFormat + " file of " + querySwcMetaInfo.Base.Uuid)
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.ExportSwcFileResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Export " + fileFormat + " file successfully!",
+		},
+		FileName:    querySwcMetaInfo.Name + "." + fileFormat,
+		FileContent: FormatSwcFileContent(swcData, fileFormat),
+	}, nil
+}
+
+func (D DBMSServerController) ValidateSwc(ctx context.Context, request *request.ValidateSwcRequest) (*response.ValidateSwcResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.ValidateSwcResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.ValidateSwcResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ValidateSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ValidateSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.ValidateSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	var swcData dbmodel.SwcDataV1
+	result := dal.QueryAllSwcData(querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance())
+	if !result.Status {
+		return &response.ValidateSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	issues := ValidateSwcTopology(swcData)
+
+	var protoIssues []*message.SwcTopologyIssueV1
+	for idx := range issues {
+		protoIssues = append(protoIssues, SwcTopologyIssueToProtobuf(&issues[idx]))
+	}
+
+	logger.GetLogger().Println("User " + executorUserMetaInfo.Name + " Validate Swc " + querySwcMetaInfo.Base.Uuid + ", " + strconv.Itoa(len(issues)) + " issues found")
+
+	return &response.ValidateSwcResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Validate Swc Successfully!",
+		},
+		IsValid: len(issues) == 0,
+		Issues:  protoIssues,
+	}, nil
+}
+
+func (D DBMSServerController) GetSwcMorphometry(ctx context.Context, request *request.GetSwcMorphometryRequest) (*response.GetSwcMorphometryResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.GetSwcMorphometryResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.GetSwcMorphometryResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcMorphometryResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcMorphometryResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.GetSwcMorphometryResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	var swcData dbmodel.SwcDataV1
+	if request.GetSwcSnapshotCollectionName() != "" {
+		if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "QuerySnapshotAndIncrementPermission") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+			return &response.GetSwcMorphometryResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: "You don't have permission to access the snapshot of this swc!",
+				},
+			}, nil
+		}
+
+		bFind := false
+		for _, snapshot := range querySwcMetaInfo.SwcSnapshotList {
+			if snapshot.SwcSnapshotCollectionName == request.GetSwcSnapshotCollectionName() {
+				bFind = true
+				break
+			}
+		}
+		if !bFind {
+			return &response.GetSwcMorphometryResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: "Cannot find snapshot " + request.GetSwcSnapshotCollectionName() + " in swc " + querySwcMetaInfo.Base.Uuid,
+				},
+			}, nil
+		}
+
+		if result := dal.QuerySwcSnapshot(request.GetSwcSnapshotCollectionName(), &swcData, dal.GetDbInstance()); !result.Status {
+			return &response.GetSwcMorphometryResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: result.Message,
+				},
+			}, nil
+		}
+	} else {
+		if result := dal.QueryAllSwcData(querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance()); !result.Status {
+			return &response.GetSwcMorphometryResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: result.Message,
+				},
+			}, nil
+		}
+	}
+
+	morphometry := ComputeSwcMorphometry(swcData, request.GetShollStepSize())
+
+	logger.GetLogger().Println("User " + executorUserMetaInfo.Name + " Get SwcMorphometry " + querySwcMetaInfo.Base.Uuid)
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.GetSwcMorphometryResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Get Swc Morphometry Successfully!",
+		},
+		Morphometry: SwcMorphometryToProtobuf(&morphometry),
+	}, nil
+}
+
+func (D DBMSServerController) RenumberSwc(ctx context.Context, request *request.RenumberSwcRequest) (*response.RenumberSwcResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.RenumberSwcResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.RenumberSwcResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RenumberSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RenumberSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "WritePermissionModifySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.RenumberSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	var nodeNParent []dbmodel.NodeNParentV1
+	var detachedNodeNumber int
+	var renumberErr error
+	var updateCount int
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		// renumbered from the nodes of the transaction, a node created concurrently conflicts on the revision
+		var swcData dbmodel.SwcDataV1
+		if result := dal.QueryAllSwcDataWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance()); !result.Status {
+			return result
+		}
+		if nodeNParent, detachedNodeNumber, renumberErr = TopologicalRenumberSwcNodes(swcData); renumberErr != nil {
+			return dal.ReturnWrapper{Status: false, Message: renumberErr.Error()}
+		}
+
+		var result dal.ReturnWrapper
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, nil, func(newRevision int64) dal.ReturnWrapper {
+			var result dal.ReturnWrapper
+			result, updateCount, _, _, _, _, _, _ = dal.UpdateSwcNParentWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &nodeNParent, dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			if updateCount != 0 && querySwcMetaInfo.CurrentIncrementOperationCollectionName != "" {
+				operationRecord := dbmodel.SwcIncrementOperationV1{}
+				operationRecord.Base.Id = primitive.NewObjectID()
+				operationRecord.Base.Uuid = uuid.NewString()
+				operationRecord.Base.DataAccessModelVersion = "V1"
+				operationRecord.IncrementOperation = dal.IncrementOp_UpdateNParent
+				operationRecord.NodeNParent = nodeNParent
+				operationRecord.CreateTime = time.Now()
+				if incrementResult := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !incrementResult.Status {
+					return incrementResult
+				}
+			}
+			return result
+		})
+		return result
+	})
+	if renumberErr != nil {
+		return &response.RenumberSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      errcode.ErrorSwcTopologyValidationFailed,
+				Message: renumberErr.Error(),
+			},
+		}, nil
+	}
+	if !result.Status {
+		return &response.RenumberSwcResponse{
+			MetaInfo:         SwcWriteFailedMetaInfo(result, revisionConflict),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
+		}, nil
+	}
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Renumber Swc " + querySwcMetaInfo.Base.Uuid + ", " + strconv.Itoa(updateCount) + " nodes changed")
+	DailyStatisticsInfo.ModifiedSwcNodeNumber += 1
+
+	var protoNodeNParent []*message.NodeNParentV1
+	for _, nodeNP := range nodeNParent {
+		protoNodeNParent = append(protoNodeNParent, &message.NodeNParentV1{
+			NodeUuid: nodeNP.Uuid,
+			N:        nodeNP.N,
+			Parent:   nodeNP.Parent,
+		})
+	}
+
+	return &response.RenumberSwcResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Renumber Swc Successfully!",
+		},
+		Revision:           revision,
+		UpdateNumber:       int32(updateCount),
+		DetachedNodeNumber: int32(detachedNodeNumber),
+		NodeNParentVec:     protoNodeNParent,
+	}, nil
+}
+
+func (D DBMSServerController) DiffSwcVersions(ctx context.Context, request *request.DiffSwcVersionsRequest) (*response.DiffSwcVersionsResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.DiffSwcVersionsResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.DiffSwcVersionsResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.DiffSwcVersionsResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.DiffSwcVersionsResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "QuerySnapshotAndIncrementPermission") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.DiffSwcVersionsResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access the history of this swc!",
+			},
+		}, nil
+	}
+
+	var oldSwcData dbmodel.SwcDataV1
+	if result := LoadSwcVersion(&querySwcMetaInfo, request.GetOldVersion(), &oldSwcData); !result.Status {
+		return &response.DiffSwcVersionsResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Load old version failed! " + result.Message,
+			},
+		}, nil
+	}
+
+	var newSwcData dbmodel.SwcDataV1
+	if result := LoadSwcVersion(&querySwcMetaInfo, request.GetNewVersion(), &newSwcData); !result.Status {
+		return &response.DiffSwcVersionsResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Load new version failed! " + result.Message,
+			},
+		}, nil
+	}
+
+	addedNodes, deletedNodes, modifiedNodes := DiffSwcVersions(oldSwcData, newSwcData)
+
+	var protoModifiedNodes []*message.SwcNodeDiffV1
+	for idx := range modifiedNodes {
+		protoModifiedNodes = append(protoModifiedNodes, SwcNodeDiffToProtobuf(&modifiedNodes[idx]))
+	}
+
+	logger.GetLogger().Println("User " + executorUserMetaInfo.Name + " Diff Swc Versions of " + querySwcMetaInfo.Base.Uuid)
+
+	return &response.DiffSwcVersionsResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Diff Swc Versions Successfully!",
+		},
+		AddedNodes:    SwcDataV1DbmodelToProtobuf(addedNodes),
+		DeletedNodes:  SwcDataV1DbmodelToProtobuf(deletedNodes),
+		ModifiedNodes: protoModifiedNodes,
+	}, nil
+}
+
+func (D DBMSServerController) GetSwcNodeDataAtTime(ctx context.Context, request *request.GetSwcNodeDataAtTimeRequest) (*response.GetSwcNodeDataAtTimeResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.GetSwcNodeDataAtTimeResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.GetSwcNodeDataAtTimeResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcNodeDataAtTimeResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcNodeDataAtTimeResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "QuerySnapshotAndIncrementPermission") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.GetSwcNodeDataAtTimeResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access the history of this swc!",
+			},
+		}, nil
+	}
+
+	if request.GetVersionTime() == nil {
+		return &response.GetSwcNodeDataAtTimeResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "VersionTime is required!",
+			},
+		}, nil
+	}
+
+	var swcData dbmodel.SwcDataV1
+	result := RebuildSwcNodeDataAtTime(&querySwcMetaInfo, request.GetVersionTime().AsTime(), &swcData)
+	if !result.Status {
+		errorId := ""
+		if IsSwcHistoryRemovedAt(&querySwcMetaInfo, request.GetVersionTime().AsTime()) {
+			errorId = errcode.ErrorSwcHistoryNotAvailable
+		}
+		return &response.GetSwcNodeDataAtTimeResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      errorId,
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + executorUserMetaInfo.Name + " Get SwcNodeData of " + querySwcMetaInfo.Base.Uuid + " at " + request.GetVersionTime().AsTime().String())
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.GetSwcNodeDataAtTimeResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		SwcNodeData: SwcDataV1DbmodelToProtobuf(swcData),
+	}, nil
+}
+
+func (D DBMSServerController) CloneSwc(ctx context.Context, request *request.CloneSwcRequest) (*response.CloneSwcResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.CloneSwcResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.CloneSwcResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.CloneSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionGroupVerify(&executorUserMetaInfo, "CreateSwcPermission") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.CloneSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to create swc!",
+			},
+		}, nil
+	}
+
+	sourceSwcMetaInfo := dbmodel.SwcMetaInfoV1{}
+	sourceSwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&sourceSwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.CloneSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &sourceSwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.CloneSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to query swc data!",
+			},
+		}, nil
+	}
+
+	if request.GetCopyHistory() && !PermissionVerify(&executorUserMetaInfo, &sourceSwcMetaInfo.Permission, "QuerySnapshotAndIncrementPermission") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.CloneSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to query snapshot and increment operation of the swc!",
+			},
+		}, nil
+	}
+
+	var targetProject dbmodel.ProjectMetaInfoV1
+	targetProject.Base.Uuid = request.GetTargetProjectUuid()
+	if targetProject.Base.Uuid == "" {
+		targetProject.Base.Uuid = sourceSwcMetaInfo.BelongingProjectUuid
+	}
+	if targetProject.Base.Uuid != "" {
+		if result := dal.QueryProject(&targetProject, dal.GetDbInstance()); !result.Status {
+			return &response.CloneSwcResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: result.Message,
+				},
+			}, nil
+		}
+	}
+
+	cloneTime := time.Now()
+	var swcMetaInfo dbmodel.SwcMetaInfoV1
+	swcMetaInfo.Base.Id = primitive.NewObjectID()
+	swcMetaInfo.Base.Uuid = uuid.NewString()
+	swcMetaInfo.Base.DataAccessModelVersion = "V1"
+	swcMetaInfo.Name = request.GetNewSwcName()
+	if swcMetaInfo.Name == "" {
+		swcMetaInfo.Name = sourceSwcMetaInfo.Name
+	}
+	swcMetaInfo.Description = request.GetDescription()
+	if swcMetaInfo.Description == "" {
+		swcMetaInfo.Description = sourceSwcMetaInfo.Description
+	}
+	swcMetaInfo.Creator = executorUserMetaInfo.Name
+	swcMetaInfo.SwcType = sourceSwcMetaInfo.SwcType
+	swcMetaInfo.CreateTime = cloneTime
+	swcMetaInfo.LastModifiedTime = cloneTime
+	swcMetaInfo.Permission = NewSwcPermission(&executorUserMetaInfo)
+	swcMetaInfo.BelongingProjectUuid = targetProject.Base.Uuid
+	swcMetaInfo.EnforceTopologyValidation = sourceSwcMetaInfo.EnforceTopologyValidation
+	swcMetaInfo.SourceSwcUuid = sourceSwcMetaInfo.Base.Uuid
+	swcMetaInfo.SourceSwcTime = cloneTime
+	// node versions are copied with the nodes, so the clone starts at the revision they refer to
+	swcMetaInfo.Revision = sourceSwcMetaInfo.Revision
+
+	// the meta info is created last so the clone is never visible half copied, what a failed clone copied is dropped
+	result := CloneSwcCollections(&sourceSwcMetaInfo, &swcMetaInfo, request.GetCopyHistory(), executorUserMetaInfo.Name)
+	if result.Status {
+		result = dal.CreateSwc(swcMetaInfo, dal.GetDbInstance())
+	}
+	if !result.Status {
+		DeleteSwcCollections(&swcMetaInfo)
+		return &response.CloneSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if targetProject.Base.Uuid != "" {
+		var projectFound bool
+		result := dal.AddSwcToProjectWithContext(context.TODO(), targetProject.Base.Uuid, swcMetaInfo.Base.Uuid, &projectFound, dal.GetDbInstance())
+		if result.Status && !projectFound {
+			result = dal.ReturnWrapper{Status: false, Message: "Cannot find project " + targetProject.Base.Uuid + "!"}
+		}
+		if !result.Status {
+			return &response.CloneSwcResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: result.Message,
+				},
+				SwcInfo: SwcMetaInfoV1DbmodelToProtobuf(&swcMetaInfo),
+			}, nil
+		}
+	}
+
+	logger.GetLogger().Println("User " + request.GetUserVerifyInfo().GetUserName() + " Clone Swc " + sourceSwcMetaInfo.Base.Uuid + " to Swc " + swcMetaInfo.Base.Uuid)
+	DailyStatisticsInfo.CreatedSwcNumber += 1
+	return &response.CloneSwcResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Clone swc successfully!",
+		},
+		SwcInfo: SwcMetaInfoV1DbmodelToProtobuf(&swcMetaInfo),
+	}, nil
+}
+
+func (D DBMSServerController) MergeSwc(ctx context.Context, request *request.MergeSwcRequest) (*response.MergeSwcResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.MergeSwcResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.MergeSwcResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if request.GetSourceSwcUuid() == request.GetTargetSwcUuid() {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Source swc and target swc must be different!",
+			},
+		}, nil
+	}
+
+	var sourceSwcMetaInfo dbmodel.SwcMetaInfoV1
+	sourceSwcMetaInfo.Base.Uuid = request.GetSourceSwcUuid()
+	if result := dal.QuerySwc(&sourceSwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &sourceSwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to query source swc data!",
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetTargetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "WritePermissionAddSwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access target swc!",
+			},
+		}, nil
+	}
+
+	var sourceSwcData dbmodel.SwcDataV1
+	if result := dal.QueryAllSwcData(sourceSwcMetaInfo.Base.Uuid, &sourceSwcData, dal.GetDbInstance()); !result.Status {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if (request.GetLinkSourceRootNodeUuid() == "") != (request.GetLinkTargetNodeUuid() == "") {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Source root and target node must be given together to link the merged swc!",
+			},
+		}, nil
+	}
+
+	if len(sourceSwcData) == 0 {
+		return &response.MergeSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  true,
+				Id:      "",
+				Message: "Empty Swc Data",
+			},
+		}, nil
+	}
+
+	// the link target is written with the new revision, so deleting it concurrently conflicts with the merge
+	linkNodeUuids := []string{}
+	if request.GetLinkTargetNodeUuid() != "" {
+		linkNodeUuids = append(linkNodeUuids, request.GetLinkTargetNodeUuid())
+	}
+	var mergedSwcData dbmodel.SwcDataV1
+	var nOffset int32
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var topologyIssues []SwcTopologyIssue
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		// n and the link target are read in the transaction, the revision increase makes concurrent appends conflict
+		var maxTargetN int32
+		if result := dal.QuerySwcMaxNWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &maxTargetN, dal.GetDbInstance()); !result.Status {
+			return result
+		}
+		var linkTargetNode *dbmodel.SwcNodeDataV1
+		if len(linkNodeUuids) != 0 {
+			var linkTargetSwcData dbmodel.SwcDataV1
+			if result := dal.QuerySwcDataByUuidWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, linkNodeUuids, &linkTargetSwcData, dal.GetDbInstance()); !result.Status {
+				return result
+			}
+			if len(linkTargetSwcData) == 0 {
+				return dal.ReturnWrapper{Status: false, Message: "Cannot find node " + request.GetLinkTargetNodeUuid() + " in target swc!"}
+			}
+			linkTargetNode = &linkTargetSwcData[0]
+		}
+		var err error
+		if mergedSwcData, nOffset, err = MergeSwcNodeData(sourceSwcData, maxTargetN, request.GetLinkSourceRootNodeUuid(), linkTargetNode); err != nil {
+			return dal.ReturnWrapper{Status: false, Message: err.Error()}
+		}
+
+		var result dal.ReturnWrapper
+		if result, topologyIssues = VerifySwcTopologyEdit(sessionContext, &querySwcMetaInfo, func(currentSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+			return ApplySwcNodeCreate(currentSwcData, mergedSwcData)
+		}); !result.Status {
+			return result
+		}
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, linkNodeUuids, func(newRevision int64) dal.ReturnWrapper {
+			for idx := range mergedSwcData {
+				mergedSwcData[idx].Version = newRevision
+			}
+			result := dal.CreateSwcDataWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &mergedSwcData, dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			operationRecord := dbmodel.SwcIncrementOperationV1{}
+			operationRecord.Base.Id = primitive.NewObjectID()
+			operationRecord.Base.Uuid = uuid.NewString()
+			operationRecord.Base.DataAccessModelVersion = "V1"
+			operationRecord.IncrementOperation = dal.IncrementOp_Create
+			operationRecord.SwcData = mergedSwcData
+			operationRecord.CreateTime = time.Now()
+			if incrementResult := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !incrementResult.Status {
+				return incrementResult
+			}
+			return result
+		})
+		return result
+	})
+	if !result.Status {
+		return &response.MergeSwcResponse{
+			MetaInfo:         SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
+		}, nil
+	}
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Merge Swc " + sourceSwcMetaInfo.Base.Uuid + " into Swc " + querySwcMetaInfo.Base.Uuid + ", nodes " + strconv.Itoa(len(mergedSwcData)))
+	DailyStatisticsInfo.CreateSwcNodeNumber += 1
+
+	var mergedNodesUuid []string
+	for idx := range mergedSwcData {
+		mergedNodesUuid = append(mergedNodesUuid, mergedSwcData[idx].Base.Uuid)
+	}
+
+	return &response.MergeSwcResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		Revision:        revision,
+		MergedNodesUuid: mergedNodesUuid,
+		NOffset:         nOffset,
+	}, nil
+}
+
+func (D DBMSServerController) DeleteSwcSnapshot(ctx context.Context, request *request.DeleteSwcSnapshotRequest) (*response.DeleteSwcSnapshotResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.DeleteSwcSnapshotResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.DeleteSwcSnapshotResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.DeleteSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.DeleteSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "DeleteSnapshotAndIncrementPermission") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.DeleteSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to delete snapshot!",
+			},
+		}, nil
+	}
+
+	result := DeleteSwcSnapshotAndIncrementList(&querySwcMetaInfo, request.GetSwcSnapshotCollectionName())
+	if !result.Status {
+		return &response.DeleteSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + request.GetUserVerifyInfo().GetUserName() + " Delete Snapshot " + request.GetSwcSnapshotCollectionName() + " of Swc " + querySwcMetaInfo.Base.Uuid)
+	return &response.DeleteSwcSnapshotResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+	}, nil
+}
+
+func (D DBMSServerController) RenameSwcSnapshot(ctx context.Context, request *request.RenameSwcSnapshotRequest) (*response.RenameSwcSnapshotResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.RenameSwcSnapshotResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.RenameSwcSnapshotResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RenameSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RenameSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "CreateSnapshotAndIncrementPermission") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.RenameSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to modify snapshot!",
+			},
+		}, nil
+	}
+
+	idx := FindSwcSnapshotIndex(&querySwcMetaInfo, request.GetSwcSnapshotCollectionName())
+	if idx == -1 {
+		return &response.RenameSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Cannot find snapshot " + request.GetSwcSnapshotCollectionName() + " in swc " + querySwcMetaInfo.Base.Uuid,
+			},
+		}, nil
+	}
+	querySwcMetaInfo.SwcSnapshotList[idx].Label = request.GetLabel()
+
+	result := dal.SetSwcSnapshotLabel(querySwcMetaInfo.Base.Uuid, request.GetSwcSnapshotCollectionName(), request.GetLabel(), dal.GetDbInstance())
+	if !result.Status {
+		return &response.RenameSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	return &response.RenameSwcSnapshotResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Rename snapshot successfully!",
+		},
+		SwcSnapshotMetaInfo: SwcSnapshotMetaInfoV1MetaInfoV1DbmodelToProtobuf(&querySwcMetaInfo.SwcSnapshotList[idx]),
+	}, nil
+}
+
+func (D DBMSServerController) ProtectSwcSnapshot(ctx context.Context, request *request.ProtectSwcSnapshotRequest) (*response.ProtectSwcSnapshotResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.ProtectSwcSnapshotResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.ProtectSwcSnapshotResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ProtectSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ProtectSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "DeleteSnapshotAndIncrementPermission") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.ProtectSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to protect snapshot!",
+			},
+		}, nil
+	}
+
+	idx := FindSwcSnapshotIndex(&querySwcMetaInfo, request.GetSwcSnapshotCollectionName())
+	if idx == -1 {
+		return &response.ProtectSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Cannot find snapshot " + request.GetSwcSnapshotCollectionName() + " in swc " + querySwcMetaInfo.Base.Uuid,
+			},
+		}, nil
+	}
+	querySwcMetaInfo.SwcSnapshotList[idx].Protected = request.GetProtected()
+
+	result := dal.SetSwcSnapshotProtected(querySwcMetaInfo.Base.Uuid, request.GetSwcSnapshotCollectionName(), request.GetProtected(), dal.GetDbInstance())
+	if !result.Status {
+		return &response.ProtectSwcSnapshotResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	return &response.ProtectSwcSnapshotResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Update snapshot protection successfully!",
+		},
+		SwcSnapshotMetaInfo: SwcSnapshotMetaInfoV1MetaInfoV1DbmodelToProtobuf(&querySwcMetaInfo.SwcSnapshotList[idx]),
+	}, nil
+}
+
+func (D DBMSServerController) ListDeleted(ctx context.Context, request *request.ListDeletedRequest) (*response.ListDeletedResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.ListDeletedResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.ListDeletedResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ListDeletedResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var swcMetaInfoList []dbmodel.SwcMetaInfoV1
+	if result := dal.QueryDeletedSwc(&swcMetaInfoList, dal.GetDbInstance()); !result.Status {
+		return &response.ListDeletedResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var projectMetaInfoList []dbmodel.ProjectMetaInfoV1
+	if result := dal.QueryDeletedProject(&projectMetaInfoList, dal.GetDbInstance()); !result.Status {
+		return &response.ListDeletedResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	// only list what the user would be allowed to restore
+	var swcInfo []*message.SwcMetaInfoV1
+	for idx := range swcMetaInfoList {
+		if PermissionVerify(&executorUserMetaInfo, &swcMetaInfoList[idx].Permission, "WritePermissionDeleteSwc") || PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+			swcInfo = append(swcInfo, SwcMetaInfoV1DbmodelToProtobuf(&swcMetaInfoList[idx]))
+		}
+	}
+
+	var projectInfo []*message.ProjectMetaInfoV1
+	for idx := range projectMetaInfoList {
+		if PermissionVerify(&executorUserMetaInfo, &projectMetaInfoList[idx].Permission, "WritePermissionDeleteProject") || PermissionGroupVerify(&executorUserMetaInfo, "AllProjectManagementPermission") {
+			projectInfo = append(projectInfo, ProjectMetaInfoV1DbmodelToProtobuf(&projectMetaInfoList[idx]))
+		}
+	}
+
+	return &response.ListDeletedResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "List deleted swc and project successfully!",
+		},
+		SwcInfo:     swcInfo,
+		ProjectInfo: projectInfo,
+	}, nil
+}
+
+func (D DBMSServerController) RestoreSwc(ctx context.Context, request *request.RestoreSwcRequest) (*response.RestoreSwcResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.RestoreSwcResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.RestoreSwcResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RestoreSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var swcMetaInfo dbmodel.SwcMetaInfoV1
+	swcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwcIncludingDeleted(&swcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RestoreSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &swcMetaInfo.Permission, "WritePermissionDeleteSwc") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.RestoreSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to restore swc!",
+			},
+		}, nil
+	}
+
+	if !swcMetaInfo.IsDeleted {
+		return &response.RestoreSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Swc " + swcMetaInfo.Base.Uuid + " is not deleted!",
+			},
+		}, nil
+	}
+
+	swcMetaInfo.IsDeleted = false
+	swcMetaInfo.DeletedBy = ""
+	swcMetaInfo.DeleteTime = time.Time{}
+
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		if result := dal.SetSwcDeletedWithContext(sessionContext, swcMetaInfo.Base.Uuid, false, "", time.Time{}, dal.GetDbInstance()); !result.Status {
+			return result
+		}
+		if swcMetaInfo.BelongingProjectUuid == "" {
+			return dal.ReturnWrapper{Status: true}
+		}
+		var projectFound bool
+		if result := dal.AddSwcToProjectWithContext(sessionContext, swcMetaInfo.BelongingProjectUuid, swcMetaInfo.Base.Uuid, &projectFound, dal.GetDbInstance()); !result.Status || projectFound {
+			return result
+		}
+		// the project has been purged meanwhile, the swc comes back as a free swc
+		swcMetaInfo.BelongingProjectUuid = ""
+		return dal.SetSwcBelongingProjectWithContext(sessionContext, swcMetaInfo.Base.Uuid, "", dal.GetDbInstance())
+	})
+	if !result.Status {
+		return &response.RestoreSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + request.GetUserVerifyInfo().GetUserName() + " Restore Swc " + swcMetaInfo.Base.Uuid)
+	return &response.RestoreSwcResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Restore swc successfully!",
+		},
+		SwcInfo: SwcMetaInfoV1DbmodelToProtobuf(&swcMetaInfo),
+	}, nil
+}
+
+func (D DBMSServerController) RestoreProject(ctx context.Context, request *request.RestoreProjectRequest) (*response.RestoreProjectResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.RestoreProjectResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.RestoreProjectResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RestoreProjectResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var projectMetaInfo dbmodel.ProjectMetaInfoV1
+	projectMetaInfo.Base.Uuid = request.GetProjectUuid()
+	if result := dal.QueryProjectIncludingDeleted(&projectMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RestoreProjectResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &projectMetaInfo.Permission, "WritePermissionDeleteProject") && !PermissionGroupVerify(&executorUserMetaInfo, "AllProjectManagementPermission") {
+		return &response.RestoreProjectResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to restore project!",
+			},
+		}, nil
+	}
+
+	if !projectMetaInfo.IsDeleted {
+		return &response.RestoreProjectResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Project " + projectMetaInfo.Base.Uuid + " is not deleted!",
+			},
+		}, nil
+	}
+
+	projectMetaInfo.IsDeleted = false
+	projectMetaInfo.DeletedBy = ""
+	projectMetaInfo.DeleteTime = time.Time{}
+	if result := dal.SetProjectDeleted(projectMetaInfo.Base.Uuid, false, "", time.Time{}, dal.GetDbInstance()); !result.Status {
+		return &response.RestoreProjectResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + request.GetUserVerifyInfo().GetUserName() + " Restore Project " + projectMetaInfo.Base.Uuid)
+	return &response.RestoreProjectResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Restore project successfully!",
+		},
+		ProjectInfo: ProjectMetaInfoV1DbmodelToProtobuf(&projectMetaInfo),
+	}, nil
+}
+
+func (D DBMSServerController) ApplySwcEditBatch(ctx context.Context, request *request.ApplySwcEditBatchRequest) (*response.ApplySwcEditBatchResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.ApplySwcEditBatchResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.ApplySwcEditBatchResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ApplySwcEditBatchResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.ApplySwcEditBatchResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var operations []dbmodel.SwcIncrementOperationV1
+	for _, operation := range request.GetOperations() {
+		operations = append(operations, *SwcIncrementOperationV1MetaInfoV1ProtobufToDbmodel(operation))
+	}
+
+	if !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		for _, operation := range operations {
+			permission, ok := SwcEditBatchOperationPermission[operation.IncrementOperation]
+			if ok && !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, permission) {
+				return &response.ApplySwcEditBatchResponse{
+					MetaInfo: &message.ResponseMetaInfoV1{
+						Status:  false,
+						Id:      "",
+						Message: "You don't have permission to access this swc!",
+					},
+				}, nil
+			}
+		}
+	}
+
+	var currentSwcData dbmodel.SwcDataV1
+	if result := dal.QueryAllSwcData(querySwcMetaInfo.Base.Uuid, &currentSwcData, dal.GetDbInstance()); !result.Status {
+		return &response.ApplySwcEditBatchResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	editTime := time.Now()
+	_, err := PrepareSwcEditBatch(currentSwcData, operations, executorUserMetaInfo.Name, editTime)
+	if err != nil {
+		return &response.ApplySwcEditBatchResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      errcode.ErrorSwcEditBatchInvalid,
+				Message: err.Error(),
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Apply Swc edit batch of " + strconv.Itoa(len(operations)) + " operations at " + querySwcMetaInfo.Base.Uuid)
+
+	// the recorded entry itself is applied, so a redo replays exactly what was applied here
+	operationRecord := dbmodel.SwcIncrementOperationV1{}
+	operationRecord.Base.Id = primitive.NewObjectID()
+	operationRecord.Base.Uuid = uuid.NewString()
+	operationRecord.Base.DataAccessModelVersion = "V1"
+	operationRecord.IncrementOperation = dal.IncrementOp_Batch
+	operationRecord.GroupedOperations = operations
+	operationRecord.CreateTime = editTime
+
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var topologyIssues []SwcTopologyIssue
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		var result dal.ReturnWrapper
+		if result, topologyIssues = VerifySwcTopologyEdit(sessionContext, &querySwcMetaInfo, func(currentSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+			return ReplaySwcIncrementOperation(currentSwcData, &operationRecord)
+		}); !result.Status {
+			return result
+		}
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, querySwcMetaInfo.Base.Uuid, request.ExpectedRevision, SwcEditBatchExistingNodeUuids(currentSwcData, operations), func(newRevision int64) dal.ReturnWrapper {
+			for _, operation := range operationRecord.GroupedOperations {
+				if operation.IncrementOperation != dal.IncrementOp_Create {
+					continue
+				}
+				for idx := range operation.SwcData {
+					operation.SwcData[idx].Version = newRevision
+				}
+			}
+			if result := dal.ApplySwcIncrementOperationWithContext(sessionContext, querySwcMetaInfo.Base.Uuid, &operationRecord, dal.GetDbInstance()); !result.Status {
+				return result
+			}
+			if querySwcMetaInfo.CurrentIncrementOperationCollectionName != "" {
+				if result := RecordSwcIncrementOperation(sessionContext, &querySwcMetaInfo, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), newRevision, operationRecord); !result.Status {
+					return result
+				}
+			}
+			return dal.ReturnWrapper{Status: true, Message: "Apply " + strconv.Itoa(len(operations)) + " operations successfully!"}
+		})
+		return result
+	})
+	if !result.Status {
+		return &response.ApplySwcEditBatchResponse{
+			MetaInfo:         SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues),
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
+		}, nil
+	}
+
+	var createdNodesUuid []string
+	for _, operation := range operations {
+		switch operation.IncrementOperation {
+		case dal.IncrementOp_Create:
+			for idx := range operation.SwcData {
+				createdNodesUuid = append(createdNodesUuid, operation.SwcData[idx].Base.Uuid)
+			}
+			DailyStatisticsInfo.CreateSwcNodeNumber += 1
+		case dal.IncrementOp_Delete:
+			DailyStatisticsInfo.DeletedSwcNodeNumber += 1
+		default:
+			DailyStatisticsInfo.ModifiedSwcNodeNumber += 1
+		}
+	}
+
+	return &response.ApplySwcEditBatchResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		Revision:         revision,
+		CreatedNodesUuid: createdNodesUuid,
+	}, nil
+}
+
+func (D DBMSServerController) SubscribeSwcChanges(request *request.SubscribeSwcChangesRequest, stream service.DBMS_SubscribeSwcChangesServer) error {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return stream.Send(&response.SubscribeSwcChangesResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		})
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return stream.Send(&response.SubscribeSwcChangesResponse{
+			MetaInfo: &responseMetaInfo,
+		})
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return stream.Send(&response.SubscribeSwcChangesResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		})
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return stream.Send(&response.SubscribeSwcChangesResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		})
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return stream.Send(&response.SubscribeSwcChangesResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		})
+	}
+
+	// subscribe before reading the revision, so no change is missed between the two
+	subscription := SubscribeSwcChange(querySwcMetaInfo.Base.Uuid)
+	defer UnsubscribeSwcChange(subscription)
+
+	var revision int64
+	if result := dal.QuerySwcRevisionWithContext(context.TODO(), querySwcMetaInfo.Base.Uuid, &revision, dal.GetDbInstance()); !result.Status {
+		return stream.Send(&response.SubscribeSwcChangesResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		})
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Subscribe Swc changes " + querySwcMetaInfo.Base.Uuid)
+
+	if err := stream.Send(&response.SubscribeSwcChangesResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Subscribe Swc changes successfully!",
+		},
+		SwcUuid:  querySwcMetaInfo.Base.Uuid,
+		Revision: revision,
+	}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Unsubscribe Swc changes " + querySwcMetaInfo.Base.Uuid)
+			return nil
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return stream.Send(&response.SubscribeSwcChangesResponse{
+					MetaInfo: &message.ResponseMetaInfoV1{
+						Status:  false,
+						Id:      "",
+						Message: "Swc changes were dropped because the subscriber fell behind, please reload the swc and subscribe again!",
+					},
+					SwcUuid:        querySwcMetaInfo.Base.Uuid,
+					ReloadRequired: true,
+				})
+			}
+			if err := stream.Send(SwcChangeEventToProtobuf(&event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (D DBMSServerController) GetSwcChangesSince(ctx context.Context, request *request.GetSwcChangesSinceRequest) (*response.GetSwcChangesSinceResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.GetSwcChangesSinceResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.GetSwcChangesSinceResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcChangesSinceResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcChangesSinceResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.GetSwcChangesSinceResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	// the revision and the increment operation lists come from the same read of the swc meta info
+	currentRevision := querySwcMetaInfo.Revision
+
+	var sinceRevision int64
+	available := true
+	result := dal.ReturnWrapper{Status: true, Message: ""}
+	if request.SinceRevision != nil {
+		sinceRevision = request.GetSinceRevision()
+	} else if request.GetSinceTime() != nil {
+		sinceRevision, available, result = FindSwcRevisionAtTime(&querySwcMetaInfo, request.GetSinceTime().AsTime())
+	} else {
+		return &response.GetSwcChangesSinceResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "SinceRevision or SinceTime is required!",
+			},
+		}, nil
+	}
+
+	var operations dbmodel.SwcIncrementOperationListV1
+	if result.Status && available {
+		operations, available, result = CollectSwcChangesSinceRevision(&querySwcMetaInfo, sinceRevision, currentRevision)
+	}
+	if !result.Status {
+		return &response.GetSwcChangesSinceResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !available {
+		logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Get Swc changes of " + querySwcMetaInfo.Base.Uuid + ", full reload required")
+		return &response.GetSwcChangesSinceResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  true,
+				Id:      "",
+				Message: result.Message,
+			},
+			Revision:           currentRevision,
+			SinceRevision:      sinceRevision,
+			FullReloadRequired: true,
+		}, nil
+	}
+
+	var protoMessage message.SwcIncrementOperationListV1
+	for idx := range operations {
+		protoMessage.SwcIncrementOperation = append(protoMessage.SwcIncrementOperation, SwcIncrementOperationListV1DbmodelToProtobuf(&operations[idx]))
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Get Swc changes of " + querySwcMetaInfo.Base.Uuid + " since revision " + strconv.FormatInt(sinceRevision, 10) + ", " + strconv.Itoa(len(operations)) + " operations")
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.GetSwcChangesSinceResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		Revision:                  currentRevision,
+		SinceRevision:             sinceRevision,
+		SwcIncrementOperationList: &protoMessage,
+	}, nil
+}
+
+func (D DBMSServerController) GetSwcNodeHistory(ctx context.Context, request *request.GetSwcNodeHistoryRequest) (*response.GetSwcNodeHistoryResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.GetSwcNodeHistoryResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.GetSwcNodeHistoryResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcNodeHistoryResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcNodeHistoryResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.GetSwcNodeHistoryResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	if request.GetNodeUuid() == "" {
+		return &response.GetSwcNodeHistoryResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "NodeUuid is required!",
+			},
+		}, nil
+	}
+
+	history, historyStartTime, result := GetSwcNodeHistory(&querySwcMetaInfo, request.GetNodeUuid())
+	if !result.Status {
+		return &response.GetSwcNodeHistoryResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var protoMessage []*message.SwcNodeHistoryEntryV1
+	for idx := range history {
+		protoMessage = append(protoMessage, SwcNodeChangeToProtobuf(&history[idx]))
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Get Swc node history of " + request.GetNodeUuid() + " in " + querySwcMetaInfo.Base.Uuid + ", " + strconv.Itoa(len(history)) + " changes")
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.GetSwcNodeHistoryResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		History:          protoMessage,
+		HistoryStartTime: timestamppb.New(historyStartTime),
+	}, nil
+}
+
+func (D DBMSServerController) BlameSwc(ctx context.Context, request *request.BlameSwcRequest) (*response.BlameSwcResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.BlameSwcResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.BlameSwcResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.BlameSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.BlameSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.BlameSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	var swcData dbmodel.SwcDataV1
+	result := dal.QueryAllSwcData(querySwcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance())
+	if !result.Status {
+		return &response.BlameSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	blames, historyStartTime, result := BlameSwc(&querySwcMetaInfo, swcData)
+	if !result.Status {
+		return &response.BlameSwcResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var protoMessage []*message.SwcNodeBlameV1
+	for idx := range blames {
+		protoMessage = append(protoMessage, SwcNodeBlameToProtobuf(&blames[idx]))
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Blame Swc " + querySwcMetaInfo.Base.Uuid)
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.BlameSwcResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		NodeBlame:        protoMessage,
+		HistoryStartTime: timestamppb.New(historyStartTime),
+	}, nil
+}
+
+func (D DBMSServerController) StreamIncrementOperation(request *request.StreamIncrementOperationRequest, stream service.DBMS_StreamIncrementOperationServer) error {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return stream.Send(&response.StreamIncrementOperationResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		})
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return stream.Send(&response.StreamIncrementOperationResponse{
+			MetaInfo: &responseMetaInfo,
+		})
+	}
+
+	var sendErr error
+	operationNumber := 0
+	result := dal.IterateSwcIncrementOperationByFilter(stream.Context(), request.GetIncrementOperationCollectionName(), SwcIncrementOperationFilterFromRequest(request), func(operation dbmodel.SwcIncrementOperationV1) bool {
+		sendErr = stream.Send(&response.StreamIncrementOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  true,
+				Id:      "",
+				Message: "Increment operation",
+			},
+			SwcIncrementOperation: SwcIncrementOperationListV1DbmodelToProtobuf(&operation),
+		})
+		operationNumber++
+		return sendErr == nil
+	}, dal.GetDbInstance())
+	if sendErr != nil {
+		return sendErr
+	}
+	if !result.Status {
+		return stream.Send(&response.StreamIncrementOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		})
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Stream increment operation of " + request.GetIncrementOperationCollectionName() + ", " + strconv.Itoa(operationNumber) + " operations")
+	return nil
+}
+
+func (D DBMSServerController) UndoLastOperation(ctx context.Context, request *request.UndoLastOperationRequest) (*response.UndoLastOperationResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.UndoLastOperationResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.UndoLastOperationResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.UndoLastOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.UndoLastOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	editTime := time.Now()
+	plan, conflictNodeUuids, result := PlanSwcUndo(&querySwcMetaInfo, executorUserMetaInfo.Name, false, editTime)
+	if len(conflictNodeUuids) != 0 {
+		return &response.UndoLastOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      errcode.ErrorSwcUndoConflict,
+				Message: result.Message,
+			},
+			RevisionConflict: &message.SwcRevisionConflictV1{
+				CurrentRevision:   querySwcMetaInfo.Revision,
+				ExpectedRevision:  querySwcMetaInfo.Revision,
+				ConflictNodeUuids: conflictNodeUuids,
+			},
+		}, nil
+	}
+	if !result.Status {
+		return &response.UndoLastOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+	if plan == nil {
+		return &response.UndoLastOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      errcode.ErrorSwcNothingToUndo,
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	// the same permission the operation applied for the undo needs on its own
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, SwcEditBatchOperationPermission[plan.Operation.IncrementOperation]) && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.UndoLastOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Undo operation " + plan.Target.Base.Uuid + " at " + querySwcMetaInfo.Base.Uuid)
+
+	revision, revisionConflict, topologyIssues, result := ApplySwcUndoPlan(&querySwcMetaInfo, plan, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), querySwcMetaInfo.Revision)
+	if !result.Status {
+		metaInfo := SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues)
+		if revisionConflict != nil {
+			metaInfo.Id = errcode.ErrorSwcUndoConflict
+		}
+		return &response.UndoLastOperationResponse{
+			MetaInfo:         metaInfo,
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
+		}, nil
+	}
+
+	plan.Operation.Creator = executorUserMetaInfo.Name
+	plan.Operation.Revision = revision
+	DailyStatisticsInfo.ModifiedSwcNodeNumber += 1
+
+	return &response.UndoLastOperationResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		Revision:                     revision,
+		TargetIncrementOperationUuid: plan.Target.Base.Uuid,
+		IncrementOperation:           SwcIncrementOperationListV1DbmodelToProtobuf(&plan.Operation),
+	}, nil
+}
+
+func (D DBMSServerController) RedoOperation(ctx context.Context, request *request.RedoOperationRequest) (*response.RedoOperationResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.RedoOperationResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.RedoOperationResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RedoOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.RedoOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	editTime := time.Now()
+	plan, conflictNodeUuids, result := PlanSwcUndo(&querySwcMetaInfo, executorUserMetaInfo.Name, true, editTime)
+	if len(conflictNodeUuids) != 0 {
+		return &response.RedoOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      errcode.ErrorSwcUndoConflict,
+				Message: result.Message,
+			},
+			RevisionConflict: &message.SwcRevisionConflictV1{
+				CurrentRevision:   querySwcMetaInfo.Revision,
+				ExpectedRevision:  querySwcMetaInfo.Revision,
+				ConflictNodeUuids: conflictNodeUuids,
+			},
+		}, nil
+	}
+	if !result.Status {
+		return &response.RedoOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+	if plan == nil {
+		return &response.RedoOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      errcode.ErrorSwcNothingToUndo,
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	// the same permission the operation applied for the redo needs on its own
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, SwcEditBatchOperationPermission[plan.Operation.IncrementOperation]) && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.RedoOperationResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Want Redo operation " + plan.Target.Base.Uuid + " at " + querySwcMetaInfo.Base.Uuid)
+
+	revision, revisionConflict, topologyIssues, result := ApplySwcUndoPlan(&querySwcMetaInfo, plan, NewSwcIncrementOperationExecutor(ctx, request.GetMetaInfo(), &executorUserMetaInfo), querySwcMetaInfo.Revision)
+	if !result.Status {
+		metaInfo := SwcEditFailedMetaInfo(result, revisionConflict, topologyIssues)
+		if revisionConflict != nil {
+			metaInfo.Id = errcode.ErrorSwcUndoConflict
+		}
+		return &response.RedoOperationResponse{
+			MetaInfo:         metaInfo,
+			RevisionConflict: SwcRevisionConflictToProtobuf(revisionConflict),
+		}, nil
+	}
+
+	plan.Operation.Creator = executorUserMetaInfo.Name
+	plan.Operation.Revision = revision
+	DailyStatisticsInfo.ModifiedSwcNodeNumber += 1
+
+	return &response.RedoOperationResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		Revision:                     revision,
+		TargetIncrementOperationUuid: plan.Target.Base.Uuid,
+		IncrementOperation:           SwcIncrementOperationListV1DbmodelToProtobuf(&plan.Operation),
+	}, nil
+}
+
+func (D DBMSServerController) GetSwcNodeDataInBoundingBox(ctx context.Context, request *request.GetSwcNodeDataInBoundingBoxRequest) (*response.GetSwcNodeDataInBoundingBoxResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.GetSwcNodeDataInBoundingBoxResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, onlineUserInfoCache := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.GetSwcNodeDataInBoundingBoxResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcNodeDataInBoundingBoxResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcNodeDataInBoundingBoxResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.GetSwcNodeDataInBoundingBoxResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	boundingBox := request.GetBoundingBox()
+	if boundingBox == nil || boundingBox.GetMinX() > boundingBox.GetMaxX() || boundingBox.GetMinY() > boundingBox.GetMaxY() || boundingBox.GetMinZ() > boundingBox.GetMaxZ() {
+		return &response.GetSwcNodeDataInBoundingBoxResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "A bounding box with min not greater than max is required!",
+			},
+		}, nil
+	}
+
+	query := dal.SwcNodeBoundingBoxQuery{
+		SwcNodeBoundingBox: SwcNodeBoundingBoxProtobufToDal(boundingBox),
+		Types:              request.GetTypes(),
+		Limit:              int64(max(request.GetLimit(), 0)),
+	}
+
+	var swcData dbmodel.SwcDataV1
+	var truncated bool
+	result := dal.QuerySwcDataInBoundingBox(querySwcMetaInfo.Base.Uuid, query, &swcData, &truncated, dal.GetDbInstance())
+	if !result.Status {
+		return &response.GetSwcNodeDataInBoundingBoxResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + onlineUserInfoCache.UserInfo.Name + " Get SwcData in bounding box " + querySwcMetaInfo.Base.Uuid + ", " + strconv.Itoa(len(swcData)) + " nodes")
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.GetSwcNodeDataInBoundingBoxResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		SwcNodeData: SwcDataV1DbmodelToProtobuf(swcData),
+		Truncated:   truncated,
+	}, nil
+}
+
+func (D DBMSServerController) FindSwcsInRegion(ctx context.Context, request *request.FindSwcsInRegionRequest) (*response.FindSwcsInRegionResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.FindSwcsInRegionResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.FindSwcsInRegionResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.FindSwcsInRegionResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var queryProjectMetaInfo dbmodel.ProjectMetaInfoV1
+	queryProjectMetaInfo.Base.Uuid = request.GetProjectUuid()
+	if result := dal.QueryProject(&queryProjectMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.FindSwcsInRegionResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &queryProjectMetaInfo.Permission, "ReadPerimissionQueryProject") && !PermissionGroupVerify(&executorUserMetaInfo, "AllProjectManagementPermission") {
+		return &response.FindSwcsInRegionResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this project!",
+			},
+		}, nil
+	}
+
+	var region SwcSpatialRegion
+	if sphere := request.GetSphere(); sphere != nil && sphere.GetRadius() >= 0 {
+		region = NewSwcSphereRegion(float64(sphere.GetCenterX()), float64(sphere.GetCenterY()), float64(sphere.GetCenterZ()), float64(sphere.GetRadius()))
+	} else if boundingBox := request.GetBoundingBox(); boundingBox != nil && boundingBox.GetMinX() <= boundingBox.GetMaxX() && boundingBox.GetMinY() <= boundingBox.GetMaxY() && boundingBox.GetMinZ() <= boundingBox.GetMaxZ() {
+		region = NewSwcBoxRegion(SwcNodeBoundingBoxProtobufToDal(boundingBox))
+	} else {
+		return &response.FindSwcsInRegionResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "A sphere with a radius not below 0 or a bounding box with min not greater than max is required!",
+			},
+		}, nil
+	}
+
+	var swcMetaInfos []dbmodel.SwcMetaInfoV1
+	for _, value := range queryProjectMetaInfo.SwcList {
+		var swcInfo dbmodel.SwcMetaInfoV1
+		swcInfo.Base.Uuid = value
+		if result := dal.QuerySwc(&swcInfo, dal.GetDbInstance()); !result.Status {
+			return &response.FindSwcsInRegionResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: result.Message,
+				},
+			}, nil
+		}
+		// swcs the user may not read are left out instead of failing the whole search
+		if swcInfo.IsDeleted || !PermissionVerify(&executorUserMetaInfo, &swcInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+			continue
+		}
+		swcMetaInfos = append(swcMetaInfos, swcInfo)
+	}
+
+	hits, result := FindSwcsInRegion(swcMetaInfos, region)
+	if !result.Status {
+		return &response.FindSwcsInRegionResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var protoMessage []*message.SwcRegionHitV1
+	for idx := range hits {
+		protoMessage = append(protoMessage, SwcRegionHitToProtobuf(&hits[idx]))
+	}
+
+	logger.GetLogger().Println("User " + request.GetUserVerifyInfo().GetUserName() + " Find swcs in region of project " + queryProjectMetaInfo.Base.Uuid + ", " + strconv.Itoa(len(hits)) + " swcs")
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.FindSwcsInRegionResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		SwcRegionHit: protoMessage,
+	}, nil
+}
+
+func (D DBMSServerController) FindNearestSwcNodes(ctx context.Context, request *request.FindNearestSwcNodesRequest) (*response.FindNearestSwcNodesResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.FindNearestSwcNodesResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.FindNearestSwcNodesResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.FindNearestSwcNodesResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	query := SwcNearestNodeQuery{
+		X:           float64(request.GetX()),
+		Y:           float64(request.GetY()),
+		Z:           float64(request.GetZ()),
+		Number:      int(request.GetK()),
+		MaxDistance: math.Inf(1),
+		TipsOnly:    request.GetTipsOnly(),
+	}
+	if query.Number <= 0 {
+		query.Number = 1
+	}
+	if query.Number > SwcNearestNodeMaxNumber {
+		return &response.FindNearestSwcNodesResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "K cannot be greater than " + strconv.Itoa(SwcNearestNodeMaxNumber) + "!",
+			},
+		}, nil
+	}
+	// a max distance of 0 or below means the search is not limited
+	if request.GetMaxDistance() > 0 {
+		query.MaxDistance = float64(request.GetMaxDistance())
+	}
+
+	var swcMetaInfos []dbmodel.SwcMetaInfoV1
+	var searchTarget string
+	if request.GetSwcUuid() != "" {
+		var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+		querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+		if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+			return &response.FindNearestSwcNodesResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: result.Message,
+				},
+			}, nil
+		}
+
+		if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+			return &response.FindNearestSwcNodesResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: "You don't have permission to access this swc!",
+				},
+			}, nil
+		}
+		swcMetaInfos = append(swcMetaInfos, querySwcMetaInfo)
+		searchTarget = "swc " + querySwcMetaInfo.Base.Uuid
+	} else if request.GetProjectUuid() != "" {
+		var queryProjectMetaInfo dbmodel.ProjectMetaInfoV1
+		queryProjectMetaInfo.Base.Uuid = request.GetProjectUuid()
+		if result := dal.QueryProject(&queryProjectMetaInfo, dal.GetDbInstance()); !result.Status {
+			return &response.FindNearestSwcNodesResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: result.Message,
+				},
+			}, nil
+		}
+
+		if !PermissionVerify(&executorUserMetaInfo, &queryProjectMetaInfo.Permission, "ReadPerimissionQueryProject") && !PermissionGroupVerify(&executorUserMetaInfo, "AllProjectManagementPermission") {
+			return &response.FindNearestSwcNodesResponse{
+				MetaInfo: &message.ResponseMetaInfoV1{
+					Status:  false,
+					Id:      "",
+					Message: "You don't have permission to access this project!",
+				},
+			}, nil
+		}
+
+		for _, value := range queryProjectMetaInfo.SwcList {
+			var swcInfo dbmodel.SwcMetaInfoV1
+			swcInfo.Base.Uuid = value
+			if result := dal.QuerySwc(&swcInfo, dal.GetDbInstance()); !result.Status {
+				return &response.FindNearestSwcNodesResponse{
+					MetaInfo: &message.ResponseMetaInfoV1{
+						Status:  false,
+						Id:      "",
+						Message: result.Message,
+					},
+				}, nil
+			}
+			// swcs the user may not read are left out instead of failing the whole search
+			if swcInfo.IsDeleted || !PermissionVerify(&executorUserMetaInfo, &swcInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+				continue
+			}
+			swcMetaInfos = append(swcMetaInfos, swcInfo)
+		}
+		searchTarget = "project " + queryProjectMetaInfo.Base.Uuid
+	} else {
+		return &response.FindNearestSwcNodesResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "A swc uuid or a project uuid is required!",
+			},
+		}, nil
+	}
+
+	nearestNodes, result := FindNearestSwcNodes(swcMetaInfos, query)
+	if !result.Status {
+		return &response.FindNearestSwcNodesResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var protoMessage []*message.SwcNearestNodeV1
+	for idx := range nearestNodes {
+		protoMessage = append(protoMessage, SwcNearestNodeToProtobuf(&nearestNodes[idx]))
+	}
+
+	logger.GetLogger().Println("User " + request.GetUserVerifyInfo().GetUserName() + " Find nearest nodes in " + searchTarget + ", " + strconv.Itoa(len(nearestNodes)) + " nodes")
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.FindNearestSwcNodesResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		NearestNode: protoMessage,
+	}, nil
+}
+
+func (D DBMSServerController) GetSwcSimplifiedNodeData(ctx context.Context, request *request.GetSwcSimplifiedNodeDataRequest) (*response.GetSwcSimplifiedNodeDataResponse, error) {
+	apiVersionVerifyResult := RequestApiVersionVerify(request.GetMetaInfo())
+	if !apiVersionVerifyResult.Status {
+		return &response.GetSwcSimplifiedNodeDataResponse{
+			MetaInfo: &apiVersionVerifyResult,
+		}, nil
+	}
+
+	responseMetaInfo, _ := UserTokenVerify(request.GetUserVerifyInfo())
+	if !responseMetaInfo.Status {
+		return &response.GetSwcSimplifiedNodeDataResponse{
+			MetaInfo: &responseMetaInfo,
+		}, nil
+	}
+
+	executorUserMetaInfo := dbmodel.UserMetaInfoV1{
+		Name: request.GetUserVerifyInfo().GetUserName(),
+	}
+	if result := dal.QueryUserByName(&executorUserMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcSimplifiedNodeDataResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	var querySwcMetaInfo dbmodel.SwcMetaInfoV1
+	querySwcMetaInfo.Base.Uuid = request.GetSwcUuid()
+	if result := dal.QuerySwc(&querySwcMetaInfo, dal.GetDbInstance()); !result.Status {
+		return &response.GetSwcSimplifiedNodeDataResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	if !PermissionVerify(&executorUserMetaInfo, &querySwcMetaInfo.Permission, "ReadPerimissionQuerySwcData") && !PermissionGroupVerify(&executorUserMetaInfo, "AllSwcManagementPermission") {
+		return &response.GetSwcSimplifiedNodeDataResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "You don't have permission to access this swc!",
+			},
+		}, nil
+	}
+
+	if request.GetTolerance() < 0 || request.GetNodeBudget() < 0 {
+		return &response.GetSwcSimplifiedNodeDataResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: "Tolerance and node budget cannot be negative!",
+			},
+		}, nil
+	}
+
+	swcData, originalNodeNumber, result := QuerySwcSimplifiedData(&querySwcMetaInfo, float64(request.GetTolerance()), int(request.GetNodeBudget()))
+	if !result.Status {
+		return &response.GetSwcSimplifiedNodeDataResponse{
+			MetaInfo: &message.ResponseMetaInfoV1{
+				Status:  false,
+				Id:      "",
+				Message: result.Message,
+			},
+		}, nil
+	}
+
+	logger.GetLogger().Println("User " + request.GetUserVerifyInfo().GetUserName() + " Get SwcSimplifiedNodeData " + querySwcMetaInfo.Base.Uuid + ", " + strconv.Itoa(len(swcData)) + " of " + strconv.FormatInt(originalNodeNumber, 10) + " nodes")
+	DailyStatisticsInfo.NodeQueryNumber += 1
+
+	return &response.GetSwcSimplifiedNodeDataResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: result.Message,
+		},
+		SwcNodeData:        SwcDataV1DbmodelToProtobuf(swcData),
+		Revision:           querySwcMetaInfo.Revision,
+		OriginalNodeNumber: originalNodeNumber,
+	}, nil
+}
@@ -0,0 +1,14 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"strconv"
+)
+
+// newTestSwcNode builds a node for tests, the uuid is derived from n.
+func newTestSwcNode(n int32, parent int32, nodeType int32, x float32, y float32, z float32) dbmodel.SwcNodeDataV1 {
+	var node dbmodel.SwcNodeDataV1
+	node.Base.Uuid = "node-" + strconv.Itoa(int(n))
+	node.SwcNodeInternalData = dbmodel.SwcNodeInternalDataV1{N: n, Type: nodeType, X: x, Y: y, Z: z, Radius: 1, Parent: parent}
+	return node
+}
@@ -0,0 +1,201 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"math"
+	"sort"
+)
+
+const (
+	DefaultShollStepSize = 10.0
+	MaxShollShellNumber  = 100000
+	SomaSwcNodeType      = 1
+)
+
+type SwcBoundingBox struct {
+	MinX, MinY, MinZ float32
+	MaxX, MaxY, MaxZ float32
+}
+
+type SwcTypeLength struct {
+	Type   int32
+	Length float64
+}
+
+type ShollIntersection struct {
+	Radius        float64
+	Intersections int32
+}
+
+type SwcMorphometry struct {
+	NodeNumber           int32
+	TreeNumber           int32
+	BranchPointNumber    int32
+	TipNumber            int32
+	TotalLength          float64
+	MaxPathDistance      float64
+	MaxEuclideanDistance float64
+	BoundingBox          SwcBoundingBox
+	TypeLengthList       []SwcTypeLength
+	ShollStepSize        float64
+	ShollProfile         []ShollIntersection
+}
+
+func swcNodeDistance(a *dbmodel.SwcNodeInternalDataV1, b *dbmodel.SwcNodeInternalDataV1) float64 {
+	dx := float64(a.X - b.X)
+	dy := float64(a.Y - b.Y)
+	dz := float64(a.Z - b.Z)
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+func ComputeSwcBoundingBox(swcData dbmodel.SwcDataV1) SwcBoundingBox {
+	var boundingBox SwcBoundingBox
+	for idx, swcNodeData := range swcData {
+		nodeData := swcNodeData.SwcNodeInternalData
+		if idx == 0 {
+			boundingBox = SwcBoundingBox{nodeData.X, nodeData.Y, nodeData.Z, nodeData.X, nodeData.Y, nodeData.Z}
+			continue
+		}
+		boundingBox.MinX = min(boundingBox.MinX, nodeData.X)
+		boundingBox.MinY = min(boundingBox.MinY, nodeData.Y)
+		boundingBox.MinZ = min(boundingBox.MinZ, nodeData.Z)
+		boundingBox.MaxX = max(boundingBox.MaxX, nodeData.X)
+		boundingBox.MaxY = max(boundingBox.MaxY, nodeData.Y)
+		boundingBox.MaxZ = max(boundingBox.MaxZ, nodeData.Z)
+	}
+	return boundingBox
+}
+
+func swcTypeLengthList(typeLengths map[int32]float64) []SwcTypeLength {
+	var typeLengthList []SwcTypeLength
+	for nodeType, length := range typeLengths {
+		typeLengthList = append(typeLengthList, SwcTypeLength{nodeType, length})
+	}
+	sort.Slice(typeLengthList, func(i, j int) bool {
+		return typeLengthList[i].Type < typeLengthList[j].Type
+	})
+	return typeLengthList
+}
+
+// ComputeSwcMorphometry measures the tree described by n/parent. The length of the segment between a node and its
+// parent is attributed to the node's type. Sholl shells are centered on the first soma root, or the first root if
+// there is no soma. Nodes which can't be reached from a root (cycles) only count towards node number and bounding box.
+// When there is no root at all, only counts, bounding box and lengths are measured, path and Euclidean distances and
+// the Sholl profile are left empty.
+func ComputeSwcMorphometry(swcData dbmodel.SwcDataV1, shollStepSize float64) SwcMorphometry {
+	var morphometry SwcMorphometry
+	morphometry.NodeNumber = int32(len(swcData))
+	if len(swcData) == 0 {
+		return morphometry
+	}
+	morphometry.BoundingBox = ComputeSwcBoundingBox(swcData)
+
+	nodeIndexByN := make(map[int32]int, len(swcData))
+	for idx, swcNodeData := range swcData {
+		if _, ok := nodeIndexByN[swcNodeData.SwcNodeInternalData.N]; !ok {
+			nodeIndexByN[swcNodeData.SwcNodeInternalData.N] = idx
+		}
+	}
+
+	parentIndexes := make([]int, len(swcData))
+	childrenIndexes := make([][]int, len(swcData))
+	var rootIndexes []int
+	for idx, swcNodeData := range swcData {
+		parentIndexes[idx] = -1
+		if parentIdx, ok := nodeIndexByN[swcNodeData.SwcNodeInternalData.Parent]; ok && !IsSwcRootNode(&swcNodeData.SwcNodeInternalData) {
+			parentIndexes[idx] = parentIdx
+			childrenIndexes[parentIdx] = append(childrenIndexes[parentIdx], idx)
+		} else {
+			rootIndexes = append(rootIndexes, idx)
+		}
+	}
+	morphometry.TreeNumber = int32(len(rootIndexes))
+
+	typeLengths := make(map[int32]float64)
+	if len(rootIndexes) == 0 {
+		// every node is on a parent loop, there is no root to center Sholl shells on or to measure paths from
+		for idx := range swcData {
+			nodeData := &swcData[idx].SwcNodeInternalData
+			length := swcNodeDistance(nodeData, &swcData[parentIndexes[idx]].SwcNodeInternalData)
+			morphometry.TotalLength += length
+			typeLengths[nodeData.Type] += length
+			if len(childrenIndexes[idx]) == 0 {
+				morphometry.TipNumber++
+			} else if len(childrenIndexes[idx]) >= 2 {
+				morphometry.BranchPointNumber++
+			}
+		}
+		morphometry.TypeLengthList = swcTypeLengthList(typeLengths)
+		return morphometry
+	}
+
+	centerIdx := rootIndexes[0]
+	for _, idx := range rootIndexes {
+		if swcData[idx].SwcNodeInternalData.Type == SomaSwcNodeType {
+			centerIdx = idx
+			break
+		}
+	}
+	center := swcData[centerIdx].SwcNodeInternalData
+
+	pathDistances := make([]float64, len(swcData))
+	var segmentRanges [][2]float64
+
+	stack := append([]int{}, rootIndexes...)
+	for len(stack) > 0 {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		nodeData := &swcData[idx].SwcNodeInternalData
+		centerDistance := swcNodeDistance(nodeData, &center)
+		morphometry.MaxEuclideanDistance = max(morphometry.MaxEuclideanDistance, centerDistance)
+
+		if parentIdx := parentIndexes[idx]; parentIdx != -1 {
+			parentData := &swcData[parentIdx].SwcNodeInternalData
+			length := swcNodeDistance(nodeData, parentData)
+			morphometry.TotalLength += length
+			typeLengths[nodeData.Type] += length
+			pathDistances[idx] = pathDistances[parentIdx] + length
+			morphometry.MaxPathDistance = max(morphometry.MaxPathDistance, pathDistances[idx])
+
+			parentCenterDistance := swcNodeDistance(parentData, &center)
+			segmentRanges = append(segmentRanges, [2]float64{min(centerDistance, parentCenterDistance), max(centerDistance, parentCenterDistance)})
+
+			if len(childrenIndexes[idx]) == 0 {
+				morphometry.TipNumber++
+			}
+		}
+
+		if len(childrenIndexes[idx]) >= 2 {
+			morphometry.BranchPointNumber++
+		}
+
+		stack = append(stack, childrenIndexes[idx]...)
+	}
+
+	morphometry.TypeLengthList = swcTypeLengthList(typeLengths)
+
+	if shollStepSize <= 0 {
+		shollStepSize = DefaultShollStepSize
+	}
+	if morphometry.MaxEuclideanDistance/shollStepSize > MaxShollShellNumber {
+		shollStepSize = morphometry.MaxEuclideanDistance / MaxShollShellNumber
+	}
+	morphometry.ShollStepSize = shollStepSize
+
+	shellNumber := int(math.Floor(morphometry.MaxEuclideanDistance / shollStepSize))
+	intersections := make([]int32, shellNumber+1)
+	for _, segmentRange := range segmentRanges {
+		// a segment crosses shell k when its near end is inside the shell and its far end is on or outside it
+		first := int(math.Floor(segmentRange[0]/shollStepSize)) + 1
+		last := int(math.Floor(segmentRange[1] / shollStepSize))
+		for shell := first; shell <= last && shell <= shellNumber; shell++ {
+			intersections[shell]++
+		}
+	}
+	for shell := 1; shell <= shellNumber; shell++ {
+		morphometry.ShollProfile = append(morphometry.ShollProfile, ShollIntersection{float64(shell) * shollStepSize, intersections[shell]})
+	}
+
+	return morphometry
+}
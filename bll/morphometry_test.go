@@ -0,0 +1,98 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"math"
+	"testing"
+)
+
+func TestComputeSwcMorphometry(t *testing.T) {
+	tests := []struct {
+		name              string
+		swcData           dbmodel.SwcDataV1
+		treeNumber        int32
+		branchPointNumber int32
+		tipNumber         int32
+		totalLength       float64
+		maxPathDistance   float64
+		shollShellNumber  int
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "rootless cycle",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, 2, 3, 0, 0, 0),
+				newTestSwcNode(2, 1, 3, 3, 4, 0),
+			},
+			totalLength: 10,
+		},
+		{
+			name: "rootless cycle with a hanging branch",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, 3, 3, 0, 0, 0),
+				newTestSwcNode(2, 1, 3, 1, 0, 0),
+				newTestSwcNode(3, 2, 3, 1, 1, 0),
+				newTestSwcNode(4, 3, 3, 1, 2, 0),
+			},
+			branchPointNumber: 1,
+			tipNumber:         1,
+			totalLength:       1 + 1 + math.Sqrt2 + 1,
+		},
+		{
+			name: "branched tree",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(2, 1, 3, 10, 0, 0),
+				newTestSwcNode(3, 2, 3, 20, 0, 0),
+				newTestSwcNode(4, 2, 3, 10, 10, 0),
+			},
+			treeNumber:        1,
+			branchPointNumber: 1,
+			tipNumber:         2,
+			totalLength:       30,
+			maxPathDistance:   20,
+			shollShellNumber:  2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			morphometry := ComputeSwcMorphometry(test.swcData, 10)
+			if morphometry.NodeNumber != int32(len(test.swcData)) {
+				t.Errorf("NodeNumber = %d, want %d", morphometry.NodeNumber, len(test.swcData))
+			}
+			if morphometry.TreeNumber != test.treeNumber {
+				t.Errorf("TreeNumber = %d, want %d", morphometry.TreeNumber, test.treeNumber)
+			}
+			if morphometry.BranchPointNumber != test.branchPointNumber {
+				t.Errorf("BranchPointNumber = %d, want %d", morphometry.BranchPointNumber, test.branchPointNumber)
+			}
+			if morphometry.TipNumber != test.tipNumber {
+				t.Errorf("TipNumber = %d, want %d", morphometry.TipNumber, test.tipNumber)
+			}
+			if math.Abs(morphometry.TotalLength-test.totalLength) > 1e-6 {
+				t.Errorf("TotalLength = %f, want %f", morphometry.TotalLength, test.totalLength)
+			}
+			if math.Abs(morphometry.MaxPathDistance-test.maxPathDistance) > 1e-6 {
+				t.Errorf("MaxPathDistance = %f, want %f", morphometry.MaxPathDistance, test.maxPathDistance)
+			}
+			if len(morphometry.ShollProfile) != test.shollShellNumber {
+				t.Errorf("Sholl shell number = %d, want %d", len(morphometry.ShollProfile), test.shollShellNumber)
+			}
+		})
+	}
+}
+
+func TestComputeSwcMorphometryBoundingBox(t *testing.T) {
+	swcData := dbmodel.SwcDataV1{
+		newTestSwcNode(1, 2, 3, -1, 2, 3),
+		newTestSwcNode(2, 1, 3, 4, -5, 6),
+	}
+	morphometry := ComputeSwcMorphometry(swcData, 0)
+	want := SwcBoundingBox{MinX: -1, MinY: -5, MinZ: 3, MaxX: 4, MaxY: 2, MaxZ: 6}
+	if morphometry.BoundingBox != want {
+		t.Errorf("BoundingBox = %+v, want %+v", morphometry.BoundingBox, want)
+	}
+}
@@ -0,0 +1,89 @@
+package bll
+
+import (
+	"DBMS/config"
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"DBMS/logger"
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// PurgeSwc permanently removes a swc: its meta info, its uuid in project swc lists and every collection it owns.
+func PurgeSwc(swcMetaInfo *dbmodel.SwcMetaInfoV1) dal.ReturnWrapper {
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		if result := dal.DeleteSwcWithContext(sessionContext, *swcMetaInfo, dal.GetDbInstance()); !result.Status {
+			return result
+		}
+		return dal.PullSwcFromAllProjectWithContext(sessionContext, swcMetaInfo.Base.Uuid, dal.GetDbInstance())
+	})
+	if !result.Status {
+		return result
+	}
+	// collections cannot be dropped in a transaction, the orphan collection gc retries whatever is left behind
+	return DeleteSwcCollections(swcMetaInfo)
+}
+
+// PurgeExpiredDeletedItems permanently removes swcs and projects which have been in the recycle bin for longer than
+// retention. Swcs of a purged project are left alone, they were not moved to the recycle bin with it.
+func PurgeExpiredDeletedItems(now time.Time, retention time.Duration) (int, int) {
+	purgedSwcNumber := 0
+	var swcMetaInfoList []dbmodel.SwcMetaInfoV1
+	if result := dal.QueryDeletedSwc(&swcMetaInfoList, dal.GetDbInstance()); !result.Status {
+		logger.GetLogger().Println(result.Message)
+	}
+	for idx := range swcMetaInfoList {
+		swcMetaInfo := &swcMetaInfoList[idx]
+		if now.Sub(swcMetaInfo.DeleteTime) < retention {
+			continue
+		}
+		if result := PurgeSwc(swcMetaInfo); !result.Status {
+			logger.GetLogger().Println("Purge swc " + swcMetaInfo.Base.Uuid + " failed: " + result.Message)
+			continue
+		}
+		logger.GetLogger().Println("Purged swc " + swcMetaInfo.Base.Uuid + " deleted by " + swcMetaInfo.DeletedBy + " at " + swcMetaInfo.DeleteTime.String())
+		purgedSwcNumber++
+	}
+
+	purgedProjectNumber := 0
+	var projectMetaInfoList []dbmodel.ProjectMetaInfoV1
+	if result := dal.QueryDeletedProject(&projectMetaInfoList, dal.GetDbInstance()); !result.Status {
+		logger.GetLogger().Println(result.Message)
+	}
+	for _, projectMetaInfo := range projectMetaInfoList {
+		if now.Sub(projectMetaInfo.DeleteTime) < retention {
+			continue
+		}
+		if result := dal.DeleteProject(projectMetaInfo, dal.GetDbInstance()); !result.Status {
+			logger.GetLogger().Println("Purge project " + projectMetaInfo.Base.Uuid + " failed: " + result.Message)
+			continue
+		}
+		logger.GetLogger().Println("Purged project " + projectMetaInfo.Base.Uuid + " deleted by " + projectMetaInfo.DeletedBy + " at " + projectMetaInfo.DeleteTime.String())
+		purgedProjectNumber++
+	}
+
+	return purgedSwcNumber, purgedProjectNumber
+}
+
+func CronPurgeDeletedItems() {
+	c := cron.New(cron.WithSeconds(), cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)), cron.WithLogger(
+		cron.VerbosePrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))))
+	EntryID, err := c.AddFunc("0 0 3 * * *", func() {
+		logger.GetLogger().Println(time.Now(), "CronPurgeDeletedItems...")
+
+		if config.AppConfig.DeletedItemPurgeDays <= 0 {
+			return
+		}
+		retention := time.Duration(config.AppConfig.DeletedItemPurgeDays) * 24 * time.Hour
+		purgedSwcNumber, purgedProjectNumber := PurgeExpiredDeletedItems(time.Now(), retention)
+		logger.GetLogger().Println("Purged swc " + strconv.Itoa(purgedSwcNumber) + ", project " + strconv.Itoa(purgedProjectNumber))
+	})
+	logger.GetLogger().Println(time.Now(), EntryID, err)
+
+	c.Start()
+}
@@ -0,0 +1,335 @@
+package bll
+
+import (
+	"DBMS/config"
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"DBMS/logger"
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const AutoSnapshotCreator = "AutoSnapshot"
+
+// CreateSwcSnapshotAndIncrementList snapshots the current nodes of the swc and starts a new increment operation list
+// from it. Only the collections are written, the new entries are appended to swcMetaInfo and returned so the caller
+// can persist them with dal.AddSwcSnapshotWithContext. swcMetaInfo.Revision has to be the revision of the current
+// nodes, the new list starts from it.
+func CreateSwcSnapshotAndIncrementList(ctx context.Context, swcMetaInfo *dbmodel.SwcMetaInfoV1, creator string) (dbmodel.SwcSnapshotMetaInfoV1, dbmodel.SwcIncrementOperationMetaInfoV1, dal.ReturnWrapper) {
+	createTime := time.Now()
+	var swcSnapshotMetaInfo dbmodel.SwcSnapshotMetaInfoV1
+	swcSnapshotMetaInfo.Base.Id = primitive.NewObjectID()
+	swcSnapshotMetaInfo.Base.Uuid = uuid.NewString()
+	swcSnapshotMetaInfo.Base.DataAccessModelVersion = "V1"
+	swcSnapshotMetaInfo.CreateTime = createTime
+	swcSnapshotMetaInfo.Creator = creator
+	swcSnapshotMetaInfo.SwcSnapshotCollectionName = "Snapshot_" + uuid.NewString()
+
+	var swcIncrementOperationMetaInfo dbmodel.SwcIncrementOperationMetaInfoV1
+	swcIncrementOperationMetaInfo.Base.Id = primitive.NewObjectID()
+	swcIncrementOperationMetaInfo.Base.Uuid = uuid.NewString()
+	swcIncrementOperationMetaInfo.Base.DataAccessModelVersion = "V1"
+	swcIncrementOperationMetaInfo.CreateTime = createTime
+	swcIncrementOperationMetaInfo.StartSnapshot = swcSnapshotMetaInfo.SwcSnapshotCollectionName
+	swcIncrementOperationMetaInfo.IncrementOperationCollectionName = "IncrementOperation_" + uuid.NewString()
+	swcIncrementOperationMetaInfo.StartRevision = swcMetaInfo.Revision
+
+	if result := dal.CreateSnapshotWithContext(ctx, swcMetaInfo.Base.Uuid, swcSnapshotMetaInfo.SwcSnapshotCollectionName, dal.GetDbInstance()); !result.Status {
+		return swcSnapshotMetaInfo, swcIncrementOperationMetaInfo, result
+	}
+
+	swcMetaInfo.SwcSnapshotList = append(swcMetaInfo.SwcSnapshotList, swcSnapshotMetaInfo)
+	swcMetaInfo.SwcIncrementOperationList = append(swcMetaInfo.SwcIncrementOperationList, swcIncrementOperationMetaInfo)
+	swcMetaInfo.CurrentIncrementOperationCollectionName = swcIncrementOperationMetaInfo.IncrementOperationCollectionName
+
+	return swcSnapshotMetaInfo, swcIncrementOperationMetaInfo, dal.ReturnWrapper{Status: true, Message: "Create snapshot " + swcSnapshotMetaInfo.SwcSnapshotCollectionName + " successfully!"}
+}
+
+// swcSnapshotSaveAttempts is how often CreateAndSaveSwcSnapshot copies the nodes again after a write changed the swc
+// during the copy.
+const swcSnapshotSaveAttempts = 3
+
+// CreateAndSaveSwcSnapshot runs CreateSwcSnapshotAndIncrementList and adds the new snapshot to the swc meta info. The
+// nodes are copied outside of any transaction, a long copy would otherwise hold the transaction open and abort it.
+// The meta info update only succeeds when the swc is still at the revision read before the copy, so the snapshot
+// holds exactly the nodes of that revision, otherwise the copy is dropped and taken again. swcMetaInfo is only updated
+// once the snapshot is saved.
+func CreateAndSaveSwcSnapshot(swcMetaInfo *dbmodel.SwcMetaInfoV1, creator string) dal.ReturnWrapper {
+	var result dal.ReturnWrapper
+	for attempt := 0; attempt < swcSnapshotSaveAttempts; attempt++ {
+		updatedSwcMetaInfo := *swcMetaInfo
+		if result = dal.QuerySwcRevisionWithContext(context.TODO(), swcMetaInfo.Base.Uuid, &updatedSwcMetaInfo.Revision, dal.GetDbInstance()); !result.Status {
+			return result
+		}
+		if result = dal.QuerySwcCurrentIncrementOperationWithContext(context.TODO(), swcMetaInfo.Base.Uuid, &updatedSwcMetaInfo.CurrentIncrementOperationCollectionName, dal.GetDbInstance()); !result.Status {
+			return result
+		}
+		previousIncrementOperationCollectionName := updatedSwcMetaInfo.CurrentIncrementOperationCollectionName
+		snapshot, incrementOperation, snapshotResult := CreateSwcSnapshotAndIncrementList(context.TODO(), &updatedSwcMetaInfo, creator)
+		if !snapshotResult.Status {
+			return snapshotResult
+		}
+
+		result = dal.AddSwcSnapshotWithContext(context.TODO(), swcMetaInfo.Base.Uuid, snapshot, incrementOperation, previousIncrementOperationCollectionName, updatedSwcMetaInfo.Revision, dal.GetDbInstance())
+		if result.Status {
+			*swcMetaInfo = updatedSwcMetaInfo
+			return snapshotResult
+		}
+		if dropResult := dal.DeleteSwcSnapshot(snapshot.SwcSnapshotCollectionName, dal.GetDbInstance()); !dropResult.Status {
+			logger.GetLogger().Println(dropResult.Message)
+		}
+	}
+	return result
+}
+
+func currentSwcIncrementOperationMetaInfo(swcMetaInfo *dbmodel.SwcMetaInfoV1) (dbmodel.SwcIncrementOperationMetaInfoV1, bool) {
+	for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
+		if incrementOperation.IncrementOperationCollectionName == swcMetaInfo.CurrentIncrementOperationCollectionName {
+			return incrementOperation, true
+		}
+	}
+	return dbmodel.SwcIncrementOperationMetaInfoV1{}, false
+}
+
+// NeedAutoSnapshot reports whether the current increment operation list holds at least operationNumberLimit
+// operations, or holds any operation and is older than ageLimit. A limit <= 0 disables that trigger.
+func NeedAutoSnapshot(swcMetaInfo *dbmodel.SwcMetaInfoV1, operationNumber int64, now time.Time, operationNumberLimit int64, ageLimit time.Duration) bool {
+	if operationNumber == 0 {
+		return false
+	}
+	if operationNumberLimit > 0 && operationNumber >= operationNumberLimit {
+		return true
+	}
+	currentIncrementOperation, ok := currentSwcIncrementOperationMetaInfo(swcMetaInfo)
+	return ok && ageLimit > 0 && now.Sub(currentIncrementOperation.CreateTime) >= ageLimit
+}
+
+// SelectExpiredSwcSnapshots applies the retention rule: the latest snapshot of each day is kept for dailyRetention,
+// after that the latest snapshot of each ISO week is kept for weeklyRetention (forever when weeklyRetention <= 0).
+// Protected snapshots and the snapshot the current increment operation list starts from are always kept. Returns the
+// snapshot collection names to remove.
+func SelectExpiredSwcSnapshots(swcMetaInfo *dbmodel.SwcMetaInfoV1, now time.Time, dailyRetention time.Duration, weeklyRetention time.Duration) []string {
+	keptSnapshots := make(map[string]bool)
+	if currentIncrementOperation, ok := currentSwcIncrementOperationMetaInfo(swcMetaInfo); ok {
+		keptSnapshots[currentIncrementOperation.StartSnapshot] = true
+	}
+
+	latestSnapshotInBucket := make(map[string]dbmodel.SwcSnapshotMetaInfoV1)
+	var expiredSnapshots []string
+	for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+		if snapshot.Protected {
+			continue
+		}
+
+		age := now.Sub(snapshot.CreateTime)
+		var bucket string
+		if age < dailyRetention {
+			year, month, day := snapshot.CreateTime.Date()
+			bucket = "Day_" + strconv.Itoa(year) + "-" + strconv.Itoa(int(month)) + "-" + strconv.Itoa(day)
+		} else if weeklyRetention <= 0 || age < weeklyRetention {
+			year, week := snapshot.CreateTime.ISOWeek()
+			bucket = "Week_" + strconv.Itoa(year) + "-" + strconv.Itoa(week)
+		} else {
+			if !keptSnapshots[snapshot.SwcSnapshotCollectionName] {
+				expiredSnapshots = append(expiredSnapshots, snapshot.SwcSnapshotCollectionName)
+			}
+			continue
+		}
+
+		latestSnapshot, ok := latestSnapshotInBucket[bucket]
+		if !ok {
+			latestSnapshotInBucket[bucket] = snapshot
+			continue
+		}
+		if snapshot.CreateTime.After(latestSnapshot.CreateTime) {
+			latestSnapshot, snapshot = snapshot, latestSnapshot
+			latestSnapshotInBucket[bucket] = latestSnapshot
+		}
+		if !keptSnapshots[snapshot.SwcSnapshotCollectionName] {
+			expiredSnapshots = append(expiredSnapshots, snapshot.SwcSnapshotCollectionName)
+		}
+	}
+
+	return expiredSnapshots
+}
+
+// RemoveSwcSnapshots drops the given snapshots from the meta info together with the increment operation lists
+// starting from them, and returns the increment operation collection names which were removed. The time ranges the
+// removed lists covered are added to SwcRemovedHistoryList and returned as well, each one ends where the next list
+// starts. Collections are not touched so the caller can persist the meta info before dropping them.
+func RemoveSwcSnapshots(swcMetaInfo *dbmodel.SwcMetaInfoV1, snapshotNames []string) ([]string, []dbmodel.SwcHistoryRangeV1) {
+	removedSnapshots := make(map[string]bool, len(snapshotNames))
+	for _, snapshotName := range snapshotNames {
+		removedSnapshots[snapshotName] = true
+	}
+
+	var snapshotList []dbmodel.SwcSnapshotMetaInfoV1
+	for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+		if !removedSnapshots[snapshot.SwcSnapshotCollectionName] {
+			snapshotList = append(snapshotList, snapshot)
+		}
+	}
+	swcMetaInfo.SwcSnapshotList = snapshotList
+
+	isRemoved := func(incrementOperation *dbmodel.SwcIncrementOperationMetaInfoV1) bool {
+		return removedSnapshots[incrementOperation.StartSnapshot] && incrementOperation.IncrementOperationCollectionName != swcMetaInfo.CurrentIncrementOperationCollectionName
+	}
+
+	var removedHistory []dbmodel.SwcHistoryRangeV1
+	sortedIncrementOperationList := sortedSwcIncrementOperationList(swcMetaInfo)
+	for idx := range sortedIncrementOperationList {
+		if !isRemoved(&sortedIncrementOperationList[idx]) {
+			continue
+		}
+		historyRange := dbmodel.SwcHistoryRangeV1{StartTime: sortedIncrementOperationList[idx].CreateTime}
+		if idx+1 < len(sortedIncrementOperationList) {
+			historyRange.EndTime = sortedIncrementOperationList[idx+1].CreateTime
+		}
+		removedHistory = append(removedHistory, historyRange)
+	}
+	swcMetaInfo.SwcRemovedHistoryList = append(swcMetaInfo.SwcRemovedHistoryList, removedHistory...)
+
+	var incrementOperationList []dbmodel.SwcIncrementOperationMetaInfoV1
+	var removedIncrementOperations []string
+	for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
+		if isRemoved(&incrementOperation) {
+			removedIncrementOperations = append(removedIncrementOperations, incrementOperation.IncrementOperationCollectionName)
+			continue
+		}
+		incrementOperationList = append(incrementOperationList, incrementOperation)
+	}
+	swcMetaInfo.SwcIncrementOperationList = incrementOperationList
+
+	return removedIncrementOperations, removedHistory
+}
+
+// AutoSnapshotAndCompactSwc runs the snapshot policy on one swc. The time ranges of the removed increment operation
+// lists are recorded, reverting to or rebuilding a time inside them fails with ErrorSwcHistoryNotAvailable.
+func AutoSnapshotAndCompactSwc(swcMetaInfo *dbmodel.SwcMetaInfoV1, now time.Time) dal.ReturnWrapper {
+	if swcMetaInfo.CurrentIncrementOperationCollectionName == "" {
+		return dal.ReturnWrapper{Status: true, Message: "Version control is not enabled for swc " + swcMetaInfo.Base.Uuid}
+	}
+
+	var operationNumber int64
+	if result := dal.CountSwcIncrementOperation(swcMetaInfo.CurrentIncrementOperationCollectionName, &operationNumber, dal.GetDbInstance()); !result.Status {
+		return result
+	}
+	ageLimit := time.Duration(config.AppConfig.AutoSnapshotIncrementOperationAgeHours) * time.Hour
+	if NeedAutoSnapshot(swcMetaInfo, operationNumber, now, int64(config.AppConfig.AutoSnapshotIncrementOperationNumber), ageLimit) {
+		if result := CreateAndSaveSwcSnapshot(swcMetaInfo, AutoSnapshotCreator); !result.Status {
+			return result
+		}
+		logger.GetLogger().Println("Auto snapshot swc " + swcMetaInfo.Base.Uuid + " after " + strconv.FormatInt(operationNumber, 10) + " increment operations")
+	}
+
+	var expiredSnapshots []string
+	if config.AppConfig.SnapshotDailyRetentionDays > 0 {
+		dailyRetention := time.Duration(config.AppConfig.SnapshotDailyRetentionDays) * 24 * time.Hour
+		weeklyRetention := time.Duration(config.AppConfig.SnapshotWeeklyRetentionDays) * 24 * time.Hour
+		expiredSnapshots = SelectExpiredSwcSnapshots(swcMetaInfo, now, dailyRetention, weeklyRetention)
+	}
+	if len(expiredSnapshots) == 0 {
+		return dal.ReturnWrapper{Status: true, Message: "Nothing to do for swc " + swcMetaInfo.Base.Uuid}
+	}
+
+	// only the history fields are written, edits of the other meta info fields made meanwhile are kept
+	expiredIncrementOperations, removedHistory := RemoveSwcSnapshots(swcMetaInfo, expiredSnapshots)
+	if result := dal.RemoveSwcSnapshotsWithContext(context.TODO(), swcMetaInfo.Base.Uuid, expiredSnapshots, expiredIncrementOperations, removedHistory, swcMetaInfo.CurrentIncrementOperationCollectionName, dal.GetDbInstance()); !result.Status {
+		return result
+	}
+
+	for _, snapshotName := range expiredSnapshots {
+		if result := dal.DeleteSwcSnapshot(snapshotName, dal.GetDbInstance()); !result.Status {
+			logger.GetLogger().Println(result.Message)
+		}
+	}
+	for _, incrementOperationCollectionName := range expiredIncrementOperations {
+		if result := dal.DeleteSwcIncrementOperationCollection(incrementOperationCollectionName, dal.GetDbInstance()); !result.Status {
+			logger.GetLogger().Println(result.Message)
+		}
+	}
+	if len(expiredSnapshots) != 0 {
+		logger.GetLogger().Println("Compact swc " + swcMetaInfo.Base.Uuid + ", removed snapshots " + strconv.Itoa(len(expiredSnapshots)) + ", increment operation lists " + strconv.Itoa(len(expiredIncrementOperations)))
+	}
+
+	return dal.ReturnWrapper{Status: true, Message: "Snapshot policy applied to swc " + swcMetaInfo.Base.Uuid}
+}
+
+func CronAutoSnapshotAndCompaction() {
+	c := cron.New(cron.WithSeconds(), cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)), cron.WithLogger(
+		cron.VerbosePrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))))
+	EntryID, err := c.AddFunc("@hourly", func() {
+		logger.GetLogger().Println(time.Now(), "CronAutoSnapshotAndCompaction...")
+
+		var swcMetaInfoList []dbmodel.SwcMetaInfoV1
+		if result := dal.QueryAllSwc(&swcMetaInfoList, dal.GetDbInstance()); !result.Status {
+			logger.GetLogger().Println(result.Message)
+			return
+		}
+
+		for _, listedSwcMetaInfo := range swcMetaInfoList {
+			// query again so edits made while earlier swcs were processed are not overwritten
+			var swcMetaInfo dbmodel.SwcMetaInfoV1
+			swcMetaInfo.Base.Uuid = listedSwcMetaInfo.Base.Uuid
+			if result := dal.QuerySwc(&swcMetaInfo, dal.GetDbInstance()); !result.Status {
+				continue
+			}
+			if result := AutoSnapshotAndCompactSwc(&swcMetaInfo, time.Now()); !result.Status {
+				logger.GetLogger().Println("Snapshot policy failed for swc " + swcMetaInfo.Base.Uuid + ": " + result.Message)
+			}
+		}
+	})
+	logger.GetLogger().Println(time.Now(), EntryID, err)
+
+	c.Start()
+}
+
+func FindSwcSnapshotIndex(swcMetaInfo *dbmodel.SwcMetaInfoV1, snapshotName string) int {
+	for idx := range swcMetaInfo.SwcSnapshotList {
+		if swcMetaInfo.SwcSnapshotList[idx].SwcSnapshotCollectionName == snapshotName {
+			return idx
+		}
+	}
+	return -1
+}
+
+// DeleteSwcSnapshotAndIncrementList removes one snapshot and the increment operation lists starting from it. Protected
+// snapshots and the snapshot the current increment operation list starts from are refused, since the live history
+// still depends on it.
+func DeleteSwcSnapshotAndIncrementList(swcMetaInfo *dbmodel.SwcMetaInfoV1, snapshotName string) dal.ReturnWrapper {
+	idx := FindSwcSnapshotIndex(swcMetaInfo, snapshotName)
+	if idx == -1 {
+		return dal.ReturnWrapper{Status: false, Message: "Cannot find snapshot " + snapshotName + " in swc " + swcMetaInfo.Base.Uuid}
+	}
+	if swcMetaInfo.SwcSnapshotList[idx].Protected {
+		return dal.ReturnWrapper{Status: false, Message: "Snapshot " + snapshotName + " is protected!"}
+	}
+	if currentIncrementOperation, ok := currentSwcIncrementOperationMetaInfo(swcMetaInfo); ok && currentIncrementOperation.StartSnapshot == snapshotName {
+		return dal.ReturnWrapper{Status: false, Message: "Current increment operation list starts from snapshot " + snapshotName + ", create a new snapshot before deleting it!"}
+	}
+
+	// the meta info no longer references the collections once the update succeeds, a collection which fails to drop
+	// afterwards is left to RunOrphanCollectionGc
+	removedIncrementOperations, removedHistory := RemoveSwcSnapshots(swcMetaInfo, []string{snapshotName})
+	if result := dal.RemoveSwcSnapshotsWithContext(context.TODO(), swcMetaInfo.Base.Uuid, []string{snapshotName}, removedIncrementOperations, removedHistory, swcMetaInfo.CurrentIncrementOperationCollectionName, dal.GetDbInstance()); !result.Status {
+		return result
+	}
+
+	if result := dal.DeleteSwcSnapshot(snapshotName, dal.GetDbInstance()); !result.Status {
+		logger.GetLogger().Println(result.Message)
+	}
+	for _, incrementOperationCollectionName := range removedIncrementOperations {
+		if result := dal.DeleteSwcIncrementOperationCollection(incrementOperationCollectionName, dal.GetDbInstance()); !result.Status {
+			logger.GetLogger().Println(result.Message)
+		}
+	}
+
+	return dal.ReturnWrapper{Status: true, Message: "Delete snapshot " + snapshotName + " and " + strconv.Itoa(len(removedIncrementOperations)) + " increment operation lists successfully!"}
+}
@@ -0,0 +1,54 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"testing"
+	"time"
+)
+
+func TestRemoveSwcSnapshotsRecordsRemovedHistory(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC)
+	}
+	var swcMetaInfo dbmodel.SwcMetaInfoV1
+	for n := 1; n <= 4; n++ {
+		snapshotName := "Snapshot_" + string(rune('0'+n))
+		swcMetaInfo.SwcSnapshotList = append(swcMetaInfo.SwcSnapshotList, dbmodel.SwcSnapshotMetaInfoV1{SwcSnapshotCollectionName: snapshotName, CreateTime: day(n)})
+		swcMetaInfo.SwcIncrementOperationList = append(swcMetaInfo.SwcIncrementOperationList, dbmodel.SwcIncrementOperationMetaInfoV1{
+			StartSnapshot:                    snapshotName,
+			CreateTime:                       day(n),
+			IncrementOperationCollectionName: "IncrementOperation_" + string(rune('0'+n)),
+		})
+	}
+	swcMetaInfo.CurrentIncrementOperationCollectionName = "IncrementOperation_4"
+
+	removedIncrementOperations, removedHistory := RemoveSwcSnapshots(&swcMetaInfo, []string{"Snapshot_2", "Snapshot_4"})
+	if len(removedIncrementOperations) != 1 || removedIncrementOperations[0] != "IncrementOperation_2" {
+		t.Fatalf("removed increment operations = %v, want [IncrementOperation_2]", removedIncrementOperations)
+	}
+	if len(removedHistory) != 1 || !removedHistory[0].StartTime.Equal(day(2)) || !removedHistory[0].EndTime.Equal(day(3)) {
+		t.Fatalf("removed history = %v, want one range from day 2 to day 3", removedHistory)
+	}
+	if len(swcMetaInfo.SwcRemovedHistoryList) != 1 {
+		t.Fatalf("SwcRemovedHistoryList has %d ranges, want 1", len(swcMetaInfo.SwcRemovedHistoryList))
+	}
+
+	tests := []struct {
+		name        string
+		versionTime time.Time
+		removed     bool
+	}{
+		{name: "before the removed range", versionTime: day(1).Add(time.Hour)},
+		{name: "start of the removed range", versionTime: day(2), removed: true},
+		{name: "inside the removed range", versionTime: day(2).Add(time.Hour), removed: true},
+		{name: "end of the removed range", versionTime: day(3)},
+		{name: "current list", versionTime: day(5)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if removed := IsSwcHistoryRemovedAt(&swcMetaInfo, test.versionTime); removed != test.removed {
+				t.Errorf("IsSwcHistoryRemovedAt = %v, want %v", removed, test.removed)
+			}
+		})
+	}
+}
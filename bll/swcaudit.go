@@ -0,0 +1,169 @@
+package bll
+
+import (
+	"DBMS/SwcDbmsCommon/Generated/go/proto/message"
+	"DBMS/config"
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// SwcIncrementOperationExecutor is who made an increment operation and from where, it is stored on the operation so
+// disputed edits can be traced back to a user and a client.
+type SwcIncrementOperationExecutor struct {
+	UserName      string
+	UserUuid      string
+	ClientAddress string
+	DeviceType    string
+	ApiVersion    string
+}
+
+// NewSwcIncrementOperationExecutor collects the executor of a request, see RequestClientAddress for the client address.
+func NewSwcIncrementOperationExecutor(ctx context.Context, requestMetaInfo *message.RequestMetaInfoV1, executorUserMetaInfo *dbmodel.UserMetaInfoV1) SwcIncrementOperationExecutor {
+	return SwcIncrementOperationExecutor{
+		UserName:      executorUserMetaInfo.Name,
+		UserUuid:      executorUserMetaInfo.Base.Uuid,
+		ClientAddress: RequestClientAddress(ctx),
+		DeviceType:    requestMetaInfo.GetDeviceType(),
+		ApiVersion:    requestMetaInfo.GetApiVersion(),
+	}
+}
+
+// RequestClientAddress is the address of the client of a request. Requests coming through the http gateway carry the
+// address of the http client in x-forwarded-for and the grpc peer is the gateway itself then. Anyone can send that
+// header, so it is only believed when the peer is the in-process gateway, which dials the grpc server on this host,
+// or one of config.AppConfig.TrustedProxyAddresses. Proxies append the address they saw, the client address is the
+// last one not added by a trusted proxy.
+func RequestClientAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	peerAddress := p.Addr.String()
+	if host, _, err := net.SplitHostPort(peerAddress); err == nil {
+		peerAddress = host
+	}
+	if !isTrustedProxyAddress(peerAddress, true) {
+		return peerAddress
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var forwardedFor []string
+	for _, value := range md.Get("x-forwarded-for") {
+		for _, address := range strings.Split(value, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				forwardedFor = append(forwardedFor, address)
+			}
+		}
+	}
+	clientAddress := peerAddress
+	for idx := len(forwardedFor) - 1; idx >= 0; idx-- {
+		clientAddress = forwardedFor[idx]
+		if !isTrustedProxyAddress(clientAddress, false) {
+			break
+		}
+	}
+	return clientAddress
+}
+
+// isTrustedProxyAddress reports whether the ip is a configured trusted proxy. The in-process gateway connects from a
+// loopback address or from the address the grpc server listens on, which counts when isPeer is set.
+func isTrustedProxyAddress(address string, isPeer bool) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	if isPeer && (ip.IsLoopback() || ip.Equal(net.ParseIP(config.AppConfig.GrpcIP))) {
+		return true
+	}
+	for _, trustedProxy := range config.AppConfig.TrustedProxyAddresses {
+		if _, network, err := net.ParseCIDR(trustedProxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if ip.Equal(net.ParseIP(trustedProxy)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (executor *SwcIncrementOperationExecutor) applyTo(operation *dbmodel.SwcIncrementOperationV1) {
+	operation.Creator = executor.UserName
+	operation.CreatorUuid = executor.UserUuid
+	operation.ClientAddress = executor.ClientAddress
+	operation.DeviceType = executor.DeviceType
+	operation.ApiVersion = executor.ApiVersion
+	if operation.DeviceType == "" {
+		// older clients only tag the nodes they send with their device type
+		for _, node := range operation.SwcData {
+			if node.DeviceType != "" {
+				operation.DeviceType = node.DeviceType
+				break
+			}
+		}
+	}
+}
+
+// swcIncrementOperationFilterRequest is implemented by the requests which query increment operations.
+type swcIncrementOperationFilterRequest interface {
+	GetCreatorUuid() string
+	GetClientAddress() string
+	GetDeviceType() string
+	GetApiVersion() string
+	GetCreator() string
+	GetIncrementOperations() []message.IncrementOperationV1
+	GetStartTime() *timestamppb.Timestamp
+	GetEndTime() *timestamppb.Timestamp
+}
+
+func SwcIncrementOperationFilterFromRequest(request swcIncrementOperationFilterRequest) dal.SwcIncrementOperationFilter {
+	filter := dal.SwcIncrementOperationFilter{
+		CreatorUuid:   request.GetCreatorUuid(),
+		ClientAddress: request.GetClientAddress(),
+		DeviceType:    request.GetDeviceType(),
+		ApiVersion:    request.GetApiVersion(),
+		Creator:       request.GetCreator(),
+	}
+	for _, incrementOperation := range request.GetIncrementOperations() {
+		filter.IncrementOperations = append(filter.IncrementOperations, incrementOperation.String())
+	}
+	if request.GetStartTime() != nil {
+		filter.StartTime = request.GetStartTime().AsTime()
+	}
+	if request.GetEndTime() != nil {
+		filter.EndTime = request.GetEndTime().AsTime()
+	}
+	return filter
+}
+
+// EncodeSwcIncrementOperationPageToken returns the page token of the page following operation.
+func EncodeSwcIncrementOperationPageToken(operation *dbmodel.SwcIncrementOperationV1) string {
+	return strconv.FormatInt(operation.Revision, 10) + "_" + operation.Base.Id.Hex()
+}
+
+func DecodeSwcIncrementOperationPageToken(pageToken string) (*dal.SwcIncrementOperationPosition, error) {
+	revision, id, found := strings.Cut(pageToken, "_")
+	if !found {
+		return nil, errors.New("Invalid page token!")
+	}
+	var position dal.SwcIncrementOperationPosition
+	var err error
+	if position.Revision, err = strconv.ParseInt(revision, 10, 64); err != nil {
+		return nil, errors.New("Invalid page token!")
+	}
+	if position.Id, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, errors.New("Invalid page token!")
+	}
+	return &position, nil
+}
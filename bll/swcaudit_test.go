@@ -0,0 +1,50 @@
+package bll
+
+import (
+	"DBMS/config"
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRequestClientAddress(t *testing.T) {
+	appConfig := config.AppConfig
+	defer func() { config.AppConfig = appConfig }()
+	config.AppConfig.GrpcIP = "10.0.0.1"
+	config.AppConfig.TrustedProxyAddresses = []string{"192.168.1.0/24", "172.16.0.9"}
+
+	tests := []struct {
+		name         string
+		peerAddress  string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "direct client", peerAddress: "203.0.113.5:4000", want: "203.0.113.5"},
+		{name: "direct client forging the header", peerAddress: "203.0.113.5:4000", forwardedFor: []string{"198.51.100.1"}, want: "203.0.113.5"},
+		{name: "in-process gateway", peerAddress: "127.0.0.1:4000", forwardedFor: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "in-process gateway on the grpc address", peerAddress: "10.0.0.1:4000", forwardedFor: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "gateway without the header", peerAddress: "127.0.0.1:4000", want: "127.0.0.1"},
+		{name: "gateway client forging the header", peerAddress: "127.0.0.1:4000", forwardedFor: []string{"1.2.3.4, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "behind trusted proxies", peerAddress: "127.0.0.1:4000", forwardedFor: []string{"1.2.3.4, 198.51.100.1, 172.16.0.9, 192.168.1.7"}, want: "198.51.100.1"},
+		{name: "trusted cidr peer", peerAddress: "192.168.1.20:4000", forwardedFor: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "only trusted proxies", peerAddress: "127.0.0.1:4000", forwardedFor: []string{"172.16.0.9"}, want: "172.16.0.9"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", test.peerAddress)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+			if test.forwardedFor != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.MD{"x-forwarded-for": test.forwardedFor})
+			}
+			if got := RequestClientAddress(ctx); got != test.want {
+				t.Errorf("RequestClientAddress = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
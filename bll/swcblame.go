@@ -0,0 +1,267 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"sort"
+	"time"
+)
+
+// SwcNodeChange is what one increment operation did to one node. Before is nil for a created node and After is nil for
+// a deleted one.
+type SwcNodeChange struct {
+	NodeUuid               string
+	IncrementOperationUuid string
+	IncrementOperation     string
+	Revision               int64
+	Actor                  string
+	CreateTime             time.Time
+	Before                 *dbmodel.SwcNodeDataV1
+	After                  *dbmodel.SwcNodeDataV1
+}
+
+// SwcNodeBlame is the last change of a node. FromHistory is false when the node was not changed within the retained
+// increment operations, the creator and modified time stored on the node are used then.
+type SwcNodeBlame struct {
+	NodeUuid           string
+	Actor              string
+	IncrementOperation string
+	Revision           int64
+	ModifiedTime       time.Time
+	FromHistory        bool
+}
+
+// sortedSwcIncrementOperationList returns the increment operation lists of the swc from the oldest to the newest.
+func sortedSwcIncrementOperationList(swcMetaInfo *dbmodel.SwcMetaInfoV1) []dbmodel.SwcIncrementOperationMetaInfoV1 {
+	incrementOperationList := make([]dbmodel.SwcIncrementOperationMetaInfoV1, len(swcMetaInfo.SwcIncrementOperationList))
+	copy(incrementOperationList, swcMetaInfo.SwcIncrementOperationList)
+	sort.SliceStable(incrementOperationList, func(i, j int) bool {
+		return incrementOperationList[i].CreateTime.Before(incrementOperationList[j].CreateTime)
+	})
+	return incrementOperationList
+}
+
+func swcIncrementOperationActor(operation *dbmodel.SwcIncrementOperationV1, node *dbmodel.SwcNodeDataV1) string {
+	if operation.Creator != "" {
+		return operation.Creator
+	}
+	// operations recorded before the executor was stored only have the creator of created and updated nodes
+	if node != nil && (operation.IncrementOperation == dal.IncrementOp_Create || operation.IncrementOperation == dal.IncrementOp_Update || operation.IncrementOperation == dal.IncrementOp_OverwriteAll) {
+		return node.Creator
+	}
+	return ""
+}
+
+// ApplySwcNodeChanges applies operation to nodes, which maps node uuid to node, and reports the change of every node
+// for which tracked returns true. Grouped operations of a batch are reported one by one with the executor, revision
+// and uuid of the batch.
+func ApplySwcNodeChanges(nodes map[string]dbmodel.SwcNodeDataV1, operation *dbmodel.SwcIncrementOperationV1, tracked func(nodeUuid string) bool, report func(change SwcNodeChange)) {
+	applySwcNodeChanges(nodes, operation, operation, tracked, report)
+}
+
+func applySwcNodeChanges(nodes map[string]dbmodel.SwcNodeDataV1, recordedOperation *dbmodel.SwcIncrementOperationV1, operation *dbmodel.SwcIncrementOperationV1, tracked func(nodeUuid string) bool, report func(change SwcNodeChange)) {
+	change := func(nodeUuid string, before *dbmodel.SwcNodeDataV1, after *dbmodel.SwcNodeDataV1) {
+		if after != nil {
+			nodes[nodeUuid] = *after
+		} else {
+			delete(nodes, nodeUuid)
+		}
+		actorNode := after
+		if actorNode == nil {
+			actorNode = before
+		}
+		report(SwcNodeChange{
+			NodeUuid:               nodeUuid,
+			IncrementOperationUuid: recordedOperation.Base.Uuid,
+			IncrementOperation:     operation.IncrementOperation,
+			Revision:               recordedOperation.Revision,
+			Actor:                  swcIncrementOperationActor(recordedOperation, actorNode),
+			CreateTime:             recordedOperation.CreateTime,
+			Before:                 before,
+			After:                  after,
+		})
+	}
+	current := func(nodeUuid string) *dbmodel.SwcNodeDataV1 {
+		if node, ok := nodes[nodeUuid]; ok {
+			return &node
+		}
+		return nil
+	}
+
+	switch operation.IncrementOperation {
+	case dal.IncrementOp_Create, dal.IncrementOp_OverwriteAll:
+		for idx := range operation.SwcData {
+			node := operation.SwcData[idx]
+			if tracked(node.Base.Uuid) {
+				change(node.Base.Uuid, current(node.Base.Uuid), &node)
+			}
+		}
+	case dal.IncrementOp_Delete:
+		for idx := range operation.SwcData {
+			nodeUuid := operation.SwcData[idx].Base.Uuid
+			if before := current(nodeUuid); before != nil && tracked(nodeUuid) {
+				change(nodeUuid, before, nil)
+			}
+		}
+	case dal.IncrementOp_Update:
+		for idx := range operation.SwcData {
+			nodeUuid := operation.SwcData[idx].Base.Uuid
+			if !tracked(nodeUuid) {
+				continue
+			}
+			before := current(nodeUuid)
+			after := operation.SwcData[idx]
+			if before != nil {
+				after = ApplySwcNodeUpdate(dbmodel.SwcDataV1{*before}, operation.SwcData[idx:idx+1])[0]
+			}
+			change(nodeUuid, before, &after)
+		}
+	case dal.IncrementOp_UpdateNParent:
+		for idx := range operation.NodeNParent {
+			nodeUuid := operation.NodeNParent[idx].Uuid
+			before := current(nodeUuid)
+			if before == nil || !tracked(nodeUuid) {
+				continue
+			}
+			after := ApplySwcNParentUpdate(dbmodel.SwcDataV1{*before}, operation.NodeNParent[idx:idx+1])[0]
+			if after.SwcNodeInternalData != before.SwcNodeInternalData {
+				change(nodeUuid, before, &after)
+			}
+		}
+	case dal.IncrementOp_ClearAll:
+		nodeUuids := make([]string, 0, len(nodes))
+		for nodeUuid := range nodes {
+			nodeUuids = append(nodeUuids, nodeUuid)
+		}
+		sort.Strings(nodeUuids)
+		for _, nodeUuid := range nodeUuids {
+			if tracked(nodeUuid) {
+				change(nodeUuid, current(nodeUuid), nil)
+			}
+		}
+	case dal.IncrementOp_Batch:
+		for idx := range operation.GroupedOperations {
+			applySwcNodeChanges(nodes, recordedOperation, &operation.GroupedOperations[idx], tracked, report)
+		}
+	}
+}
+
+// GetSwcNodeHistory lists every change of the node across the retained increment operation lists of the swc, oldest
+// first, and the time from which the history is complete. The value of the node is taken again from the start
+// snapshot of each list, so a list removed from the middle of the chain does not leave wrong before values behind.
+func GetSwcNodeHistory(swcMetaInfo *dbmodel.SwcMetaInfoV1, nodeUuid string) ([]SwcNodeChange, time.Time, dal.ReturnWrapper) {
+	snapshotNames := make(map[string]bool, len(swcMetaInfo.SwcSnapshotList))
+	for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+		snapshotNames[snapshot.SwcSnapshotCollectionName] = true
+	}
+
+	tracked := func(uuid string) bool {
+		return uuid == nodeUuid
+	}
+
+	var history []SwcNodeChange
+	var historyStartTime time.Time
+	nodes := map[string]dbmodel.SwcNodeDataV1{}
+	for idx, incrementOperation := range sortedSwcIncrementOperationList(swcMetaInfo) {
+		if idx == 0 {
+			historyStartTime = incrementOperation.CreateTime
+		}
+		if snapshotNames[incrementOperation.StartSnapshot] {
+			var snapshotNodes dbmodel.SwcDataV1
+			if result := dal.QuerySwcSnapshotNodesByUuid(incrementOperation.StartSnapshot, []string{nodeUuid}, &snapshotNodes, dal.GetDbInstance()); !result.Status {
+				return nil, historyStartTime, result
+			}
+			nodes = map[string]dbmodel.SwcNodeDataV1{}
+			for _, node := range snapshotNodes {
+				nodes[node.Base.Uuid] = node
+			}
+		}
+
+		var operations dbmodel.SwcIncrementOperationListV1
+		if result := dal.QuerySwcIncrementOperationTouchingNodes(incrementOperation.IncrementOperationCollectionName, []string{nodeUuid}, &operations, dal.GetDbInstance()); !result.Status {
+			return nil, historyStartTime, result
+		}
+		for operationIdx := range operations {
+			ApplySwcNodeChanges(nodes, &operations[operationIdx], tracked, func(change SwcNodeChange) {
+				history = append(history, change)
+			})
+		}
+	}
+
+	return history, historyStartTime, dal.ReturnWrapper{Status: true, Message: "Get swc node history successfully!"}
+}
+
+// BlameSwc finds the last change of every current node of the swc by replaying the retained increment operation
+// lists, and the time from which the history is complete. Like GetSwcNodeHistory it starts each list from its start
+// snapshot.
+func BlameSwc(swcMetaInfo *dbmodel.SwcMetaInfoV1, swcData dbmodel.SwcDataV1) ([]SwcNodeBlame, time.Time, dal.ReturnWrapper) {
+	snapshotNames := make(map[string]bool, len(swcMetaInfo.SwcSnapshotList))
+	for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+		snapshotNames[snapshot.SwcSnapshotCollectionName] = true
+	}
+
+	tracked := func(string) bool {
+		return true
+	}
+
+	var historyStartTime time.Time
+	nodes := map[string]dbmodel.SwcNodeDataV1{}
+	lastChanges := map[string]SwcNodeChange{}
+	for idx, incrementOperation := range sortedSwcIncrementOperationList(swcMetaInfo) {
+		if idx == 0 {
+			historyStartTime = incrementOperation.CreateTime
+		}
+		if snapshotNames[incrementOperation.StartSnapshot] {
+			var snapshotNodes dbmodel.SwcDataV1
+			if result := dal.QuerySwcSnapshot(incrementOperation.StartSnapshot, &snapshotNodes, dal.GetDbInstance()); !result.Status {
+				return nil, historyStartTime, result
+			}
+			nodes = make(map[string]dbmodel.SwcNodeDataV1, len(snapshotNodes))
+			for _, node := range snapshotNodes {
+				nodes[node.Base.Uuid] = node
+			}
+		}
+
+		var operations dbmodel.SwcIncrementOperationListV1
+		if result := dal.QuerySwcIncrementOperationAfterRevision(incrementOperation.IncrementOperationCollectionName, -1, &operations, dal.GetDbInstance()); !result.Status {
+			return nil, historyStartTime, result
+		}
+		for operationIdx := range operations {
+			ApplySwcNodeChanges(nodes, &operations[operationIdx], tracked, func(change SwcNodeChange) {
+				if change.After != nil {
+					lastChanges[change.NodeUuid] = change
+				} else {
+					delete(lastChanges, change.NodeUuid)
+				}
+			})
+		}
+	}
+
+	blames := make([]SwcNodeBlame, 0, len(swcData))
+	for idx := range swcData {
+		node := &swcData[idx]
+		if change, ok := lastChanges[node.Base.Uuid]; ok {
+			blames = append(blames, SwcNodeBlame{
+				NodeUuid:           node.Base.Uuid,
+				Actor:              change.Actor,
+				IncrementOperation: change.IncrementOperation,
+				Revision:           change.Revision,
+				ModifiedTime:       change.CreateTime,
+				FromHistory:        true,
+			})
+			continue
+		}
+		modifiedTime := node.LastModifiedTime
+		if modifiedTime.IsZero() {
+			modifiedTime = node.CreateTime
+		}
+		blames = append(blames, SwcNodeBlame{
+			NodeUuid:     node.Base.Uuid,
+			Actor:        node.Creator,
+			Revision:     node.Version,
+			ModifiedTime: modifiedTime,
+		})
+	}
+
+	return blames, historyStartTime, dal.ReturnWrapper{Status: true, Message: "Blame swc successfully!"}
+}
@@ -0,0 +1,211 @@
+package bll
+
+import (
+	"DBMS/SwcDbmsCommon/Generated/go/proto/message"
+	"DBMS/SwcDbmsCommon/Generated/go/proto/response"
+	"DBMS/config"
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"DBMS/logger"
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SwcChangeSubscriptionBufferSize is the number of changes a subscriber may lag behind before it is dropped.
+const SwcChangeSubscriptionBufferSize = 1024
+
+// swcChangeCollectionCacheCapacity is the number of increment operation collections whose swc uuid the change stream
+// watcher keeps, the least recently used one is dropped first.
+const swcChangeCollectionCacheCapacity = 1024
+
+// SwcChangeEvent is a committed change of the nodes of a swc. IncrementOperation is nil when the nodes were changed
+// in a way no increment operation describes, like a revert, and subscribers have to reload the swc.
+type SwcChangeEvent struct {
+	SwcUuid            string
+	Revision           int64
+	IncrementOperation *dbmodel.SwcIncrementOperationV1
+}
+
+// SwcChangeSubscription receives the changes of one swc. Events is closed when the subscriber could not keep up and
+// changes were dropped.
+type SwcChangeSubscription struct {
+	SwcUuid string
+	Events  chan SwcChangeEvent
+}
+
+type swcChangeBroker struct {
+	mutex         sync.Mutex
+	subscriptions map[string]map[*SwcChangeSubscription]bool
+}
+
+var swcChangeBrokerInstance = swcChangeBroker{subscriptions: map[string]map[*SwcChangeSubscription]bool{}}
+
+func SubscribeSwcChange(swcUuid string) *SwcChangeSubscription {
+	subscription := &SwcChangeSubscription{
+		SwcUuid: swcUuid,
+		Events:  make(chan SwcChangeEvent, SwcChangeSubscriptionBufferSize),
+	}
+
+	swcChangeBrokerInstance.mutex.Lock()
+	defer swcChangeBrokerInstance.mutex.Unlock()
+	if swcChangeBrokerInstance.subscriptions[swcUuid] == nil {
+		swcChangeBrokerInstance.subscriptions[swcUuid] = map[*SwcChangeSubscription]bool{}
+	}
+	swcChangeBrokerInstance.subscriptions[swcUuid][subscription] = true
+	return subscription
+}
+
+func UnsubscribeSwcChange(subscription *SwcChangeSubscription) {
+	swcChangeBrokerInstance.mutex.Lock()
+	defer swcChangeBrokerInstance.mutex.Unlock()
+	swcChangeBrokerInstance.removeLocked(subscription)
+}
+
+func (broker *swcChangeBroker) removeLocked(subscription *SwcChangeSubscription) {
+	subscriptions := broker.subscriptions[subscription.SwcUuid]
+	if !subscriptions[subscription] {
+		return
+	}
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(broker.subscriptions, subscription.SwcUuid)
+	}
+	close(subscription.Events)
+}
+
+// PublishSwcChange hands the change to every subscriber of the swc on this server instance. It never blocks, a
+// subscriber whose buffer is full is dropped.
+func PublishSwcChange(event SwcChangeEvent) {
+	swcChangeBrokerInstance.mutex.Lock()
+	defer swcChangeBrokerInstance.mutex.Unlock()
+	for subscription := range swcChangeBrokerInstance.subscriptions[event.SwcUuid] {
+		select {
+		case subscription.Events <- event:
+		default:
+			logger.GetLogger().Println("Swc change subscriber of " + event.SwcUuid + " fell behind, dropped it")
+			swcChangeBrokerInstance.removeLocked(subscription)
+		}
+	}
+}
+
+var swcChangeStreamActive bool
+
+// NotifySwcChange publishes the change once the transaction of ctx has committed. Increment operations are left to
+// the change stream watcher when it runs, it sees the ones written by every server instance. Changes without an
+// increment operation are only published on this instance.
+func NotifySwcChange(ctx context.Context, event SwcChangeEvent) {
+	if swcChangeStreamActive && event.IncrementOperation != nil {
+		return
+	}
+	dal.RunAfterCommit(ctx, func() {
+		PublishSwcChange(event)
+	})
+}
+
+// RecordSwcIncrementOperation stores the increment operation executor made at revision in the current increment
+// operation list of the swc and notifies the subscribers of the swc. The current list is read again through ctx
+// instead of taken from swcMetaInfo, which was queried before the write, so an operation is not recorded in a list a
+// snapshot has rotated out meanwhile. Inside the transaction of the write, a rotation committed after the read makes
+// the transaction conflict and retry.
+func RecordSwcIncrementOperation(ctx context.Context, swcMetaInfo *dbmodel.SwcMetaInfoV1, executor SwcIncrementOperationExecutor, revision int64, operation dbmodel.SwcIncrementOperationV1) dal.ReturnWrapper {
+	var incrementOperationCollectionName string
+	if result := dal.QuerySwcCurrentIncrementOperationWithContext(ctx, swcMetaInfo.Base.Uuid, &incrementOperationCollectionName, dal.GetDbInstance()); !result.Status {
+		return result
+	}
+	if incrementOperationCollectionName == "" {
+		return dal.ReturnWrapper{Status: false, Message: "Swc " + swcMetaInfo.Base.Uuid + " has no current increment operation list!"}
+	}
+
+	executor.applyTo(&operation)
+	operation.Revision = revision
+	result := dal.CreateIncrementOperationWithContext(ctx, incrementOperationCollectionName, operation, dal.GetDbInstance())
+	if !result.Status {
+		return result
+	}
+	NotifySwcChange(ctx, SwcChangeEvent{SwcUuid: swcMetaInfo.Base.Uuid, Revision: revision, IncrementOperation: &operation})
+	return result
+}
+
+func SwcChangeEventToProtobuf(event *SwcChangeEvent) *response.SubscribeSwcChangesResponse {
+	protoMessage := &response.SubscribeSwcChangesResponse{
+		MetaInfo: &message.ResponseMetaInfoV1{
+			Status:  true,
+			Id:      "",
+			Message: "Swc changed",
+		},
+		SwcUuid:  event.SwcUuid,
+		Revision: event.Revision,
+	}
+	if event.IncrementOperation != nil {
+		protoMessage.IncrementOperation = SwcIncrementOperationListV1DbmodelToProtobuf(event.IncrementOperation)
+	} else {
+		protoMessage.ReloadRequired = true
+	}
+	return protoMessage
+}
+
+// StartSwcChangeStreamWatcher publishes the increment operations of every server instance to the subscribers of this
+// one through a MongoDB change stream, when configured. Change streams need a replica set or a sharded cluster,
+// otherwise changes stay on the instance which made them.
+func StartSwcChangeStreamWatcher() {
+	if !config.AppConfig.SwcChangeUseMongoChangeStream {
+		return
+	}
+	if !dal.IsTransactionSupported(dal.GetDbInstance()) {
+		logger.GetLogger().Println("MongoDB change streams need a replica set, swc changes are only published in process")
+		return
+	}
+	swcChangeStreamActive = true
+
+	// increment operation collection names are unique, the swc they belong to never changes. Only the watcher
+	// goroutine uses the cache, a collection leaves it when it is dropped or when it is the least recently used one
+	type cachedSwcUuid struct {
+		SwcUuid  string
+		LastUsed time.Time
+	}
+	collectionSwcUuids := map[string]*cachedSwcUuid{}
+	handle := func(collectionName string, operation dbmodel.SwcIncrementOperationV1) {
+		cached, ok := collectionSwcUuids[collectionName]
+		if !ok {
+			cached = &cachedSwcUuid{}
+			if result := dal.QuerySwcUuidByIncrementOperationCollection(collectionName, &cached.SwcUuid, dal.GetDbInstance()); !result.Status {
+				logger.GetLogger().Println(result.Message)
+				return
+			}
+			collectionSwcUuids[collectionName] = cached
+			for len(collectionSwcUuids) > swcChangeCollectionCacheCapacity {
+				var oldestCollectionName string
+				var oldestTime time.Time
+				for name, entry := range collectionSwcUuids {
+					if oldestCollectionName == "" || entry.LastUsed.Before(oldestTime) {
+						oldestCollectionName, oldestTime = name, entry.LastUsed
+					}
+				}
+				delete(collectionSwcUuids, oldestCollectionName)
+			}
+		}
+		cached.LastUsed = time.Now()
+		PublishSwcChange(SwcChangeEvent{SwcUuid: cached.SwcUuid, Revision: operation.Revision, IncrementOperation: &operation})
+	}
+	drop := func(collectionName string) {
+		delete(collectionSwcUuids, collectionName)
+	}
+
+	go func() {
+		var resumeToken bson.Raw
+		for {
+			lastResumeToken := resumeToken
+			result := dal.WatchSwcIncrementOperation(context.TODO(), &resumeToken, handle, drop, dal.GetDbInstance())
+			logger.GetLogger().Println(result.Message)
+			if !result.Status && resumeToken != nil && bytes.Equal(resumeToken, lastResumeToken) {
+				// the resume point may have left the oplog, start over from now instead of failing forever
+				resumeToken = nil
+			}
+			time.Sleep(5 * time.Second)
+		}
+	}()
+}
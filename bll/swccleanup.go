@@ -0,0 +1,168 @@
+package bll
+
+import (
+	"DBMS/config"
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"DBMS/logger"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DeleteSwcCollections drops every collection owned by the swc: nodes, snapshots, increment operation lists and the
+// ano, apo and soma swc attachments. It keeps going after a failure so as much as possible is released, the last
+// failure is returned and anything left behind is picked up by the orphan collection gc.
+func DeleteSwcCollections(swcMetaInfo *dbmodel.SwcMetaInfoV1) dal.ReturnWrapper {
+	dbInstance := dal.GetDbInstance()
+	result := dal.ReturnWrapper{Status: true, Message: "Delete collections of swc " + swcMetaInfo.Base.Uuid + " successfully!"}
+	drop := func(database *mongo.Database, collectionName string) {
+		if collectionName == "" {
+			return
+		}
+		if dropResult := dal.DropCollection(database, collectionName); !dropResult.Status {
+			logger.GetLogger().Println(dropResult.Message)
+			result = dropResult
+		}
+	}
+
+	drop(dbInstance.SwcDb, swcMetaInfo.Base.Uuid)
+	for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+		drop(dbInstance.SnapshotDb, snapshot.SwcSnapshotCollectionName)
+	}
+	for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
+		drop(dbInstance.IncrementOperationDb, incrementOperation.IncrementOperationCollectionName)
+	}
+	drop(dbInstance.IncrementOperationDb, swcMetaInfo.CurrentIncrementOperationCollectionName)
+	drop(dbInstance.AttachmentDb, "Attachment_Ano_"+swcMetaInfo.Base.Uuid)
+	drop(dbInstance.AttachmentDb, swcMetaInfo.SwcAttachmentApoMetaInfo.AttachmentUuid)
+	drop(dbInstance.AttachmentDb, swcMetaInfo.SwcAttachmentSwcUuid)
+
+	return result
+}
+
+type OrphanCollection struct {
+	DatabaseName   string
+	CollectionName string
+}
+
+func (orphanCollection OrphanCollection) key() string {
+	return orphanCollection.DatabaseName + "." + orphanCollection.CollectionName
+}
+
+// FindOrphanCollections lists the snapshot, increment operation and attachment collections which no swc meta info
+// references. Collections without the prefixes the server uses are ignored.
+func FindOrphanCollections() ([]OrphanCollection, dal.ReturnWrapper) {
+	var swcMetaInfoList []dbmodel.SwcMetaInfoV1
+	if result := dal.QueryAllSwc(&swcMetaInfoList, dal.GetDbInstance()); !result.Status {
+		return nil, result
+	}
+
+	referencedCollections := make(map[string]bool)
+	for _, swcMetaInfo := range swcMetaInfoList {
+		for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+			referencedCollections[snapshot.SwcSnapshotCollectionName] = true
+		}
+		for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
+			referencedCollections[incrementOperation.IncrementOperationCollectionName] = true
+		}
+		referencedCollections[swcMetaInfo.CurrentIncrementOperationCollectionName] = true
+		referencedCollections["Attachment_Ano_"+swcMetaInfo.Base.Uuid] = true
+		referencedCollections[swcMetaInfo.SwcAttachmentApoMetaInfo.AttachmentUuid] = true
+		referencedCollections[swcMetaInfo.SwcAttachmentSwcUuid] = true
+	}
+
+	dbInstance := dal.GetDbInstance()
+	scanList := []struct {
+		database *mongo.Database
+		prefix   string
+	}{
+		{dbInstance.SnapshotDb, "Snapshot_"},
+		{dbInstance.IncrementOperationDb, "IncrementOperation_"},
+		{dbInstance.IncrementOperationDb, dal.SwcIncrementOperationCopyPrefix},
+		{dbInstance.AttachmentDb, "Attachment_"},
+	}
+
+	var orphanCollections []OrphanCollection
+	for _, scan := range scanList {
+		var collectionNames []string
+		if result := dal.ListCollectionNames(scan.database, &collectionNames); !result.Status {
+			return nil, result
+		}
+		sort.Strings(collectionNames)
+		for _, collectionName := range collectionNames {
+			if strings.HasPrefix(collectionName, scan.prefix) && !referencedCollections[collectionName] {
+				orphanCollections = append(orphanCollections, OrphanCollection{scan.database.Name(), collectionName})
+			}
+		}
+	}
+
+	return orphanCollections, dal.ReturnWrapper{Status: true, Message: "Find orphan collections successfully!"}
+}
+
+var orphanCollectionGcMutex sync.Mutex
+var lastOrphanCollections = map[string]bool{}
+
+// RunOrphanCollectionGc reports orphan collections, and drops them when removeOrphans is set. A collection is only
+// dropped once it was found orphaned by two runs in a row, because requests create collections before they record
+// them in the swc meta info.
+func RunOrphanCollectionGc(removeOrphans bool) (int, int, dal.ReturnWrapper) {
+	orphanCollectionGcMutex.Lock()
+	defer orphanCollectionGcMutex.Unlock()
+
+	orphanCollections, result := FindOrphanCollections()
+	if !result.Status {
+		return 0, 0, result
+	}
+
+	dbInstance := dal.GetDbInstance()
+	databases := map[string]*mongo.Database{
+		dbInstance.SnapshotDb.Name():           dbInstance.SnapshotDb,
+		dbInstance.IncrementOperationDb.Name(): dbInstance.IncrementOperationDb,
+		dbInstance.AttachmentDb.Name():         dbInstance.AttachmentDb,
+	}
+
+	currentOrphanCollections := make(map[string]bool, len(orphanCollections))
+	removedNumber := 0
+	for _, orphanCollection := range orphanCollections {
+		currentOrphanCollections[orphanCollection.key()] = true
+		logger.GetLogger().Println("Orphan collection " + orphanCollection.key())
+		if !removeOrphans || !lastOrphanCollections[orphanCollection.key()] {
+			continue
+		}
+		if dropResult := dal.DropCollection(databases[orphanCollection.DatabaseName], orphanCollection.CollectionName); dropResult.Status {
+			removedNumber++
+			delete(currentOrphanCollections, orphanCollection.key())
+		} else {
+			logger.GetLogger().Println(dropResult.Message)
+		}
+	}
+	lastOrphanCollections = currentOrphanCollections
+
+	return len(orphanCollections), removedNumber, dal.ReturnWrapper{Status: true, Message: "Orphan collection gc finished!"}
+}
+
+func CronOrphanCollectionGc() {
+	c := cron.New(cron.WithSeconds(), cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)), cron.WithLogger(
+		cron.VerbosePrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))))
+	EntryID, err := c.AddFunc("0 30 */6 * * *", func() {
+		logger.GetLogger().Println(time.Now(), "CronOrphanCollectionGc...")
+
+		orphanNumber, removedNumber, result := RunOrphanCollectionGc(config.AppConfig.GarbageCollectionRemoveOrphans)
+		if !result.Status {
+			logger.GetLogger().Println(result.Message)
+			return
+		}
+		logger.GetLogger().Println("Orphan collections found " + strconv.Itoa(orphanNumber) + ", removed " + strconv.Itoa(removedNumber))
+	})
+	logger.GetLogger().Println(time.Now(), EntryID, err)
+
+	c.Start()
+}
@@ -0,0 +1,165 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"DBMS/logger"
+	"context"
+	"reflect"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewSwcPermission gives the executor and its permission group full access, the same as CreateSwc.
+func NewSwcPermission(executorUserMetaInfo *dbmodel.UserMetaInfoV1) dbmodel.PermissionMetaInfoV1 {
+	var permission dbmodel.PermissionMetaInfoV1
+	permission.Owner.UserUuid = executorUserMetaInfo.Base.Uuid
+	ownerAce := reflect.ValueOf(&permission.Owner.Ace).Elem()
+	for i := 0; i < ownerAce.NumField(); i++ {
+		ownerAce.Field(i).Set(reflect.ValueOf(true))
+	}
+
+	var groupPermission dbmodel.GroupPermissionAclV1
+	groupPermission.GroupUuid = executorUserMetaInfo.PermissionGroupUuid
+	groupAce := reflect.ValueOf(&groupPermission.Ace).Elem()
+	for i := 0; i < groupAce.NumField(); i++ {
+		groupAce.Field(i).Set(reflect.ValueOf(true))
+	}
+	permission.Groups = append(permission.Groups, groupPermission)
+
+	return permission
+}
+
+// swcCloneNodeUuids gives every node uuid of the source swc a new uuid in the clone, so a node uuid is never shared by
+// two swcs. A node keeps the same new uuid in the nodes, snapshots and increment operations of the clone, so its
+// copied history still replays to its nodes.
+type swcCloneNodeUuids map[string]string
+
+func (nodeUuids swcCloneNodeUuids) cloneUuid(nodeUuid string) string {
+	if nodeUuid == "" {
+		return ""
+	}
+	cloneUuid, ok := nodeUuids[nodeUuid]
+	if !ok {
+		cloneUuid = uuid.NewString()
+		nodeUuids[nodeUuid] = cloneUuid
+	}
+	return cloneUuid
+}
+
+func (nodeUuids swcCloneNodeUuids) mapNode(node *dbmodel.SwcNodeDataV1) {
+	node.Base.Id = primitive.NewObjectID()
+	node.Base.Uuid = nodeUuids.cloneUuid(node.Base.Uuid)
+}
+
+func (nodeUuids swcCloneNodeUuids) mapOperation(operation *dbmodel.SwcIncrementOperationV1) {
+	for idx := range operation.SwcData {
+		nodeUuids.mapNode(&operation.SwcData[idx])
+	}
+	for idx := range operation.NodeNParent {
+		operation.NodeNParent[idx].Uuid = nodeUuids.cloneUuid(operation.NodeNParent[idx].Uuid)
+	}
+	for idx := range operation.GroupedOperations {
+		nodeUuids.mapOperation(&operation.GroupedOperations[idx])
+	}
+}
+
+// CloneSwcCollections copies the node collection and the ano, apo and soma swc attachments of sourceSwcMetaInfo to
+// new collections owned by targetSwcMetaInfo, whose uuid must already be assigned. The copied nodes get new uuids.
+// With copyHistory the snapshots and increment operation lists are copied under new names as well, otherwise the
+// clone starts its own history with a snapshot of the copied nodes.
+//
+// Every collection is recorded in targetSwcMetaInfo before it is written, so on failure DeleteSwcCollections of
+// targetSwcMetaInfo releases whatever was copied.
+func CloneSwcCollections(sourceSwcMetaInfo *dbmodel.SwcMetaInfoV1, targetSwcMetaInfo *dbmodel.SwcMetaInfoV1, copyHistory bool, creator string) dal.ReturnWrapper {
+	targetSwcMetaInfo.SwcSnapshotList = nil
+	targetSwcMetaInfo.SwcIncrementOperationList = nil
+	targetSwcMetaInfo.CurrentIncrementOperationCollectionName = ""
+	targetSwcMetaInfo.SwcRemovedHistoryList = nil
+	targetSwcMetaInfo.SwcAttachmentApoMetaInfo.AttachmentUuid = ""
+	targetSwcMetaInfo.SwcAttachmentSwcUuid = ""
+
+	nodeUuids := swcCloneNodeUuids{}
+	if result := dal.CopySwcData(sourceSwcMetaInfo.Base.Uuid, targetSwcMetaInfo.Base.Uuid, nodeUuids.mapNode, dal.GetDbInstance()); !result.Status {
+		return result
+	}
+
+	if sourceSwcMetaInfo.SwcAttachmentAnoMetaInfo.AttachmentUuid != "" {
+		targetSwcMetaInfo.SwcAttachmentAnoMetaInfo.AttachmentUuid = sourceSwcMetaInfo.SwcAttachmentAnoMetaInfo.AttachmentUuid
+		result := dal.CopyAttachment("Attachment_Ano_"+sourceSwcMetaInfo.Base.Uuid, "Attachment_Ano_"+targetSwcMetaInfo.Base.Uuid, dal.GetDbInstance())
+		if !result.Status {
+			return result
+		}
+	}
+
+	if sourceSwcMetaInfo.SwcAttachmentApoMetaInfo.AttachmentUuid != "" {
+		targetSwcMetaInfo.SwcAttachmentApoMetaInfo.AttachmentUuid = "Attachment_Apo_" + uuid.NewString()
+		result := dal.CopyAttachment(sourceSwcMetaInfo.SwcAttachmentApoMetaInfo.AttachmentUuid, targetSwcMetaInfo.SwcAttachmentApoMetaInfo.AttachmentUuid, dal.GetDbInstance())
+		if !result.Status {
+			return result
+		}
+	}
+
+	if sourceSwcMetaInfo.SwcAttachmentSwcUuid != "" {
+		targetSwcMetaInfo.SwcAttachmentSwcUuid = "Attachment_Swc_" + uuid.NewString()
+		result := dal.CopyAttachment(sourceSwcMetaInfo.SwcAttachmentSwcUuid, targetSwcMetaInfo.SwcAttachmentSwcUuid, dal.GetDbInstance())
+		if !result.Status {
+			return result
+		}
+	}
+
+	if copyHistory && len(sourceSwcMetaInfo.SwcIncrementOperationList) != 0 {
+		return cloneSwcHistory(sourceSwcMetaInfo, targetSwcMetaInfo, nodeUuids)
+	}
+
+	if swcSnapshotMetaInfo, _, result := CreateSwcSnapshotAndIncrementList(context.TODO(), targetSwcMetaInfo, creator); !result.Status {
+		// the snapshot is only added to targetSwcMetaInfo once it is copied
+		if dropResult := dal.DropCollection(dal.GetDbInstance().SnapshotDb, swcSnapshotMetaInfo.SwcSnapshotCollectionName); !dropResult.Status {
+			logger.GetLogger().Println(dropResult.Message)
+		}
+		return result
+	}
+
+	return dal.ReturnWrapper{Status: true, Message: "Clone swc collections successfully!"}
+}
+
+// cloneSwcHistory copies every snapshot and increment operation list under a new name, keeping creation times so
+// RevertSwcVersion and GetSwcNodeDataAtTime work on the clone the same way as on the source. The node uuids in them
+// are mapped by nodeUuids like the copied nodes.
+func cloneSwcHistory(sourceSwcMetaInfo *dbmodel.SwcMetaInfoV1, targetSwcMetaInfo *dbmodel.SwcMetaInfoV1, nodeUuids swcCloneNodeUuids) dal.ReturnWrapper {
+	targetSwcMetaInfo.SwcRemovedHistoryList = sourceSwcMetaInfo.SwcRemovedHistoryList
+	snapshotNames := make(map[string]string, len(sourceSwcMetaInfo.SwcSnapshotList))
+	for _, snapshot := range sourceSwcMetaInfo.SwcSnapshotList {
+		snapshotName := "Snapshot_" + uuid.NewString()
+		sourceSnapshotName := snapshot.SwcSnapshotCollectionName
+		snapshotNames[sourceSnapshotName] = snapshotName
+
+		snapshot.Base.Id = primitive.NewObjectID()
+		snapshot.Base.Uuid = uuid.NewString()
+		snapshot.SwcSnapshotCollectionName = snapshotName
+		targetSwcMetaInfo.SwcSnapshotList = append(targetSwcMetaInfo.SwcSnapshotList, snapshot)
+		if result := dal.CopySwcSnapshot(sourceSnapshotName, snapshotName, nodeUuids.mapNode, dal.GetDbInstance()); !result.Status {
+			return result
+		}
+	}
+
+	for _, incrementOperation := range sourceSwcMetaInfo.SwcIncrementOperationList {
+		collectionName := "IncrementOperation_" + uuid.NewString()
+		sourceCollectionName := incrementOperation.IncrementOperationCollectionName
+		if sourceCollectionName == sourceSwcMetaInfo.CurrentIncrementOperationCollectionName {
+			targetSwcMetaInfo.CurrentIncrementOperationCollectionName = collectionName
+		}
+
+		incrementOperation.Base.Id = primitive.NewObjectID()
+		incrementOperation.Base.Uuid = uuid.NewString()
+		incrementOperation.StartSnapshot = snapshotNames[incrementOperation.StartSnapshot]
+		incrementOperation.IncrementOperationCollectionName = collectionName
+		targetSwcMetaInfo.SwcIncrementOperationList = append(targetSwcMetaInfo.SwcIncrementOperationList, incrementOperation)
+		if result := dal.CopySwcIncrementOperation(sourceCollectionName, collectionName, nodeUuids.mapOperation, dal.GetDbInstance()); !result.Status {
+			return result
+		}
+	}
+
+	return dal.ReturnWrapper{Status: true, Message: "Clone swc collections successfully!"}
+}
@@ -0,0 +1,61 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"testing"
+)
+
+func TestSwcCloneNodeUuidsMapsHistoryConsistently(t *testing.T) {
+	nodeUuids := swcCloneNodeUuids{}
+
+	node := newTestSwcNode(1, -1, 1, 0, 0, 0)
+	sourceId := node.Base.Id
+	nodeUuids.mapNode(&node)
+	if node.Base.Uuid == "node-1" || node.Base.Uuid == "" {
+		t.Fatalf("cloned node uuid = %q, want a new uuid", node.Base.Uuid)
+	}
+	if node.Base.Id == sourceId {
+		t.Fatalf("cloned node kept the _id of the source node")
+	}
+	cloneUuid := node.Base.Uuid
+
+	snapshotNode := newTestSwcNode(1, -1, 1, 0, 0, 0)
+	nodeUuids.mapNode(&snapshotNode)
+	if snapshotNode.Base.Uuid != cloneUuid {
+		t.Fatalf("snapshot node uuid = %q, want %q like the cloned node", snapshotNode.Base.Uuid, cloneUuid)
+	}
+
+	operation := dbmodel.SwcIncrementOperationV1{
+		IncrementOperation: dal.IncrementOp_Batch,
+		GroupedOperations: []dbmodel.SwcIncrementOperationV1{
+			{
+				IncrementOperation: dal.IncrementOp_Create,
+				SwcData:            dbmodel.SwcDataV1{newTestSwcNode(1, -1, 1, 0, 0, 0), newTestSwcNode(2, 1, 3, 1, 0, 0)},
+			},
+			{
+				IncrementOperation: dal.IncrementOp_UpdateNParent,
+				NodeNParent:        []dbmodel.NodeNParentV1{{Uuid: "node-2", N: 2, Parent: 1}, {Uuid: "", N: 3, Parent: 2}},
+			},
+		},
+	}
+	nodeUuids.mapOperation(&operation)
+
+	created := operation.GroupedOperations[0].SwcData
+	if created[0].Base.Uuid != cloneUuid {
+		t.Errorf("created node uuid = %q, want %q", created[0].Base.Uuid, cloneUuid)
+	}
+	if created[1].Base.Uuid == "node-2" {
+		t.Errorf("created node uuid was not mapped")
+	}
+	nodeNParent := operation.GroupedOperations[1].NodeNParent
+	if nodeNParent[0].Uuid != created[1].Base.Uuid {
+		t.Errorf("UpdateNParent uuid = %q, want %q like the created node", nodeNParent[0].Uuid, created[1].Base.Uuid)
+	}
+	if nodeNParent[1].Uuid != "" {
+		t.Errorf("empty uuid was mapped to %q", nodeNParent[1].Uuid)
+	}
+	if len(nodeUuids) != 2 {
+		t.Errorf("mapped %d node uuids, want 2", len(nodeUuids))
+	}
+}
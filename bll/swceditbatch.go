@@ -0,0 +1,134 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SwcEditBatchOperationPermission is the swc permission each operation type of an edit batch needs, the same one
+// the corresponding single operation RPC checks.
+var SwcEditBatchOperationPermission = map[string]string{
+	dal.IncrementOp_Create:        "WritePermissionAddSwcData",
+	dal.IncrementOp_Delete:        "WritePermissionDeleteSwcData",
+	dal.IncrementOp_Update:        "WritePermissionModifySwcData",
+	dal.IncrementOp_UpdateNParent: "WritePermissionAddSwcData",
+}
+
+// PrepareSwcEditBatch checks an ordered batch of node operations against the current nodes and fills in the fields
+// the single operation RPCs fill in on the server. A node an update, delete or reparent refers to must exist when the
+// operation runs, which includes nodes created earlier in the batch. Created nodes keep a client supplied uuid when it
+// is a valid unused uuid so later operations of the batch can refer to them. Returns the nodes after the batch.
+func PrepareSwcEditBatch(swcData dbmodel.SwcDataV1, operations []dbmodel.SwcIncrementOperationV1, executorUserName string, editTime time.Time) (dbmodel.SwcDataV1, error) {
+	if len(operations) == 0 {
+		return nil, errors.New("Edit batch is empty!")
+	}
+
+	existingUuids := make(map[string]bool, len(swcData))
+	for idx := range swcData {
+		existingUuids[swcData[idx].Base.Uuid] = true
+	}
+	requireExisting := func(operationIndex int, nodeUuid string) error {
+		if !existingUuids[nodeUuid] {
+			return errors.New("Operation " + strconv.Itoa(operationIndex) + " refers to node " + nodeUuid + " which does not exist!")
+		}
+		return nil
+	}
+
+	for idx := range operations {
+		operation := &operations[idx]
+		if _, ok := SwcEditBatchOperationPermission[operation.IncrementOperation]; !ok {
+			return nil, errors.New("Operation " + strconv.Itoa(idx) + " has type " + operation.IncrementOperation + " which is not supported in an edit batch!")
+		}
+		if len(operation.SwcData) == 0 && len(operation.NodeNParent) == 0 {
+			return nil, errors.New("Operation " + strconv.Itoa(idx) + " is empty!")
+		}
+
+		operation.Base.Id = primitive.NewObjectID()
+		operation.Base.Uuid = uuid.NewString()
+		operation.Base.DataAccessModelVersion = "V1"
+		operation.CreateTime = editTime
+		operation.GroupedOperations = nil
+
+		switch operation.IncrementOperation {
+		case dal.IncrementOp_Create:
+			for nodeIdx := range operation.SwcData {
+				node := &operation.SwcData[nodeIdx]
+				if _, err := uuid.Parse(node.Base.Uuid); err != nil {
+					node.Base.Uuid = uuid.NewString()
+				} else if existingUuids[node.Base.Uuid] {
+					return nil, errors.New("Operation " + strconv.Itoa(idx) + " creates node " + node.Base.Uuid + " which already exists!")
+				}
+				existingUuids[node.Base.Uuid] = true
+				node.Base.Id = primitive.NewObjectID()
+				node.Base.DataAccessModelVersion = "V1"
+				node.Creator = executorUserName
+				node.CreateTime = editTime
+				node.LastModifiedTime = editTime
+				node.CheckerUserUuid = ""
+			}
+		case dal.IncrementOp_Update:
+			for nodeIdx := range operation.SwcData {
+				node := &operation.SwcData[nodeIdx]
+				if err := requireExisting(idx, node.Base.Uuid); err != nil {
+					return nil, err
+				}
+				node.Creator = executorUserName
+				node.LastModifiedTime = editTime
+			}
+		case dal.IncrementOp_Delete:
+			for nodeIdx := range operation.SwcData {
+				if err := requireExisting(idx, operation.SwcData[nodeIdx].Base.Uuid); err != nil {
+					return nil, err
+				}
+				delete(existingUuids, operation.SwcData[nodeIdx].Base.Uuid)
+			}
+		case dal.IncrementOp_UpdateNParent:
+			for _, nodeNParent := range operation.NodeNParent {
+				if err := requireExisting(idx, nodeNParent.Uuid); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for idx := range operations {
+		swcData = ReplaySwcIncrementOperation(swcData, &operations[idx])
+	}
+	return swcData, nil
+}
+
+// SwcEditBatchExistingNodeUuids lists the nodes of swcData which the operations of a batch change or delete, nodes
+// created by the batch itself are left out.
+func SwcEditBatchExistingNodeUuids(swcData dbmodel.SwcDataV1, operations []dbmodel.SwcIncrementOperationV1) []string {
+	existingUuids := make(map[string]bool, len(swcData))
+	for idx := range swcData {
+		existingUuids[swcData[idx].Base.Uuid] = true
+	}
+
+	nodeUuids := []string{}
+	addNodeUuid := func(nodeUuid string) {
+		if existingUuids[nodeUuid] {
+			nodeUuids = append(nodeUuids, nodeUuid)
+			delete(existingUuids, nodeUuid)
+		}
+	}
+	for _, operation := range operations {
+		switch operation.IncrementOperation {
+		case dal.IncrementOp_Update, dal.IncrementOp_Delete:
+			for idx := range operation.SwcData {
+				addNodeUuid(operation.SwcData[idx].Base.Uuid)
+			}
+		case dal.IncrementOp_UpdateNParent:
+			for _, nodeNParent := range operation.NodeNParent {
+				addNodeUuid(nodeNParent.Uuid)
+			}
+		}
+	}
+	return nodeUuids
+}
@@ -0,0 +1,161 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"bufio"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	SwcFileFormat  = "swc"
+	EswcFileFormat = "eswc"
+)
+
+func NormalizeSwcFileFormat(fileFormat string) (string, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileFormat), ".")) {
+	case "", SwcFileFormat:
+		return SwcFileFormat, nil
+	case EswcFileFormat:
+		return EswcFileFormat, nil
+	default:
+		return "", errors.New("Unsupported swc file format: " + fileFormat)
+	}
+}
+
+// ParseSwcFileContent parses the text of a .swc or .eswc file. Comment lines start with '#', columns may be
+// separated by spaces, tabs or commas. For eswc the optional columns after parent are mapped to
+// seg_id, level, mode, timestamp and feature_value in that order.
+func ParseSwcFileContent(fileContent string, fileFormat string) (dbmodel.SwcDataV1, error) {
+	var swcData dbmodel.SwcDataV1
+
+	scanner := bufio.NewScanner(strings.NewReader(fileContent))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		})
+		if len(fields) < 7 {
+			return nil, errors.New("Line " + strconv.Itoa(lineNumber) + ": expected at least 7 columns but got " + strconv.Itoa(len(fields)))
+		}
+
+		columnNumber := 7
+		if fileFormat == EswcFileFormat {
+			columnNumber = min(len(fields), 12)
+		}
+		// x, y, z and radius are the only real valued columns
+		var intValues [12]int32
+		var floatValues [12]float32
+		for idx := 0; idx < columnNumber; idx++ {
+			if idx >= 2 && idx <= 5 {
+				value, err := strconv.ParseFloat(fields[idx], 32)
+				if err != nil {
+					return nil, errors.New("Line " + strconv.Itoa(lineNumber) + ": invalid value " + fields[idx])
+				}
+				floatValues[idx] = float32(value)
+				continue
+			}
+			value, err := parseSwcIntColumn(fields[idx])
+			if err != nil {
+				return nil, errors.New("Line " + strconv.Itoa(lineNumber) + ": invalid integer value " + fields[idx])
+			}
+			intValues[idx] = value
+		}
+
+		var swcNodeData dbmodel.SwcNodeDataV1
+		swcNodeData.SwcNodeInternalData.N = intValues[0]
+		swcNodeData.SwcNodeInternalData.Type = intValues[1]
+		swcNodeData.SwcNodeInternalData.X = floatValues[2]
+		swcNodeData.SwcNodeInternalData.Y = floatValues[3]
+		swcNodeData.SwcNodeInternalData.Z = floatValues[4]
+		swcNodeData.SwcNodeInternalData.Radius = floatValues[5]
+		swcNodeData.SwcNodeInternalData.Parent = intValues[6]
+		swcNodeData.SwcNodeInternalData.Seg_id = intValues[7]
+		swcNodeData.SwcNodeInternalData.Level = intValues[8]
+		swcNodeData.SwcNodeInternalData.Mode = intValues[9]
+		swcNodeData.SwcNodeInternalData.Timestamp = intValues[10]
+		swcNodeData.SwcNodeInternalData.Feature_value = intValues[11]
+
+		swcData = append(swcData, swcNodeData)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return swcData, nil
+}
+
+// parseSwcIntColumn parses an integer column. Some tools write integers as "1.0", integral values are accepted in that
+// form, fractions and values out of the int32 range are rejected instead of being truncated.
+func parseSwcIntColumn(field string) (int32, error) {
+	if value, err := strconv.ParseInt(field, 10, 32); err == nil {
+		return int32(value), nil
+	}
+	value, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, err
+	}
+	if value != math.Trunc(value) || value < math.MinInt32 || value > math.MaxInt32 {
+		return 0, errors.New("not an int32 value " + field)
+	}
+	return int32(value), nil
+}
+
+// FormatSwcFileContent writes nodes ordered by n using the column layout Vaa3D expects for the given format.
+func FormatSwcFileContent(swcData dbmodel.SwcDataV1, fileFormat string) string {
+	sortedSwcData := make(dbmodel.SwcDataV1, len(swcData))
+	copy(sortedSwcData, swcData)
+	sort.SliceStable(sortedSwcData, func(i, j int) bool {
+		return sortedSwcData[i].SwcNodeInternalData.N < sortedSwcData[j].SwcNodeInternalData.N
+	})
+
+	formatFloat := func(value float32) string {
+		return strconv.FormatFloat(float64(value), 'f', 3, 32)
+	}
+
+	var builder strings.Builder
+	if fileFormat == EswcFileFormat {
+		builder.WriteString("#n type x y z radius parent seg_id level mode timestamp feature_value\n")
+	} else {
+		builder.WriteString("#n type x y z radius parent\n")
+	}
+
+	for _, swcNodeData := range sortedSwcData {
+		nodeData := swcNodeData.SwcNodeInternalData
+		columns := []string{
+			strconv.Itoa(int(nodeData.N)),
+			strconv.Itoa(int(nodeData.Type)),
+			formatFloat(nodeData.X),
+			formatFloat(nodeData.Y),
+			formatFloat(nodeData.Z),
+			formatFloat(nodeData.Radius),
+			strconv.Itoa(int(nodeData.Parent)),
+		}
+		if fileFormat == EswcFileFormat {
+			columns = append(columns,
+				strconv.Itoa(int(nodeData.Seg_id)),
+				strconv.Itoa(int(nodeData.Level)),
+				strconv.Itoa(int(nodeData.Mode)),
+				strconv.Itoa(int(nodeData.Timestamp)),
+				strconv.Itoa(int(nodeData.Feature_value)),
+			)
+		}
+		builder.WriteString(strings.Join(columns, " "))
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
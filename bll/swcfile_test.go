@@ -0,0 +1,194 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"testing"
+)
+
+func TestNormalizeSwcFileFormat(t *testing.T) {
+	tests := []struct {
+		fileFormat string
+		want       string
+		wantErr    bool
+	}{
+		{fileFormat: "", want: SwcFileFormat},
+		{fileFormat: "swc", want: SwcFileFormat},
+		{fileFormat: " .SWC ", want: SwcFileFormat},
+		{fileFormat: ".eswc", want: EswcFileFormat},
+		{fileFormat: "ESWC", want: EswcFileFormat},
+		{fileFormat: "obj", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.fileFormat, func(t *testing.T) {
+			got, err := NormalizeSwcFileFormat(test.fileFormat)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NormalizeSwcFileFormat(%q) error = %v, want error %v", test.fileFormat, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("NormalizeSwcFileFormat(%q) = %q, want %q", test.fileFormat, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSwcFileContent(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		fileFormat string
+		want       []dbmodel.SwcNodeInternalDataV1
+		// wantErr is the expected error message, "" when parsing succeeds
+		wantErr string
+	}{
+		{
+			name:       "comments and blank lines",
+			content:    "# header\n\n1 1 0 0 0 1 -1\n  # indented comment\n2 3 1.5 2 3 0.5 1\n",
+			fileFormat: SwcFileFormat,
+			want: []dbmodel.SwcNodeInternalDataV1{
+				{N: 1, Type: 1, Radius: 1, Parent: -1},
+				{N: 2, Type: 3, X: 1.5, Y: 2, Z: 3, Radius: 0.5, Parent: 1},
+			},
+		},
+		{
+			name:       "tab and comma separators",
+			content:    "1\t1\t0\t0\t0\t1\t-1\n2,3,1,2,3,1,1\n",
+			fileFormat: SwcFileFormat,
+			want: []dbmodel.SwcNodeInternalDataV1{
+				{N: 1, Type: 1, Radius: 1, Parent: -1},
+				{N: 2, Type: 3, X: 1, Y: 2, Z: 3, Radius: 1, Parent: 1},
+			},
+		},
+		{
+			name:       "swc ignores extra columns",
+			content:    "1 1 0 0 0 1 -1 7 8 9 10 11\n",
+			fileFormat: SwcFileFormat,
+			want:       []dbmodel.SwcNodeInternalDataV1{{N: 1, Type: 1, Radius: 1, Parent: -1}},
+		},
+		{
+			name:       "eswc extra columns",
+			content:    "1 1 0 0 0 1 -1 7 8 9 10 11\n",
+			fileFormat: EswcFileFormat,
+			want: []dbmodel.SwcNodeInternalDataV1{
+				{N: 1, Type: 1, Radius: 1, Parent: -1, Seg_id: 7, Level: 8, Mode: 9, Timestamp: 10, Feature_value: 11},
+			},
+		},
+		{
+			name:       "eswc without extra columns",
+			content:    "1 1 0 0 0 1 -1 7\n",
+			fileFormat: EswcFileFormat,
+			want:       []dbmodel.SwcNodeInternalDataV1{{N: 1, Type: 1, Radius: 1, Parent: -1, Seg_id: 7}},
+		},
+		{
+			name:       "too few columns",
+			content:    "1 1 0 0 0 1\n",
+			fileFormat: SwcFileFormat,
+			wantErr:    "Line 1: expected at least 7 columns but got 6",
+		},
+		{
+			name:       "invalid value",
+			content:    "1 1 0 0 x 1 -1\n",
+			fileFormat: SwcFileFormat,
+			wantErr:    "Line 1: invalid value x",
+		},
+		{
+			name:       "integral float in an integer column",
+			content:    "1.0 1 0 0 0 1 -1.0\n",
+			fileFormat: SwcFileFormat,
+			want:       []dbmodel.SwcNodeInternalDataV1{{N: 1, Type: 1, Radius: 1, Parent: -1}},
+		},
+		{
+			name:       "fractional n",
+			content:    "1 1 0 0 0 1 -1\n2.5 3 1 0 0 1 1\n",
+			fileFormat: SwcFileFormat,
+			wantErr:    "Line 2: invalid integer value 2.5",
+		},
+		{
+			name:       "fractional parent",
+			content:    "# header\n1 1 0 0 0 1 0.7\n",
+			fileFormat: SwcFileFormat,
+			wantErr:    "Line 2: invalid integer value 0.7",
+		},
+		{
+			name:       "n out of the int32 range",
+			content:    "3000000000 1 0 0 0 1 -1\n",
+			fileFormat: SwcFileFormat,
+			wantErr:    "Line 1: invalid integer value 3000000000",
+		},
+		{
+			name:       "fractional eswc column",
+			content:    "1 1 0 0 0 1 -1 7 8.5\n",
+			fileFormat: EswcFileFormat,
+			wantErr:    "Line 1: invalid integer value 8.5",
+		},
+		{
+			name:       "empty file",
+			content:    "# only a header\n",
+			fileFormat: SwcFileFormat,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			swcData, err := ParseSwcFileContent(test.content, test.fileFormat)
+			if err != nil || test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("ParseSwcFileContent error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if len(swcData) != len(test.want) {
+				t.Fatalf("ParseSwcFileContent returned %d nodes, want %d", len(swcData), len(test.want))
+			}
+			for idx, node := range swcData {
+				if node.SwcNodeInternalData != test.want[idx] {
+					t.Errorf("node %d = %+v, want %+v", idx, node.SwcNodeInternalData, test.want[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestFormatSwcFileContent(t *testing.T) {
+	swcData := dbmodel.SwcDataV1{
+		newTestSwcNode(2, 1, 3, 1.5, 2, 3),
+		newTestSwcNode(1, -1, 1, 0, 0, 0),
+	}
+	swcData[0].SwcNodeInternalData.Seg_id = 4
+
+	tests := []struct {
+		fileFormat string
+		want       string
+		// wantNode is node 2 after parsing the formatted content, swc has no seg_id column
+		wantNode dbmodel.SwcNodeInternalDataV1
+	}{
+		{
+			fileFormat: SwcFileFormat,
+			want: "#n type x y z radius parent\n" +
+				"1 1 0.000 0.000 0.000 1.000 -1\n" +
+				"2 3 1.500 2.000 3.000 1.000 1\n",
+			wantNode: dbmodel.SwcNodeInternalDataV1{N: 2, Type: 3, X: 1.5, Y: 2, Z: 3, Radius: 1, Parent: 1},
+		},
+		{
+			fileFormat: EswcFileFormat,
+			want: "#n type x y z radius parent seg_id level mode timestamp feature_value\n" +
+				"1 1 0.000 0.000 0.000 1.000 -1 0 0 0 0 0\n" +
+				"2 3 1.500 2.000 3.000 1.000 1 4 0 0 0 0\n",
+			wantNode: dbmodel.SwcNodeInternalDataV1{N: 2, Type: 3, X: 1.5, Y: 2, Z: 3, Radius: 1, Parent: 1, Seg_id: 4},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.fileFormat, func(t *testing.T) {
+			content := FormatSwcFileContent(swcData, test.fileFormat)
+			if content != test.want {
+				t.Fatalf("FormatSwcFileContent =\n%s\nwant\n%s", content, test.want)
+			}
+
+			parsedSwcData, err := ParseSwcFileContent(content, test.fileFormat)
+			if err != nil {
+				t.Fatalf("ParseSwcFileContent of the formatted content failed: %v", err)
+			}
+			if len(parsedSwcData) != 2 || parsedSwcData[1].SwcNodeInternalData != test.wantNode {
+				t.Errorf("round trip = %+v, want node 2 last as %+v", parsedSwcData, test.wantNode)
+			}
+		})
+	}
+}
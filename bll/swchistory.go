@@ -0,0 +1,321 @@
+package bll
+
+import (
+	"DBMS/SwcDbmsCommon/Generated/go/proto/message"
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ReplaySwcIncrementOperation applies one recorded operation in memory, following the switch in
+// dal.ApplySwcIncrementOperationWithContext.
+func ReplaySwcIncrementOperation(swcData dbmodel.SwcDataV1, operation *dbmodel.SwcIncrementOperationV1) dbmodel.SwcDataV1 {
+	switch operation.IncrementOperation {
+	case dal.IncrementOp_Create:
+		return ApplySwcNodeCreate(swcData, operation.SwcData)
+	case dal.IncrementOp_Delete:
+		return ApplySwcNodeDelete(swcData, operation.SwcData)
+	case dal.IncrementOp_Update:
+		return ApplySwcNodeUpdate(swcData, operation.SwcData)
+	case dal.IncrementOp_UpdateNParent:
+		return ApplySwcNParentUpdate(swcData, operation.NodeNParent)
+	case dal.IncrementOp_ClearAll:
+		return dbmodel.SwcDataV1{}
+	case dal.IncrementOp_OverwriteAll:
+		return ApplySwcNodeCreate(swcData, operation.SwcData)
+	case dal.IncrementOp_Batch:
+		for idx := range operation.GroupedOperations {
+			swcData = ReplaySwcIncrementOperation(swcData, &operation.GroupedOperations[idx])
+		}
+		return swcData
+	}
+	return swcData
+}
+
+// FindSwcVersionStart picks the snapshot and increment operation list used to rebuild the swc at endTime, the same
+// way RevertSwcVersion does: the latest of each created before or at endTime, and the list must start at the snapshot.
+func FindSwcVersionStart(swcMetaInfo *dbmodel.SwcMetaInfoV1, endTime time.Time) (dbmodel.SwcSnapshotMetaInfoV1, dbmodel.SwcIncrementOperationMetaInfoV1, bool) {
+	var latestSnapshot dbmodel.SwcSnapshotMetaInfoV1
+	for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+		if !snapshot.CreateTime.After(endTime) && snapshot.CreateTime.After(latestSnapshot.CreateTime) {
+			latestSnapshot = snapshot
+		}
+	}
+
+	var latestIncrementOperation dbmodel.SwcIncrementOperationMetaInfoV1
+	for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
+		if !incrementOperation.CreateTime.After(endTime) && incrementOperation.CreateTime.After(latestIncrementOperation.CreateTime) {
+			latestIncrementOperation = incrementOperation
+		}
+	}
+
+	ok := latestIncrementOperation.StartSnapshot != "" && latestIncrementOperation.StartSnapshot == latestSnapshot.SwcSnapshotCollectionName
+	return latestSnapshot, latestIncrementOperation, ok
+}
+
+// IsSwcHistoryRemovedAt reports whether the increment operations recorded around versionTime were removed by the
+// snapshot policy or by deleting a snapshot, so the swc cannot be rebuilt at that time anymore.
+func IsSwcHistoryRemovedAt(swcMetaInfo *dbmodel.SwcMetaInfoV1, versionTime time.Time) bool {
+	for _, historyRange := range swcMetaInfo.SwcRemovedHistoryList {
+		if !versionTime.Before(historyRange.StartTime) && (historyRange.EndTime.IsZero() || versionTime.Before(historyRange.EndTime)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RebuildSwcNodeDataAtTime loads the nearest snapshot and replays the increment operations up to endTime in memory.
+// Neither the live swc collection nor the increment operation collections are modified.
+func RebuildSwcNodeDataAtTime(swcMetaInfo *dbmodel.SwcMetaInfoV1, endTime time.Time, swcData *dbmodel.SwcDataV1) dal.ReturnWrapper {
+	if IsSwcHistoryRemovedAt(swcMetaInfo, endTime) {
+		return dal.ReturnWrapper{Status: false, Message: "History of swc " + swcMetaInfo.Base.Uuid + " at " + endTime.String() + " is no longer available!"}
+	}
+
+	snapshot, incrementOperationMetaInfo, ok := FindSwcVersionStart(swcMetaInfo, endTime)
+	if !ok {
+		return dal.ReturnWrapper{Status: false, Message: "Cannot find snapshot and increment operation list of swc " + swcMetaInfo.Base.Uuid + " at " + endTime.String() + "!"}
+	}
+
+	var nodes dbmodel.SwcDataV1
+	if result := dal.QuerySwcSnapshot(snapshot.SwcSnapshotCollectionName, &nodes, dal.GetDbInstance()); !result.Status {
+		return result
+	}
+
+	var operations dbmodel.SwcIncrementOperationListV1
+	if result := dal.QuerySwcIncrementOperation(incrementOperationMetaInfo.IncrementOperationCollectionName, &operations, dal.GetDbInstance()); !result.Status {
+		return result
+	}
+
+	for idx := range operations {
+		if operations[idx].CreateTime.After(endTime) {
+			continue
+		}
+		nodes = ReplaySwcIncrementOperation(nodes, &operations[idx])
+	}
+
+	*swcData = nodes
+	return dal.ReturnWrapper{Status: true, Message: "Rebuild swc node data successfully!"}
+}
+
+// swcRevertAttempts is how often RevertSwcToTime rebuilds the swc after a write or a snapshot changed it meanwhile.
+const swcRevertAttempts = 3
+
+// RevertSwcToTime brings the swc back to its state at endTime and drops the history after it. The state is rebuilt in
+// memory from the nearest snapshot outside of any transaction, the transaction then only writes the nodes which differ
+// from the current ones, truncates the increment operation list and switches the history in the meta info. The write
+// needs the swc to still be at the revision the nodes were compared at, or at expectedRevision when one is given.
+func RevertSwcToTime(swcUuid string, endTime time.Time, expectedRevision *int64) (int64, *SwcRevisionConflict, dal.ReturnWrapper) {
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var result dal.ReturnWrapper
+	for attempt := 0; attempt < swcRevertAttempts; attempt++ {
+		var swcMetaInfo dbmodel.SwcMetaInfoV1
+		swcMetaInfo.Base.Uuid = swcUuid
+		if result = dal.QuerySwc(&swcMetaInfo, dal.GetDbInstance()); !result.Status {
+			return 0, nil, result
+		}
+		_, incrementOperationMetaInfo, ok := FindSwcVersionStart(&swcMetaInfo, endTime)
+		if !ok {
+			return 0, nil, dal.ReturnWrapper{Status: false, Message: "Critical! Dbms cannot decided which increment operation list can be used to revert swc version to " + endTime.String() + "!"}
+		}
+
+		// the revision is read before the nodes, a write after it fails the revision check below
+		checkedRevision := swcMetaInfo.Revision
+		if expectedRevision != nil {
+			checkedRevision = *expectedRevision
+		}
+		var currentSwcData dbmodel.SwcDataV1
+		if result = dal.QueryAllSwcData(swcUuid, &currentSwcData, dal.GetDbInstance()); !result.Status {
+			return 0, nil, result
+		}
+		var revertedSwcData dbmodel.SwcDataV1
+		if result = RebuildSwcNodeDataAtTime(&swcMetaInfo, endTime, &revertedSwcData); !result.Status {
+			return 0, nil, result
+		}
+		addedNodes, deletedNodes, modifiedNodes := DiffSwcVersions(currentSwcData, revertedSwcData)
+		removedNodes := deletedNodes
+		for _, modifiedNode := range modifiedNodes {
+			removedNodes = append(removedNodes, modifiedNode.OldNode)
+			addedNodes = append(addedNodes, modifiedNode.NewNode)
+		}
+
+		previousIncrementOperationCollectionName := swcMetaInfo.CurrentIncrementOperationCollectionName
+		revertedSwcMetaInfo := swcMetaInfo
+		revertedSwcMetaInfo.SwcSnapshotList = nil
+		for _, swcSnapshot := range swcMetaInfo.SwcSnapshotList {
+			if !swcSnapshot.CreateTime.After(endTime) {
+				revertedSwcMetaInfo.SwcSnapshotList = append(revertedSwcMetaInfo.SwcSnapshotList, swcSnapshot)
+			}
+		}
+		revertedSwcMetaInfo.SwcIncrementOperationList = nil
+		for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
+			if !incrementOperation.CreateTime.After(endTime) {
+				revertedSwcMetaInfo.SwcIncrementOperationList = append(revertedSwcMetaInfo.SwcIncrementOperationList, incrementOperation)
+			}
+		}
+		revertedSwcMetaInfo.CurrentIncrementOperationCollectionName = incrementOperationMetaInfo.IncrementOperationCollectionName
+		// operations recorded after the revert go to the list current at endTime, a later removed range would hide them
+		revertedSwcMetaInfo.SwcRemovedHistoryList = nil
+		for _, historyRange := range swcMetaInfo.SwcRemovedHistoryList {
+			if !historyRange.StartTime.After(endTime) {
+				revertedSwcMetaInfo.SwcRemovedHistoryList = append(revertedSwcMetaInfo.SwcRemovedHistoryList, historyRange)
+			}
+		}
+
+		result = dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+			var result dal.ReturnWrapper
+			revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, swcUuid, &checkedRevision, nil, func(newRevision int64) dal.ReturnWrapper {
+				if len(removedNodes) != 0 {
+					if result := dal.DeleteSwcDataWithContext(sessionContext, swcUuid, removedNodes, dal.GetDbInstance()); !result.Status {
+						return result
+					}
+				}
+				if len(addedNodes) != 0 {
+					if result := dal.CreateSwcDataWithContext(sessionContext, swcUuid, &addedNodes, dal.GetDbInstance()); !result.Status {
+						return result
+					}
+				}
+				if result := dal.DeleteSwcIncrementOperationAfterWithContext(sessionContext, incrementOperationMetaInfo.IncrementOperationCollectionName, endTime, dal.GetDbInstance()); !result.Status {
+					return result
+				}
+				NotifySwcChange(sessionContext, SwcChangeEvent{SwcUuid: swcUuid, Revision: newRevision})
+				return dal.SetSwcVersionControlWithContext(sessionContext, revertedSwcMetaInfo, previousIncrementOperationCollectionName, dal.GetDbInstance())
+			})
+			return result
+		})
+		// a conflict with the caller's revision is final, a write or a snapshot landing meanwhile is retried
+		if result.Status || (expectedRevision != nil && revisionConflict != nil) {
+			break
+		}
+	}
+	if !result.Status {
+		return 0, revisionConflict, result
+	}
+	return revision, nil, dal.ReturnWrapper{Status: true, Message: "Revert swc " + swcUuid + " to " + endTime.String() + " successfully!"}
+}
+
+// LoadSwcVersion loads the nodes of one diff endpoint: the live collection, one of the swc's snapshots, or the state
+// rebuilt at a point in time.
+func LoadSwcVersion(swcMetaInfo *dbmodel.SwcMetaInfoV1, endpoint *message.SwcVersionEndpointV1, swcData *dbmodel.SwcDataV1) dal.ReturnWrapper {
+	if endpoint.GetIsCurrent() {
+		return dal.QueryAllSwcData(swcMetaInfo.Base.Uuid, swcData, dal.GetDbInstance())
+	}
+
+	if endpoint.GetSwcSnapshotCollectionName() != "" {
+		for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+			if snapshot.SwcSnapshotCollectionName == endpoint.GetSwcSnapshotCollectionName() {
+				return dal.QuerySwcSnapshot(snapshot.SwcSnapshotCollectionName, swcData, dal.GetDbInstance())
+			}
+		}
+		return dal.ReturnWrapper{Status: false, Message: "Cannot find snapshot " + endpoint.GetSwcSnapshotCollectionName() + " in swc " + swcMetaInfo.Base.Uuid}
+	}
+
+	if endpoint.GetVersionTime() != nil {
+		return RebuildSwcNodeDataAtTime(swcMetaInfo, endpoint.GetVersionTime().AsTime(), swcData)
+	}
+
+	return dal.ReturnWrapper{Status: false, Message: "Version endpoint must be current, a snapshot name or a time!"}
+}
+
+type SwcNodeFieldDiff struct {
+	FieldName string
+	OldValue  string
+	NewValue  string
+}
+
+type SwcNodeDiff struct {
+	NodeUuid   string
+	FieldDiffs []SwcNodeFieldDiff
+	OldNode    dbmodel.SwcNodeDataV1
+	NewNode    dbmodel.SwcNodeDataV1
+}
+
+func DiffSwcNodeData(oldNode *dbmodel.SwcNodeDataV1, newNode *dbmodel.SwcNodeDataV1) []SwcNodeFieldDiff {
+	formatInt := func(value int32) string {
+		return strconv.Itoa(int(value))
+	}
+	formatFloat := func(value float32) string {
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	}
+
+	oldData := &oldNode.SwcNodeInternalData
+	newData := &newNode.SwcNodeInternalData
+	fields := []SwcNodeFieldDiff{
+		{"n", formatInt(oldData.N), formatInt(newData.N)},
+		{"type", formatInt(oldData.Type), formatInt(newData.Type)},
+		{"x", formatFloat(oldData.X), formatFloat(newData.X)},
+		{"y", formatFloat(oldData.Y), formatFloat(newData.Y)},
+		{"z", formatFloat(oldData.Z), formatFloat(newData.Z)},
+		{"radius", formatFloat(oldData.Radius), formatFloat(newData.Radius)},
+		{"parent", formatInt(oldData.Parent), formatInt(newData.Parent)},
+		{"seg_id", formatInt(oldData.Seg_id), formatInt(newData.Seg_id)},
+		{"level", formatInt(oldData.Level), formatInt(newData.Level)},
+		{"mode", formatInt(oldData.Mode), formatInt(newData.Mode)},
+		{"timestamp", formatInt(oldData.Timestamp), formatInt(newData.Timestamp)},
+		{"feature_value", formatInt(oldData.Feature_value), formatInt(newData.Feature_value)},
+		{"CheckerUserUuid", oldNode.CheckerUserUuid, newNode.CheckerUserUuid},
+	}
+
+	var fieldDiffs []SwcNodeFieldDiff
+	for _, field := range fields {
+		if field.OldValue != field.NewValue {
+			fieldDiffs = append(fieldDiffs, field)
+		}
+	}
+	return fieldDiffs
+}
+
+// DiffSwcVersions matches nodes by uuid and returns the added, deleted and modified nodes going from oldSwcData to
+// newSwcData. Results are ordered by n of the version the node belongs to.
+func DiffSwcVersions(oldSwcData dbmodel.SwcDataV1, newSwcData dbmodel.SwcDataV1) (dbmodel.SwcDataV1, dbmodel.SwcDataV1, []SwcNodeDiff) {
+	oldNodes := make(map[string]*dbmodel.SwcNodeDataV1, len(oldSwcData))
+	for idx := range oldSwcData {
+		oldNodes[oldSwcData[idx].Base.Uuid] = &oldSwcData[idx]
+	}
+	newNodes := make(map[string]*dbmodel.SwcNodeDataV1, len(newSwcData))
+	for idx := range newSwcData {
+		newNodes[newSwcData[idx].Base.Uuid] = &newSwcData[idx]
+	}
+
+	var addedNodes dbmodel.SwcDataV1
+	var modifiedNodes []SwcNodeDiff
+	for idx := range newSwcData {
+		newNode := &newSwcData[idx]
+		oldNode, ok := oldNodes[newNode.Base.Uuid]
+		if !ok {
+			addedNodes = append(addedNodes, *newNode)
+			continue
+		}
+		if fieldDiffs := DiffSwcNodeData(oldNode, newNode); len(fieldDiffs) != 0 {
+			modifiedNodes = append(modifiedNodes, SwcNodeDiff{
+				NodeUuid:   newNode.Base.Uuid,
+				FieldDiffs: fieldDiffs,
+				OldNode:    *oldNode,
+				NewNode:    *newNode,
+			})
+		}
+	}
+
+	var deletedNodes dbmodel.SwcDataV1
+	for idx := range oldSwcData {
+		if _, ok := newNodes[oldSwcData[idx].Base.Uuid]; !ok {
+			deletedNodes = append(deletedNodes, oldSwcData[idx])
+		}
+	}
+
+	sortByN := func(swcData dbmodel.SwcDataV1) {
+		sort.SliceStable(swcData, func(i, j int) bool {
+			return swcData[i].SwcNodeInternalData.N < swcData[j].SwcNodeInternalData.N
+		})
+	}
+	sortByN(addedNodes)
+	sortByN(deletedNodes)
+	sort.SliceStable(modifiedNodes, func(i, j int) bool {
+		return modifiedNodes[i].NewNode.SwcNodeInternalData.N < modifiedNodes[j].NewNode.SwcNodeInternalData.N
+	})
+
+	return addedNodes, deletedNodes, modifiedNodes
+}
@@ -0,0 +1,60 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MergeSwcNodeData prepares the nodes of sourceSwcData for insertion into a target swc whose largest n is maxTargetN.
+// Every node gets a new uuid and n/parent are shifted past maxTargetN so both numberings stay intact. When
+// linkSourceRootUuid is given, that source root is attached below linkTargetNode. Creator and CreateTime of the source
+// nodes are kept so attribution survives the merge. Returns the nodes to insert and the applied n offset.
+func MergeSwcNodeData(sourceSwcData dbmodel.SwcDataV1, maxTargetN int32, linkSourceRootUuid string, linkTargetNode *dbmodel.SwcNodeDataV1) (dbmodel.SwcDataV1, int32, error) {
+	if (linkSourceRootUuid == "") != (linkTargetNode == nil) {
+		return nil, 0, errors.New("Source root and target node must be given together to link the merged swc!")
+	}
+
+	if len(sourceSwcData) == 0 {
+		return nil, 0, nil
+	}
+
+	minSourceN := sourceSwcData[0].SwcNodeInternalData.N
+	for idx := range sourceSwcData {
+		minSourceN = min(minSourceN, sourceSwcData[idx].SwcNodeInternalData.N)
+	}
+	offset := max(maxTargetN-minSourceN+1, 0)
+
+	mergeTime := time.Now()
+	mergedSwcData := make(dbmodel.SwcDataV1, len(sourceSwcData))
+	linked := linkSourceRootUuid == ""
+	for idx := range sourceSwcData {
+		swcNodeData := sourceSwcData[idx]
+		isLinkRoot := swcNodeData.Base.Uuid == linkSourceRootUuid
+		if isLinkRoot && !IsSwcRootNode(&swcNodeData.SwcNodeInternalData) {
+			return nil, 0, errors.New("Node " + linkSourceRootUuid + " is not a root of source swc!")
+		}
+
+		swcNodeData.Base.Id = primitive.NewObjectID()
+		swcNodeData.Base.Uuid = uuid.NewString()
+		swcNodeData.Base.DataAccessModelVersion = "V1"
+		swcNodeData.LastModifiedTime = mergeTime
+		swcNodeData.SwcNodeInternalData.N += offset
+		if isLinkRoot {
+			swcNodeData.SwcNodeInternalData.Parent = linkTargetNode.SwcNodeInternalData.N
+			linked = true
+		} else if !IsSwcRootNode(&swcNodeData.SwcNodeInternalData) {
+			swcNodeData.SwcNodeInternalData.Parent += offset
+		}
+		mergedSwcData[idx] = swcNodeData
+	}
+
+	if !linked {
+		return nil, 0, errors.New("Cannot find node " + linkSourceRootUuid + " in source swc!")
+	}
+
+	return mergedSwcData, offset, nil
+}
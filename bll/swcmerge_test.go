@@ -0,0 +1,57 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"testing"
+)
+
+func TestMergeSwcNodeData(t *testing.T) {
+	sourceSwcData := dbmodel.SwcDataV1{
+		newTestSwcNode(3, -1, 1, 0, 0, 0),
+		newTestSwcNode(4, 3, 3, 1, 0, 0),
+	}
+	linkTargetNode := newTestSwcNode(7, 6, 3, 0, 0, 0)
+
+	type nodeNParent struct{ N, Parent int32 }
+	tests := []struct {
+		name               string
+		maxTargetN         int32
+		linkSourceRootUuid string
+		linkTargetNode     *dbmodel.SwcNodeDataV1
+		want               []nodeNParent
+		wantOffset         int32
+		wantErr            bool
+	}{
+		{name: "empty target", maxTargetN: 0, want: []nodeNParent{{3, -1}, {4, 3}}},
+		{name: "after the target", maxTargetN: 10, want: []nodeNParent{{11, -1}, {12, 11}}, wantOffset: 8},
+		{name: "linked", maxTargetN: 10, linkSourceRootUuid: "node-3", linkTargetNode: &linkTargetNode, want: []nodeNParent{{11, 7}, {12, 11}}, wantOffset: 8},
+		{name: "link without target node", maxTargetN: 10, linkSourceRootUuid: "node-3", wantErr: true},
+		{name: "link from a non root", maxTargetN: 10, linkSourceRootUuid: "node-4", linkTargetNode: &linkTargetNode, wantErr: true},
+		{name: "link from a missing root", maxTargetN: 10, linkSourceRootUuid: "node-9", linkTargetNode: &linkTargetNode, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mergedSwcData, offset, err := MergeSwcNodeData(sourceSwcData, test.maxTargetN, test.linkSourceRootUuid, test.linkTargetNode)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("MergeSwcNodeData error = %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if offset != test.wantOffset {
+				t.Errorf("offset = %d, want %d", offset, test.wantOffset)
+			}
+			if len(mergedSwcData) != len(test.want) {
+				t.Fatalf("merged %d nodes, want %d", len(mergedSwcData), len(test.want))
+			}
+			for idx, node := range mergedSwcData {
+				if got := (nodeNParent{node.SwcNodeInternalData.N, node.SwcNodeInternalData.Parent}); got != test.want[idx] {
+					t.Errorf("node %d = %v, want %v", idx, got, test.want[idx])
+				}
+				if node.Base.Uuid == sourceSwcData[idx].Base.Uuid {
+					t.Errorf("node %d kept the source uuid", idx)
+				}
+			}
+		})
+	}
+}
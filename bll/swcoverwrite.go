@@ -0,0 +1,89 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"DBMS/logger"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OverwriteSwcNodes replaces every node of the swc with swcData, which must already carry new uuids, and records a
+// ClearAll and an OverwriteAll operation when version control is enabled. A snapshot of the new nodes taken after the
+// write starts a new increment operation list, so rebuilding them does not replay the history before the overwrite.
+// The whole swc has to be at expectedRevision when one is given. The topology issues are returned when
+// VerifySwcTopologyEdit rejects the nodes.
+func OverwriteSwcNodes(swcMetaInfo *dbmodel.SwcMetaInfoV1, swcData dbmodel.SwcDataV1, expectedRevision *int64, executor SwcIncrementOperationExecutor, creator string) (int64, *SwcRevisionConflict, []SwcTopologyIssue, dal.ReturnWrapper) {
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var topologyIssues []SwcTopologyIssue
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		var result dal.ReturnWrapper
+		if result, topologyIssues = VerifySwcTopologyEdit(sessionContext, swcMetaInfo, func(currentSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+			return swcData
+		}); !result.Status {
+			return result
+		}
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, swcMetaInfo.Base.Uuid, expectedRevision, nil, func(newRevision int64) dal.ReturnWrapper {
+			operationTime := time.Now()
+			for idx := range swcData {
+				swcData[idx].Version = newRevision
+			}
+			result := dal.ClearAllNodeWithContext(sessionContext, swcMetaInfo.Base.Uuid, dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			if swcMetaInfo.CurrentIncrementOperationCollectionName != "" {
+				operationRecord := dbmodel.SwcIncrementOperationV1{}
+				operationRecord.Base.Id = primitive.NewObjectID()
+				operationRecord.Base.Uuid = uuid.NewString()
+				operationRecord.Base.DataAccessModelVersion = "V1"
+				operationRecord.IncrementOperation = dal.IncrementOp_ClearAll
+				operationRecord.CreateTime = operationTime
+				if incrementResult := RecordSwcIncrementOperation(sessionContext, swcMetaInfo, executor, newRevision, operationRecord); !incrementResult.Status {
+					return incrementResult
+				}
+			}
+
+			if len(swcData) == 0 {
+				return result
+			}
+
+			result = dal.CreateSwcDataWithContext(sessionContext, swcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance())
+			if !result.Status {
+				return result
+			}
+
+			if swcMetaInfo.CurrentIncrementOperationCollectionName != "" {
+				operationRecord := dbmodel.SwcIncrementOperationV1{}
+				operationRecord.Base.Id = primitive.NewObjectID()
+				operationRecord.Base.Uuid = uuid.NewString()
+				operationRecord.Base.DataAccessModelVersion = "V1"
+				operationRecord.IncrementOperation = dal.IncrementOp_OverwriteAll
+				operationRecord.SwcData = swcData
+				operationRecord.CreateTime = operationTime
+				if incrementResult := RecordSwcIncrementOperation(sessionContext, swcMetaInfo, executor, newRevision, operationRecord); !incrementResult.Status {
+					return incrementResult
+				}
+			}
+
+			return result
+		})
+		return result
+	})
+	if !result.Status || len(swcData) == 0 {
+		return revision, revisionConflict, topologyIssues, result
+	}
+
+	// taken after the commit, the copy of the nodes stays out of the transaction. Until it is saved the ClearAll and
+	// OverwriteAll operations in the old list rebuild the same nodes
+	snapshotSwcMetaInfo := *swcMetaInfo
+	if snapshotResult := CreateAndSaveSwcSnapshot(&snapshotSwcMetaInfo, creator); !snapshotResult.Status {
+		logger.GetLogger().Println("Create snapshot after overwriting swc " + swcMetaInfo.Base.Uuid + " failed! " + snapshotResult.Message)
+	}
+	return revision, revisionConflict, topologyIssues, result
+}
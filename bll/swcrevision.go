@@ -0,0 +1,136 @@
+package bll
+
+import (
+	"DBMS/SwcDbmsCommon/Generated/go/proto/message"
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"DBMS/errcode"
+	"context"
+	"strconv"
+)
+
+// SwcRevisionConflict describes why a write with an expected revision was rejected. ConflictNodeUuids lists the nodes
+// the write touches which were changed or deleted after the expected revision, it is empty when the write covers the
+// whole swc.
+type SwcRevisionConflict struct {
+	CurrentRevision   int64
+	ExpectedRevision  int64
+	ConflictNodeUuids []string
+}
+
+func SwcRevisionConflictToProtobuf(conflict *SwcRevisionConflict) *message.SwcRevisionConflictV1 {
+	if conflict == nil {
+		return nil
+	}
+	return &message.SwcRevisionConflictV1{
+		CurrentRevision:   conflict.CurrentRevision,
+		ExpectedRevision:  conflict.ExpectedRevision,
+		ConflictNodeUuids: conflict.ConflictNodeUuids,
+	}
+}
+
+// SwcWriteFailedMetaInfo is the response meta info of a failed node data write, a revision conflict gets its own
+// error id so clients can reload and retry.
+func SwcWriteFailedMetaInfo(result dal.ReturnWrapper, conflict *SwcRevisionConflict) *message.ResponseMetaInfoV1 {
+	metaInfo := &message.ResponseMetaInfoV1{
+		Status:  false,
+		Id:      "",
+		Message: result.Message,
+	}
+	if conflict != nil {
+		metaInfo.Id = errcode.ErrorSwcRevisionConflict
+	}
+	return metaInfo
+}
+
+// SwcEditFailedMetaInfo is SwcWriteFailedMetaInfo for a write checked by VerifySwcTopologyEdit, an edit rejected for
+// topologyIssues gets ErrorSwcTopologyValidationFailed.
+func SwcEditFailedMetaInfo(result dal.ReturnWrapper, conflict *SwcRevisionConflict, topologyIssues []SwcTopologyIssue) *message.ResponseMetaInfoV1 {
+	metaInfo := SwcWriteFailedMetaInfo(result, conflict)
+	if len(topologyIssues) != 0 {
+		metaInfo.Id = errcode.ErrorSwcTopologyValidationFailed
+	}
+	return metaInfo
+}
+
+// RunSwcRevisionWrite advances the revision of the swc and runs write with the new revision, then stamps the nodes
+// the write touched with it. Run it inside the transaction of the write so both commit together.
+//
+// Without an expected revision the write always goes ahead. With one, a write to some nodes only conflicts when one
+// of nodeUuids was changed or deleted after the expected revision, edits to other nodes are merged. A write covering
+// the whole swc, marked by a nil nodeUuids, needs the swc to still be at the expected revision. Nodes the write
+// creates are not in nodeUuids, write sets their version itself.
+func RunSwcRevisionWrite(ctx context.Context, swcUuid string, expectedRevision *int64, nodeUuids []string, write func(newRevision int64) dal.ReturnWrapper) (int64, *SwcRevisionConflict, dal.ReturnWrapper) {
+	var currentRevision int64
+	if result := dal.QuerySwcRevisionWithContext(ctx, swcUuid, &currentRevision, dal.GetDbInstance()); !result.Status {
+		return 0, nil, result
+	}
+
+	if expectedRevision != nil && *expectedRevision != currentRevision {
+		conflict := &SwcRevisionConflict{
+			CurrentRevision:   currentRevision,
+			ExpectedRevision:  *expectedRevision,
+			ConflictNodeUuids: []string{},
+		}
+		if nodeUuids == nil {
+			return 0, conflict, dal.ReturnWrapper{Status: false, Message: "Swc " + swcUuid + " has changed since revision " + strconv.FormatInt(*expectedRevision, 10) + ", current revision is " + strconv.FormatInt(currentRevision, 10) + "!"}
+		}
+
+		var swcData dbmodel.SwcDataV1
+		if result := dal.QuerySwcDataByUuidWithContext(ctx, swcUuid, nodeUuids, &swcData, dal.GetDbInstance()); !result.Status {
+			return 0, nil, result
+		}
+		nodeVersions := make(map[string]int64, len(swcData))
+		for _, node := range swcData {
+			nodeVersions[node.Base.Uuid] = node.Version
+		}
+		for _, nodeUuid := range nodeUuids {
+			if version, ok := nodeVersions[nodeUuid]; !ok || version > *expectedRevision {
+				conflict.ConflictNodeUuids = append(conflict.ConflictNodeUuids, nodeUuid)
+			}
+		}
+		if len(conflict.ConflictNodeUuids) != 0 {
+			return 0, conflict, dal.ReturnWrapper{Status: false, Message: strconv.Itoa(len(conflict.ConflictNodeUuids)) + " nodes have changed since revision " + strconv.FormatInt(*expectedRevision, 10) + ", current revision is " + strconv.FormatInt(currentRevision, 10) + "!"}
+		}
+	}
+
+	// a write with an expected revision claims the revision it checked against, the others just take the next one
+	var checkedRevision *int64
+	if expectedRevision != nil {
+		checkedRevision = &currentRevision
+	}
+	var newRevision int64
+	if result := dal.IncreaseSwcRevisionWithContext(ctx, swcUuid, checkedRevision, &newRevision, dal.GetDbInstance()); !result.Status {
+		if expectedRevision == nil {
+			return 0, nil, result
+		}
+		// another writer claimed the next revision between the check and the increase
+		return 0, &SwcRevisionConflict{CurrentRevision: currentRevision + 1, ExpectedRevision: *expectedRevision, ConflictNodeUuids: []string{}}, result
+	}
+
+	result := write(newRevision)
+	if !result.Status {
+		return 0, nil, result
+	}
+
+	if versionResult := dal.SetSwcNodeVersionWithContext(ctx, swcUuid, nodeUuids, newRevision, dal.GetDbInstance()); !versionResult.Status {
+		return 0, nil, versionResult
+	}
+	return newRevision, nil, result
+}
+
+func SwcNodeUuids(swcData dbmodel.SwcDataV1) []string {
+	nodeUuids := make([]string, 0, len(swcData))
+	for _, node := range swcData {
+		nodeUuids = append(nodeUuids, node.Base.Uuid)
+	}
+	return nodeUuids
+}
+
+func NodeNParentUuids(nodeNParent []dbmodel.NodeNParentV1) []string {
+	nodeUuids := make([]string, 0, len(nodeNParent))
+	for _, node := range nodeNParent {
+		nodeUuids = append(nodeUuids, node.Uuid)
+	}
+	return nodeUuids
+}
@@ -0,0 +1,218 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// swcSimplificationCacheCapacity is the number of swcs whose simplification is kept in memory, the least recently used
+// one is dropped first.
+const swcSimplificationCacheCapacity = 4
+
+// swcSimplification ranks the nodes of one revision of a swc by how much of the shape they carry. Roots, tips, branch
+// points, soma nodes and nodes whose type differs from their parent rank first with an infinite importance, the other
+// nodes follow by their Douglas-Peucker distance. Keeping any prefix of Rank keeps the topology of the swc.
+type swcSimplification struct {
+	Revision   int64
+	SwcData    dbmodel.SwcDataV1
+	Importance []float64
+	Rank       []int
+	// Segments are the node index paths between two kept nodes, used to reconnect the kept nodes
+	Segments [][]int
+	LastUsed time.Time
+}
+
+var swcSimplificationMutex sync.Mutex
+var swcSimplificationCache = map[string]*swcSimplification{}
+
+func swcNodeDistanceToLine(node, start, end *dbmodel.SwcNodeInternalDataV1) float64 {
+	dx, dy, dz := float64(end.X-start.X), float64(end.Y-start.Y), float64(end.Z-start.Z)
+	px, py, pz := float64(node.X-start.X), float64(node.Y-start.Y), float64(node.Z-start.Z)
+	lengthSquare := dx*dx + dy*dy + dz*dz
+	t := 0.0
+	if lengthSquare > 0 {
+		t = min(max((px*dx+py*dy+pz*dz)/lengthSquare, 0), 1)
+	}
+	px, py, pz = px-t*dx, py-t*dy, pz-t*dz
+	return math.Sqrt(px*px + py*py + pz*pz)
+}
+
+// buildSwcSimplification computes the node ranking of swcData. A node split off a segment by Douglas-Peucker gets at
+// most the importance of the node that split the segment before it, so every kept node keeps the nodes its segment
+// was split by.
+func buildSwcSimplification(swcData dbmodel.SwcDataV1) *swcSimplification {
+	nodeIndex := make(map[int32]int, len(swcData))
+	for idx := range swcData {
+		nodeIndex[swcData[idx].SwcNodeInternalData.N] = idx
+	}
+	children := make([][]int, len(swcData))
+	isRoot := make([]bool, len(swcData))
+	for idx := range swcData {
+		parentIdx, ok := nodeIndex[swcData[idx].SwcNodeInternalData.Parent]
+		if swcData[idx].SwcNodeInternalData.Parent == -1 || !ok || parentIdx == idx {
+			isRoot[idx] = true
+			continue
+		}
+		children[parentIdx] = append(children[parentIdx], idx)
+	}
+
+	importance := make([]float64, len(swcData))
+	isKey := func(idx int) bool {
+		node := &swcData[idx].SwcNodeInternalData
+		return isRoot[idx] || len(children[idx]) != 1 || node.Type == 1 || node.Type != swcData[nodeIndex[node.Parent]].SwcNodeInternalData.Type
+	}
+	for idx := range swcData {
+		if isKey(idx) {
+			importance[idx] = math.Inf(1)
+		}
+	}
+
+	var segments [][]int
+	visited := make([]bool, len(swcData))
+	for idx := range swcData {
+		if !isKey(idx) {
+			continue
+		}
+		visited[idx] = true
+		for _, child := range children[idx] {
+			segment := []int{idx}
+			current := child
+			for !isKey(current) && !visited[current] {
+				visited[current] = true
+				segment = append(segment, current)
+				current = children[current][0]
+			}
+			segment = append(segment, current)
+			segments = append(segments, segment)
+
+			type segmentRange struct {
+				start, end int
+				bound      float64
+			}
+			stack := []segmentRange{{0, len(segment) - 1, math.MaxFloat64}}
+			for len(stack) != 0 {
+				current := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if current.end-current.start < 2 {
+					continue
+				}
+				start := &swcData[segment[current.start]].SwcNodeInternalData
+				end := &swcData[segment[current.end]].SwcNodeInternalData
+				farthest, farthestDistance := -1, -1.0
+				for position := current.start + 1; position < current.end; position++ {
+					if distance := swcNodeDistanceToLine(&swcData[segment[position]].SwcNodeInternalData, start, end); distance > farthestDistance {
+						farthest, farthestDistance = position, distance
+					}
+				}
+				bound := min(farthestDistance, current.bound)
+				importance[segment[farthest]] = bound
+				stack = append(stack, segmentRange{current.start, farthest, bound}, segmentRange{farthest, current.end, bound})
+			}
+		}
+	}
+	// nodes on a parent loop are not reached from any key node, keep them as they are
+	for idx := range swcData {
+		if !visited[idx] && importance[idx] == 0 {
+			importance[idx] = math.Inf(1)
+		}
+	}
+
+	rank := make([]int, len(swcData))
+	for idx := range rank {
+		rank[idx] = idx
+	}
+	sort.SliceStable(rank, func(i, j int) bool {
+		return importance[rank[i]] > importance[rank[j]]
+	})
+
+	return &swcSimplification{
+		SwcData:    swcData,
+		Importance: importance,
+		Rank:       rank,
+		Segments:   segments,
+	}
+}
+
+// Simplify returns the nodes kept for tolerance and nodeBudget in their original order, with the parent of every kept
+// node pointing to its nearest kept ancestor. Nodes closer than tolerance to the simplified segment are dropped, and
+// at most nodeBudget nodes are kept when it is above 0, but never fewer than needed to keep the topology.
+func (simplification *swcSimplification) Simplify(tolerance float64, nodeBudget int) dbmodel.SwcDataV1 {
+	keepNumber := sort.Search(len(simplification.Rank), func(i int) bool {
+		return simplification.Importance[simplification.Rank[i]] <= tolerance
+	})
+	if nodeBudget > 0 && nodeBudget < keepNumber {
+		topologyNumber := sort.Search(len(simplification.Rank), func(i int) bool {
+			return !math.IsInf(simplification.Importance[simplification.Rank[i]], 1)
+		})
+		keepNumber = max(nodeBudget, topologyNumber)
+	}
+
+	kept := make([]bool, len(simplification.SwcData))
+	for _, idx := range simplification.Rank[:keepNumber] {
+		kept[idx] = true
+	}
+	parents := make(map[int]int32, keepNumber)
+	for _, segment := range simplification.Segments {
+		lastKept := segment[0]
+		for _, idx := range segment[1:] {
+			if kept[idx] {
+				parents[idx] = simplification.SwcData[lastKept].SwcNodeInternalData.N
+				lastKept = idx
+			}
+		}
+	}
+
+	swcData := make(dbmodel.SwcDataV1, 0, keepNumber)
+	for idx := range simplification.SwcData {
+		if !kept[idx] {
+			continue
+		}
+		node := simplification.SwcData[idx]
+		if parent, ok := parents[idx]; ok {
+			node.SwcNodeInternalData.Parent = parent
+		}
+		swcData = append(swcData, node)
+	}
+	return swcData
+}
+
+// QuerySwcSimplifiedData returns the simplified nodes of the swc and the number of nodes it has. The ranking is
+// cached by swc revision, so it is computed again only after the next increment operation on the swc.
+func QuerySwcSimplifiedData(swcMetaInfo *dbmodel.SwcMetaInfoV1, tolerance float64, nodeBudget int) (dbmodel.SwcDataV1, int64, dal.ReturnWrapper) {
+	swcSimplificationMutex.Lock()
+	simplification, ok := swcSimplificationCache[swcMetaInfo.Base.Uuid]
+	if ok && simplification.Revision == swcMetaInfo.Revision {
+		simplification.LastUsed = time.Now()
+	}
+	swcSimplificationMutex.Unlock()
+
+	if !ok || simplification.Revision != swcMetaInfo.Revision {
+		var swcData dbmodel.SwcDataV1
+		if result := dal.QueryAllSwcData(swcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance()); !result.Status {
+			return nil, 0, result
+		}
+		simplification = buildSwcSimplification(swcData)
+		simplification.Revision = swcMetaInfo.Revision
+		simplification.LastUsed = time.Now()
+
+		swcSimplificationMutex.Lock()
+		swcSimplificationCache[swcMetaInfo.Base.Uuid] = simplification
+		for len(swcSimplificationCache) > swcSimplificationCacheCapacity {
+			var oldestSwcUuid string
+			var oldestTime time.Time
+			for swcUuid, cached := range swcSimplificationCache {
+				if oldestSwcUuid == "" || cached.LastUsed.Before(oldestTime) {
+					oldestSwcUuid, oldestTime = swcUuid, cached.LastUsed
+				}
+			}
+			delete(swcSimplificationCache, oldestSwcUuid)
+		}
+		swcSimplificationMutex.Unlock()
+	}
+
+	return simplification.Simplify(tolerance, nodeBudget), int64(len(simplification.SwcData)), dal.ReturnWrapper{Status: true, Message: "Query simplified swc data success!"}
+}
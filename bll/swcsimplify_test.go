@@ -0,0 +1,105 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSwcNodeDistanceToLine(t *testing.T) {
+	start := dbmodel.SwcNodeInternalDataV1{X: 0, Y: 0, Z: 0}
+	end := dbmodel.SwcNodeInternalDataV1{X: 10, Y: 0, Z: 0}
+	tests := []struct {
+		name string
+		node dbmodel.SwcNodeInternalDataV1
+		want float64
+	}{
+		{name: "on the line", node: dbmodel.SwcNodeInternalDataV1{X: 5}, want: 0},
+		{name: "beside the line", node: dbmodel.SwcNodeInternalDataV1{X: 5, Y: 3, Z: 4}, want: 5},
+		{name: "before the start", node: dbmodel.SwcNodeInternalDataV1{X: -3, Y: 4}, want: 5},
+		{name: "after the end", node: dbmodel.SwcNodeInternalDataV1{X: 13, Y: 4}, want: 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if distance := swcNodeDistanceToLine(&test.node, &start, &end); math.Abs(distance-test.want) > 1e-6 {
+				t.Errorf("swcNodeDistanceToLine = %v, want %v", distance, test.want)
+			}
+		})
+	}
+
+	if distance := swcNodeDistanceToLine(&end, &start, &start); math.Abs(distance-10) > 1e-6 {
+		t.Errorf("distance to a zero length line = %v, want 10", distance)
+	}
+}
+
+func TestSwcSimplification(t *testing.T) {
+	// a branch at node 3: one arm along x with a bump of 2 at node 5, the other arm straight along y
+	swcData := dbmodel.SwcDataV1{
+		newTestSwcNode(1, -1, 3, 0, 0, 0),
+		newTestSwcNode(2, 1, 3, 1, 0, 0),
+		newTestSwcNode(3, 2, 3, 2, 0, 0),
+		newTestSwcNode(4, 3, 3, 3, 0, 0),
+		newTestSwcNode(5, 4, 3, 4, 2, 0),
+		newTestSwcNode(6, 5, 3, 5, 0, 0),
+		newTestSwcNode(7, 6, 3, 6, 0, 0),
+		newTestSwcNode(8, 3, 3, 2, 1, 0),
+		newTestSwcNode(9, 8, 3, 2, 2, 0),
+	}
+	simplification := buildSwcSimplification(swcData)
+
+	type nodeNParent struct{ N, Parent int32 }
+	tests := []struct {
+		name       string
+		tolerance  float64
+		nodeBudget int
+		want       []nodeNParent
+	}{
+		{
+			name:      "zero tolerance keeps every node off the simplified lines",
+			tolerance: 0,
+			want:      []nodeNParent{{1, -1}, {3, 1}, {4, 3}, {5, 4}, {6, 5}, {7, 6}, {9, 3}},
+		},
+		{
+			name:      "tolerance below the bump",
+			tolerance: 1.5,
+			want:      []nodeNParent{{1, -1}, {3, 1}, {5, 3}, {7, 5}, {9, 3}},
+		},
+		{
+			name:      "tolerance above the bump keeps the topology",
+			tolerance: 10,
+			want:      []nodeNParent{{1, -1}, {3, 1}, {7, 3}, {9, 3}},
+		},
+		{
+			name:       "node budget",
+			tolerance:  0,
+			nodeBudget: 5,
+			want:       []nodeNParent{{1, -1}, {3, 1}, {5, 3}, {7, 5}, {9, 3}},
+		},
+		{
+			name:       "node budget below the topology",
+			tolerance:  0,
+			nodeBudget: 1,
+			want:       []nodeNParent{{1, -1}, {3, 1}, {7, 3}, {9, 3}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			simplifiedSwcData := simplification.Simplify(test.tolerance, test.nodeBudget)
+			var got []nodeNParent
+			for _, node := range simplifiedSwcData {
+				got = append(got, nodeNParent{node.SwcNodeInternalData.N, node.SwcNodeInternalData.Parent})
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Simplify = %v, want %v", got, test.want)
+			}
+			if issues := ValidateSwcTopology(simplifiedSwcData); len(issues) != 0 {
+				t.Errorf("simplified swc has topology issues %+v", issues)
+			}
+		})
+	}
+
+	if swcData[4].SwcNodeInternalData.Parent != 4 {
+		t.Errorf("Simplify modified the nodes of the simplification")
+	}
+}
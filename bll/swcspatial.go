@@ -0,0 +1,335 @@
+package bll
+
+import (
+	"DBMS/SwcDbmsCommon/Generated/go/proto/message"
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"math"
+	"sort"
+	"sync"
+)
+
+// SwcSpatialRegion is an axis aligned box, or a sphere when IsSphere is set. BoundingBox is the box around the sphere
+// then, so the node coordinate index can narrow down the nodes before the distance check.
+type SwcSpatialRegion struct {
+	BoundingBox dal.SwcNodeBoundingBox
+	IsSphere    bool
+	CenterX     float64
+	CenterY     float64
+	CenterZ     float64
+	Radius      float64
+}
+
+func NewSwcBoxRegion(boundingBox dal.SwcNodeBoundingBox) SwcSpatialRegion {
+	return SwcSpatialRegion{
+		BoundingBox: boundingBox,
+		CenterX:     (float64(boundingBox.MinX) + float64(boundingBox.MaxX)) / 2,
+		CenterY:     (float64(boundingBox.MinY) + float64(boundingBox.MaxY)) / 2,
+		CenterZ:     (float64(boundingBox.MinZ) + float64(boundingBox.MaxZ)) / 2,
+	}
+}
+
+func NewSwcSphereRegion(centerX float64, centerY float64, centerZ float64, radius float64) SwcSpatialRegion {
+	return SwcSpatialRegion{
+		BoundingBox: dal.SwcNodeBoundingBox{
+			MinX: float32(centerX - radius),
+			MinY: float32(centerY - radius),
+			MinZ: float32(centerZ - radius),
+			MaxX: float32(centerX + radius),
+			MaxY: float32(centerY + radius),
+			MaxZ: float32(centerZ + radius),
+		},
+		IsSphere: true,
+		CenterX:  centerX,
+		CenterY:  centerY,
+		CenterZ:  centerZ,
+		Radius:   radius,
+	}
+}
+
+func SwcNodeBoundingBoxProtobufToDal(protoMessage *message.BoundingBoxV1) dal.SwcNodeBoundingBox {
+	return dal.SwcNodeBoundingBox{
+		MinX: protoMessage.GetMinX(),
+		MinY: protoMessage.GetMinY(),
+		MinZ: protoMessage.GetMinZ(),
+		MaxX: protoMessage.GetMaxX(),
+		MaxY: protoMessage.GetMaxY(),
+		MaxZ: protoMessage.GetMaxZ(),
+	}
+}
+
+// distanceToSwcNodeBoundingBox is the distance from the point to the nearest point of the box, 0 inside of it.
+func distanceToSwcNodeBoundingBox(boundingBox *dal.SwcNodeBoundingBox, x float64, y float64, z float64) float64 {
+	axisDistance := func(value float64, minValue float32, maxValue float32) float64 {
+		return max(float64(minValue)-value, 0, value-float64(maxValue))
+	}
+	dx := axisDistance(x, boundingBox.MinX, boundingBox.MaxX)
+	dy := axisDistance(y, boundingBox.MinY, boundingBox.MaxY)
+	dz := axisDistance(z, boundingBox.MinZ, boundingBox.MaxZ)
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+func swcNodeBoundingBoxesOverlap(a *dal.SwcNodeBoundingBox, b *dal.SwcNodeBoundingBox) bool {
+	return a.MinX <= b.MaxX && b.MinX <= a.MaxX && a.MinY <= b.MaxY && b.MinY <= a.MaxY && a.MinZ <= b.MaxZ && b.MinZ <= a.MaxZ
+}
+
+func swcNodeDistanceToPoint(node *dbmodel.SwcNodeDataV1, x float64, y float64, z float64) float64 {
+	dx := float64(node.SwcNodeInternalData.X) - x
+	dy := float64(node.SwcNodeInternalData.Y) - y
+	dz := float64(node.SwcNodeInternalData.Z) - z
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// Intersects tells whether the region may contain nodes inside boundingBox.
+func (region *SwcSpatialRegion) Intersects(boundingBox *dal.SwcNodeBoundingBox) bool {
+	if region.IsSphere {
+		return distanceToSwcNodeBoundingBox(boundingBox, region.CenterX, region.CenterY, region.CenterZ) <= region.Radius
+	}
+	return swcNodeBoundingBoxesOverlap(&region.BoundingBox, boundingBox)
+}
+
+func (region *SwcSpatialRegion) Contains(node *dbmodel.SwcNodeDataV1) bool {
+	if region.IsSphere {
+		return swcNodeDistanceToPoint(node, region.CenterX, region.CenterY, region.CenterZ) <= region.Radius
+	}
+	// the box query already returned only nodes inside the box
+	return true
+}
+
+type swcBoundingBoxSummary struct {
+	Revision    int64
+	BoundingBox dal.SwcNodeBoundingBox
+	NodeNumber  int64
+}
+
+var swcBoundingBoxSummaryMutex sync.Mutex
+var swcBoundingBoxSummaryCache = map[string]swcBoundingBoxSummary{}
+
+// QuerySwcBoundingBoxSummary returns the box around the nodes of the swc and their number. Summaries are cached by
+// swc revision, every node write advances the revision and so replaces the summary on the next call.
+func QuerySwcBoundingBoxSummary(swcMetaInfo *dbmodel.SwcMetaInfoV1) (dal.SwcNodeBoundingBox, int64, dal.ReturnWrapper) {
+	swcBoundingBoxSummaryMutex.Lock()
+	summary, ok := swcBoundingBoxSummaryCache[swcMetaInfo.Base.Uuid]
+	swcBoundingBoxSummaryMutex.Unlock()
+	if ok && summary.Revision == swcMetaInfo.Revision {
+		return summary.BoundingBox, summary.NodeNumber, dal.ReturnWrapper{Status: true, Message: "Query swc bounding box success!"}
+	}
+
+	summary = swcBoundingBoxSummary{Revision: swcMetaInfo.Revision}
+	if result := dal.QuerySwcDataBoundingBox(swcMetaInfo.Base.Uuid, &summary.BoundingBox, &summary.NodeNumber, dal.GetDbInstance()); !result.Status {
+		return dal.SwcNodeBoundingBox{}, 0, result
+	}
+
+	swcBoundingBoxSummaryMutex.Lock()
+	swcBoundingBoxSummaryCache[swcMetaInfo.Base.Uuid] = summary
+	swcBoundingBoxSummaryMutex.Unlock()
+	return summary.BoundingBox, summary.NodeNumber, dal.ReturnWrapper{Status: true, Message: "Query swc bounding box success!"}
+}
+
+// SwcRegionHit is a swc passing through a region, NearestNode is its node nearest to the center of the region.
+type SwcRegionHit struct {
+	SwcUuid         string
+	SwcName         string
+	HitNumber       int64
+	NearestNode     dbmodel.SwcNodeDataV1
+	NearestDistance float64
+}
+
+// FindSwcsInRegion counts the nodes of every swc inside region, swcs whose bounding box summary misses the region are
+// not searched. Hits are ordered by hit number, highest first.
+func FindSwcsInRegion(swcMetaInfos []dbmodel.SwcMetaInfoV1, region SwcSpatialRegion) ([]SwcRegionHit, dal.ReturnWrapper) {
+	hits := []SwcRegionHit{}
+	for idx := range swcMetaInfos {
+		swcMetaInfo := &swcMetaInfos[idx]
+		boundingBox, nodeNumber, result := QuerySwcBoundingBoxSummary(swcMetaInfo)
+		if !result.Status {
+			return nil, result
+		}
+		if nodeNumber == 0 || !region.Intersects(&boundingBox) {
+			continue
+		}
+
+		var swcData dbmodel.SwcDataV1
+		var truncated bool
+		if result := dal.QuerySwcDataInBoundingBox(swcMetaInfo.Base.Uuid, dal.SwcNodeBoundingBoxQuery{SwcNodeBoundingBox: region.BoundingBox}, &swcData, &truncated, dal.GetDbInstance()); !result.Status {
+			return nil, result
+		}
+
+		hit := SwcRegionHit{SwcUuid: swcMetaInfo.Base.Uuid, SwcName: swcMetaInfo.Name, NearestDistance: math.Inf(1)}
+		for nodeIdx := range swcData {
+			node := &swcData[nodeIdx]
+			if !region.Contains(node) {
+				continue
+			}
+			hit.HitNumber++
+			if distance := swcNodeDistanceToPoint(node, region.CenterX, region.CenterY, region.CenterZ); distance < hit.NearestDistance {
+				hit.NearestDistance = distance
+				hit.NearestNode = *node
+			}
+		}
+		if hit.HitNumber != 0 {
+			hits = append(hits, hit)
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].HitNumber > hits[j].HitNumber
+	})
+	return hits, dal.ReturnWrapper{Status: true, Message: "Find swcs in region successfully!"}
+}
+
+// SwcNearestNodeMaxNumber caps the number of nearest nodes one request may ask for.
+const SwcNearestNodeMaxNumber = 10000
+
+// SwcNearestNodeQuery asks for the Number nodes nearest to the point. MaxDistance limits the search, it is
+// unlimited when math.Inf(1). TipsOnly leaves out nodes which are the parent of another node.
+type SwcNearestNodeQuery struct {
+	X, Y, Z     float64
+	Number      int
+	MaxDistance float64
+	TipsOnly    bool
+}
+
+type SwcNearestNode struct {
+	SwcUuid  string
+	Node     dbmodel.SwcNodeDataV1
+	Distance float64
+}
+
+func sortSwcNearestNodes(nodes []SwcNearestNode, number int) []SwcNearestNode {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Distance < nodes[j].Distance
+	})
+	if len(nodes) > number {
+		nodes = nodes[:number]
+	}
+	return nodes
+}
+
+// findNearestNodesInSwc searches growing cubes around the point through the node coordinate index until the cube
+// holds enough nodes within its half size, reaches maxDistance or covers the whole swc.
+func findNearestNodesInSwc(swcMetaInfo *dbmodel.SwcMetaInfoV1, boundingBox *dal.SwcNodeBoundingBox, nodeNumber int64, query *SwcNearestNodeQuery, maxDistance float64) ([]SwcNearestNode, dal.ReturnWrapper) {
+	queryNodesInCube := func(cube dal.SwcNodeBoundingBoxQuery, swcData *dbmodel.SwcDataV1) dal.ReturnWrapper {
+		var truncated bool
+		return dal.QuerySwcDataInBoundingBox(swcMetaInfo.Base.Uuid, cube, swcData, &truncated, dal.GetDbInstance())
+	}
+	queryParentsIn := func(ns []int32, parents *[]int32) dal.ReturnWrapper {
+		return dal.QuerySwcNodeParentsIn(swcMetaInfo.Base.Uuid, ns, parents, dal.GetDbInstance())
+	}
+	return searchNearestNodesInSwc(swcMetaInfo.Base.Uuid, boundingBox, nodeNumber, query, maxDistance, queryNodesInCube, queryParentsIn)
+}
+
+// searchNearestNodesInSwc is the search of findNearestNodesInSwc, queryNodesInCube returns the nodes inside a cube
+// and queryParentsIn the parents among the given n.
+func searchNearestNodesInSwc(swcUuid string, boundingBox *dal.SwcNodeBoundingBox, nodeNumber int64, query *SwcNearestNodeQuery, maxDistance float64, queryNodesInCube func(cube dal.SwcNodeBoundingBoxQuery, swcData *dbmodel.SwcDataV1) dal.ReturnWrapper, queryParentsIn func(ns []int32, parents *[]int32) dal.ReturnWrapper) ([]SwcNearestNode, dal.ReturnWrapper) {
+	// half size of the cube around the point which contains the whole swc
+	coverHalfSize := max(
+		math.Abs(query.X-float64(boundingBox.MinX)), math.Abs(query.X-float64(boundingBox.MaxX)),
+		math.Abs(query.Y-float64(boundingBox.MinY)), math.Abs(query.Y-float64(boundingBox.MaxY)),
+		math.Abs(query.Z-float64(boundingBox.MinZ)), math.Abs(query.Z-float64(boundingBox.MaxZ)),
+	)
+	// start with a cube expected to hold about the asked number of nodes if they were spread evenly
+	volume := max(float64(boundingBox.MaxX-boundingBox.MinX), 1) * max(float64(boundingBox.MaxY-boundingBox.MinY), 1) * max(float64(boundingBox.MaxZ-boundingBox.MinZ), 1)
+	halfSize := max(distanceToSwcNodeBoundingBox(boundingBox, query.X, query.Y, query.Z), math.Cbrt(volume*float64(query.Number)/float64(nodeNumber))/2, 1)
+
+	for {
+		halfSize = min(halfSize, maxDistance, coverHalfSize)
+		cube := dal.SwcNodeBoundingBoxQuery{SwcNodeBoundingBox: dal.SwcNodeBoundingBox{
+			MinX: float32(query.X - halfSize),
+			MinY: float32(query.Y - halfSize),
+			MinZ: float32(query.Z - halfSize),
+			MaxX: float32(query.X + halfSize),
+			MaxY: float32(query.Y + halfSize),
+			MaxZ: float32(query.Z + halfSize),
+		}}
+		var swcData dbmodel.SwcDataV1
+		if result := queryNodesInCube(cube, &swcData); !result.Status {
+			return nil, result
+		}
+
+		var candidates []SwcNearestNode
+		for idx := range swcData {
+			// nodes in the corners of the cube may be farther than nodes just outside of it
+			if distance := swcNodeDistanceToPoint(&swcData[idx], query.X, query.Y, query.Z); distance <= halfSize {
+				candidates = append(candidates, SwcNearestNode{SwcUuid: swcUuid, Node: swcData[idx], Distance: distance})
+			}
+		}
+
+		if query.TipsOnly && len(candidates) != 0 {
+			ns := make([]int32, 0, len(candidates))
+			for _, candidate := range candidates {
+				ns = append(ns, candidate.Node.SwcNodeInternalData.N)
+			}
+			var parents []int32
+			if result := queryParentsIn(ns, &parents); !result.Status {
+				return nil, result
+			}
+			isParent := make(map[int32]bool, len(parents))
+			for _, parent := range parents {
+				isParent[parent] = true
+			}
+			tips := candidates[:0]
+			for _, candidate := range candidates {
+				if !isParent[candidate.Node.SwcNodeInternalData.N] {
+					tips = append(tips, candidate)
+				}
+			}
+			candidates = tips
+		}
+
+		if len(candidates) >= query.Number || halfSize >= maxDistance || halfSize >= coverHalfSize {
+			return sortSwcNearestNodes(candidates, query.Number), dal.ReturnWrapper{Status: true, Message: "Find nearest nodes successfully!"}
+		}
+		halfSize *= 2
+	}
+}
+
+// FindNearestSwcNodes finds the nodes of the swcs nearest to the point of query, nearest first. Swcs are searched
+// from the one whose bounding box summary is nearest, and the search stops at the first swc whose box is farther
+// away than the farthest node found so far.
+func FindNearestSwcNodes(swcMetaInfos []dbmodel.SwcMetaInfoV1, query SwcNearestNodeQuery) ([]SwcNearestNode, dal.ReturnWrapper) {
+	type swcCandidate struct {
+		swcMetaInfo  *dbmodel.SwcMetaInfoV1
+		boundingBox  dal.SwcNodeBoundingBox
+		nodeNumber   int64
+		nearestBound float64
+	}
+	var swcCandidates []swcCandidate
+	for idx := range swcMetaInfos {
+		boundingBox, nodeNumber, result := QuerySwcBoundingBoxSummary(&swcMetaInfos[idx])
+		if !result.Status {
+			return nil, result
+		}
+		if nodeNumber == 0 {
+			continue
+		}
+		swcCandidates = append(swcCandidates, swcCandidate{
+			swcMetaInfo:  &swcMetaInfos[idx],
+			boundingBox:  boundingBox,
+			nodeNumber:   nodeNumber,
+			nearestBound: distanceToSwcNodeBoundingBox(&boundingBox, query.X, query.Y, query.Z),
+		})
+	}
+	sort.SliceStable(swcCandidates, func(i, j int) bool {
+		return swcCandidates[i].nearestBound < swcCandidates[j].nearestBound
+	})
+
+	nearestNodes := []SwcNearestNode{}
+	for _, candidate := range swcCandidates {
+		maxDistance := query.MaxDistance
+		if len(nearestNodes) == query.Number {
+			maxDistance = min(maxDistance, nearestNodes[len(nearestNodes)-1].Distance)
+		}
+		if candidate.nearestBound > maxDistance {
+			break
+		}
+
+		swcNearestNodes, result := findNearestNodesInSwc(candidate.swcMetaInfo, &candidate.boundingBox, candidate.nodeNumber, &query, maxDistance)
+		if !result.Status {
+			return nil, result
+		}
+		nearestNodes = sortSwcNearestNodes(append(nearestNodes, swcNearestNodes...), query.Number)
+	}
+
+	return nearestNodes, dal.ReturnWrapper{Status: true, Message: "Find nearest nodes successfully!"}
+}
@@ -0,0 +1,148 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"math"
+	"sort"
+	"testing"
+)
+
+// newTestSwcNearestNodeSource serves the cube and parent queries of searchNearestNodesInSwc from swcData.
+func newTestSwcNearestNodeSource(swcData dbmodel.SwcDataV1) (func(cube dal.SwcNodeBoundingBoxQuery, swcData *dbmodel.SwcDataV1) dal.ReturnWrapper, func(ns []int32, parents *[]int32) dal.ReturnWrapper) {
+	queryNodesInCube := func(cube dal.SwcNodeBoundingBoxQuery, result *dbmodel.SwcDataV1) dal.ReturnWrapper {
+		for _, node := range swcData {
+			nodeData := node.SwcNodeInternalData
+			if nodeData.X >= cube.MinX && nodeData.X <= cube.MaxX && nodeData.Y >= cube.MinY && nodeData.Y <= cube.MaxY && nodeData.Z >= cube.MinZ && nodeData.Z <= cube.MaxZ {
+				*result = append(*result, node)
+			}
+		}
+		return dal.ReturnWrapper{Status: true}
+	}
+	queryParentsIn := func(ns []int32, parents *[]int32) dal.ReturnWrapper {
+		isQueried := make(map[int32]bool, len(ns))
+		for _, n := range ns {
+			isQueried[n] = true
+		}
+		for _, node := range swcData {
+			if isQueried[node.SwcNodeInternalData.Parent] {
+				*parents = append(*parents, node.SwcNodeInternalData.Parent)
+			}
+		}
+		return dal.ReturnWrapper{Status: true}
+	}
+	return queryNodesInCube, queryParentsIn
+}
+
+func TestSearchNearestNodesInSwc(t *testing.T) {
+	// a chain along x with a side branch, every node one apart from its parent
+	var swcData dbmodel.SwcDataV1
+	swcData = append(swcData, newTestSwcNode(1, -1, 1, 0, 0, 0))
+	for n := int32(2); n <= 40; n++ {
+		swcData = append(swcData, newTestSwcNode(n, n-1, 3, float32(n-1), 0, 0))
+	}
+	for n := int32(41); n <= 50; n++ {
+		parent := n - 1
+		if n == 41 {
+			parent = 20
+		}
+		swcData = append(swcData, newTestSwcNode(n, parent, 3, 19, float32(n-40), 0))
+	}
+	boundingBox := dal.SwcNodeBoundingBox{MinX: 0, MaxX: 39, MinY: 0, MaxY: 10, MinZ: 0, MaxZ: 0}
+	queryNodesInCube, queryParentsIn := newTestSwcNearestNodeSource(swcData)
+
+	bruteForce := func(query SwcNearestNodeQuery) []int32 {
+		var nodes []SwcNearestNode
+		for _, node := range swcData {
+			if query.TipsOnly && node.SwcNodeInternalData.N != 40 && node.SwcNodeInternalData.N != 50 {
+				continue
+			}
+			if distance := swcNodeDistanceToPoint(&node, query.X, query.Y, query.Z); distance <= query.MaxDistance {
+				nodes = append(nodes, SwcNearestNode{Node: node, Distance: distance})
+			}
+		}
+		var ns []int32
+		for _, node := range sortSwcNearestNodes(nodes, query.Number) {
+			ns = append(ns, node.Node.SwcNodeInternalData.N)
+		}
+		return ns
+	}
+
+	tests := []struct {
+		name  string
+		query SwcNearestNodeQuery
+	}{
+		{name: "point on the chain", query: SwcNearestNodeQuery{X: 10.2, Y: 0, Z: 0, Number: 5, MaxDistance: math.Inf(1)}},
+		{name: "point near the branch", query: SwcNearestNodeQuery{X: 18, Y: 6, Z: 1, Number: 3, MaxDistance: math.Inf(1)}},
+		{name: "point outside the swc", query: SwcNearestNodeQuery{X: -100, Y: 50, Z: 20, Number: 4, MaxDistance: math.Inf(1)}},
+		{name: "more nodes than the swc has", query: SwcNearestNodeQuery{X: 5, Y: 0, Z: 0, Number: 100, MaxDistance: math.Inf(1)}},
+		{name: "max distance", query: SwcNearestNodeQuery{X: 5, Y: 0, Z: 0, Number: 10, MaxDistance: 2.5}},
+		{name: "max distance excludes every node", query: SwcNearestNodeQuery{X: 5, Y: 5, Z: 0, Number: 10, MaxDistance: 1}},
+		{name: "tips only", query: SwcNearestNodeQuery{X: 0, Y: 0, Z: 0, Number: 1, MaxDistance: math.Inf(1), TipsOnly: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, result := searchNearestNodesInSwc("swc", &boundingBox, int64(len(swcData)), &test.query, test.query.MaxDistance, queryNodesInCube, queryParentsIn)
+			if !result.Status {
+				t.Fatalf("searchNearestNodesInSwc failed: %s", result.Message)
+			}
+			var ns []int32
+			for _, node := range nodes {
+				ns = append(ns, node.Node.SwcNodeInternalData.N)
+				if node.SwcUuid != "swc" {
+					t.Errorf("node %d has swc uuid %q, want swc", node.Node.SwcNodeInternalData.N, node.SwcUuid)
+				}
+			}
+			want := bruteForce(test.query)
+			if len(ns) != len(want) {
+				t.Fatalf("nearest nodes = %v, want %v", ns, want)
+			}
+			for idx := range want {
+				if ns[idx] != want[idx] {
+					t.Fatalf("nearest nodes = %v, want %v", ns, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSortSwcNearestNodes(t *testing.T) {
+	nodes := []SwcNearestNode{
+		{Node: newTestSwcNode(1, -1, 1, 0, 0, 0), Distance: 3},
+		{Node: newTestSwcNode(2, 1, 3, 0, 0, 0), Distance: 1},
+		{Node: newTestSwcNode(3, 1, 3, 0, 0, 0), Distance: 2},
+		{Node: newTestSwcNode(4, 1, 3, 0, 0, 0), Distance: 1},
+	}
+	sortedNodes := sortSwcNearestNodes(nodes, 3)
+	if len(sortedNodes) != 3 {
+		t.Fatalf("sortSwcNearestNodes kept %d nodes, want 3", len(sortedNodes))
+	}
+	if !sort.SliceIsSorted(sortedNodes, func(i, j int) bool { return sortedNodes[i].Distance < sortedNodes[j].Distance }) {
+		t.Errorf("sortSwcNearestNodes result is not sorted by distance")
+	}
+	// equal distances keep their order
+	if sortedNodes[0].Node.SwcNodeInternalData.N != 2 || sortedNodes[1].Node.SwcNodeInternalData.N != 4 {
+		t.Errorf("sortSwcNearestNodes is not stable")
+	}
+}
+
+func TestDistanceToSwcNodeBoundingBox(t *testing.T) {
+	boundingBox := dal.SwcNodeBoundingBox{MinX: 0, MinY: 0, MinZ: 0, MaxX: 10, MaxY: 10, MaxZ: 10}
+	tests := []struct {
+		name    string
+		x, y, z float64
+		want    float64
+	}{
+		{name: "inside", x: 5, y: 5, z: 5, want: 0},
+		{name: "on a face", x: 10, y: 5, z: 5, want: 0},
+		{name: "beside a face", x: 13, y: 5, z: 5, want: 3},
+		{name: "beside a corner", x: -3, y: -4, z: 5, want: 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if distance := distanceToSwcNodeBoundingBox(&boundingBox, test.x, test.y, test.z); math.Abs(distance-test.want) > 1e-9 {
+				t.Errorf("distanceToSwcNodeBoundingBox = %v, want %v", distance, test.want)
+			}
+		})
+	}
+}
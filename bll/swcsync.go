@@ -0,0 +1,70 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"sort"
+	"time"
+)
+
+// CollectSwcChangesSinceRevision collects the increment operations which took the swc from sinceRevision to
+// currentRevision, in revision order. The second result is false when the increment operation lists no longer hold
+// every one of those revisions, because compaction removed them, a revert dropped them or the nodes changed without
+// an increment operation, and the client has to reload the whole swc instead.
+func CollectSwcChangesSinceRevision(swcMetaInfo *dbmodel.SwcMetaInfoV1, sinceRevision int64, currentRevision int64) (dbmodel.SwcIncrementOperationListV1, bool, dal.ReturnWrapper) {
+	if sinceRevision > currentRevision {
+		return nil, false, dal.ReturnWrapper{Status: true, Message: "Revision is newer than the swc, reload required!"}
+	}
+
+	var operations dbmodel.SwcIncrementOperationListV1
+	for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
+		var listOperations dbmodel.SwcIncrementOperationListV1
+		if result := dal.QuerySwcIncrementOperationAfterRevision(incrementOperation.IncrementOperationCollectionName, sinceRevision, &listOperations, dal.GetDbInstance()); !result.Status {
+			return nil, false, result
+		}
+		for _, operation := range listOperations {
+			// written after the current revision was read, the next sync picks it up
+			if operation.Revision <= currentRevision {
+				operations = append(operations, operation)
+			}
+		}
+	}
+	sort.SliceStable(operations, func(i, j int) bool {
+		return operations[i].Revision < operations[j].Revision
+	})
+
+	coveredRevisions := make(map[int64]bool, len(operations))
+	for _, operation := range operations {
+		coveredRevisions[operation.Revision] = true
+	}
+	if int64(len(coveredRevisions)) != currentRevision-sinceRevision {
+		return nil, false, dal.ReturnWrapper{Status: true, Message: "Changes since the revision are no longer available, reload required!"}
+	}
+
+	return operations, true, dal.ReturnWrapper{Status: true, Message: "Collect swc changes successfully!"}
+}
+
+// FindSwcRevisionAtTime finds the revision the swc had at sinceTime from its increment operation lists. The second
+// result is false when the lists do not reach back to sinceTime.
+func FindSwcRevisionAtTime(swcMetaInfo *dbmodel.SwcMetaInfoV1, sinceTime time.Time) (int64, bool, dal.ReturnWrapper) {
+	incrementOperationList := make([]dbmodel.SwcIncrementOperationMetaInfoV1, len(swcMetaInfo.SwcIncrementOperationList))
+	copy(incrementOperationList, swcMetaInfo.SwcIncrementOperationList)
+	sort.SliceStable(incrementOperationList, func(i, j int) bool {
+		return incrementOperationList[i].CreateTime.After(incrementOperationList[j].CreateTime)
+	})
+
+	for _, incrementOperation := range incrementOperationList {
+		var operations dbmodel.SwcIncrementOperationListV1
+		if result := dal.QueryLastSwcIncrementOperationAtTime(incrementOperation.IncrementOperationCollectionName, sinceTime, &operations, dal.GetDbInstance()); !result.Status {
+			return 0, false, result
+		}
+		if len(operations) != 0 {
+			return operations[0].Revision, true, dal.ReturnWrapper{Status: true, Message: "Find swc revision successfully!"}
+		}
+		if !incrementOperation.CreateTime.After(sinceTime) {
+			return incrementOperation.StartRevision, true, dal.ReturnWrapper{Status: true, Message: "Find swc revision successfully!"}
+		}
+	}
+
+	return 0, false, dal.ReturnWrapper{Status: true, Message: "Changes since the time are no longer available, reload required!"}
+}
@@ -0,0 +1,388 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SwcTopologyIssue_DuplicateN    = "DuplicateN"
+	SwcTopologyIssue_MissingParent = "MissingParent"
+	SwcTopologyIssue_MultipleRoots = "MultipleRoots"
+	SwcTopologyIssue_Cycle         = "Cycle"
+)
+
+type SwcTopologyIssue struct {
+	IssueType   string
+	NodeUuid    string
+	N           int32
+	Parent      int32
+	Description string
+}
+
+func (issue SwcTopologyIssue) key() string {
+	return issue.IssueType + "/" + issue.NodeUuid
+}
+
+func IsSwcRootNode(nodeData *dbmodel.SwcNodeInternalDataV1) bool {
+	return nodeData.Parent < 0
+}
+
+// ValidateSwcTopology reports duplicate n, parents pointing at a missing n, more than one root and cycles.
+// Every issue is attached to a single node so that two results can be compared node by node.
+func ValidateSwcTopology(swcData dbmodel.SwcDataV1) []SwcTopologyIssue {
+	var issues []SwcTopologyIssue
+
+	nodeIndexByN := make(map[int32]int, len(swcData))
+	for idx, swcNodeData := range swcData {
+		n := swcNodeData.SwcNodeInternalData.N
+		if firstIdx, ok := nodeIndexByN[n]; ok {
+			issues = append(issues, SwcTopologyIssue{
+				IssueType:   SwcTopologyIssue_DuplicateN,
+				NodeUuid:    swcNodeData.Base.Uuid,
+				N:           n,
+				Parent:      swcNodeData.SwcNodeInternalData.Parent,
+				Description: "n " + strconv.Itoa(int(n)) + " is already used by node " + swcData[firstIdx].Base.Uuid,
+			})
+			continue
+		}
+		nodeIndexByN[n] = idx
+	}
+
+	var rootIndexes []int
+	for idx, swcNodeData := range swcData {
+		nodeData := swcNodeData.SwcNodeInternalData
+		if IsSwcRootNode(&nodeData) {
+			rootIndexes = append(rootIndexes, idx)
+			continue
+		}
+		if _, ok := nodeIndexByN[nodeData.Parent]; !ok {
+			issues = append(issues, SwcTopologyIssue{
+				IssueType:   SwcTopologyIssue_MissingParent,
+				NodeUuid:    swcNodeData.Base.Uuid,
+				N:           nodeData.N,
+				Parent:      nodeData.Parent,
+				Description: "parent " + strconv.Itoa(int(nodeData.Parent)) + " does not exist",
+			})
+		}
+	}
+
+	if len(rootIndexes) > 1 {
+		for _, idx := range rootIndexes {
+			issues = append(issues, SwcTopologyIssue{
+				IssueType:   SwcTopologyIssue_MultipleRoots,
+				NodeUuid:    swcData[idx].Base.Uuid,
+				N:           swcData[idx].SwcNodeInternalData.N,
+				Parent:      swcData[idx].SwcNodeInternalData.Parent,
+				Description: "swc has " + strconv.Itoa(len(rootIndexes)) + " roots",
+			})
+		}
+	}
+
+	// walk up the parent chain of every node, 1 = on the current path, 2 = already known to end at a root or a missing parent
+	state := make([]int8, len(swcData))
+	for startIdx := range swcData {
+		if state[startIdx] != 0 {
+			continue
+		}
+
+		var path []int
+		idx := startIdx
+		for {
+			if state[idx] == 2 {
+				break
+			}
+			if state[idx] == 1 {
+				cycleStart := 0
+				for pathIdx, nodeIdx := range path {
+					if nodeIdx == idx {
+						cycleStart = pathIdx
+						break
+					}
+				}
+				for _, nodeIdx := range path[cycleStart:] {
+					issues = append(issues, SwcTopologyIssue{
+						IssueType:   SwcTopologyIssue_Cycle,
+						NodeUuid:    swcData[nodeIdx].Base.Uuid,
+						N:           swcData[nodeIdx].SwcNodeInternalData.N,
+						Parent:      swcData[nodeIdx].SwcNodeInternalData.Parent,
+						Description: "node is part of a cycle of " + strconv.Itoa(len(path)-cycleStart) + " nodes",
+					})
+				}
+				break
+			}
+
+			state[idx] = 1
+			path = append(path, idx)
+
+			nodeData := swcData[idx].SwcNodeInternalData
+			if IsSwcRootNode(&nodeData) {
+				break
+			}
+			parentIdx, ok := nodeIndexByN[nodeData.Parent]
+			if !ok {
+				break
+			}
+			idx = parentIdx
+		}
+
+		for _, nodeIdx := range path {
+			state[nodeIdx] = 2
+		}
+	}
+
+	return issues
+}
+
+// FindNewSwcTopologyIssues returns the issues of after which are not present in before.
+func FindNewSwcTopologyIssues(before dbmodel.SwcDataV1, after dbmodel.SwcDataV1) []SwcTopologyIssue {
+	existingIssues := make(map[string]bool)
+	for _, issue := range ValidateSwcTopology(before) {
+		existingIssues[issue.key()] = true
+	}
+
+	var newIssues []SwcTopologyIssue
+	for _, issue := range ValidateSwcTopology(after) {
+		if !existingIssues[issue.key()] {
+			newIssues = append(newIssues, issue)
+		}
+	}
+	return newIssues
+}
+
+func SwcTopologyIssuesToMessage(issues []SwcTopologyIssue) string {
+	const maxListedIssueNumber = 10
+
+	var descriptions []string
+	for idx, issue := range issues {
+		if idx == maxListedIssueNumber {
+			descriptions = append(descriptions, "...")
+			break
+		}
+		descriptions = append(descriptions, issue.IssueType+"(n "+strconv.Itoa(int(issue.N))+", uuid "+issue.NodeUuid+"): "+issue.Description)
+	}
+	return strconv.Itoa(len(issues)) + " topology issues found! " + strings.Join(descriptions, "; ")
+}
+
+// ApplySwcNodeCreate, ApplySwcNodeUpdate, ApplySwcNodeDelete and ApplySwcNParentUpdate apply a write in memory the same way the
+// corresponding dal function applies it to the database. The input slice is never modified.
+func ApplySwcNodeCreate(swcData dbmodel.SwcDataV1, createdSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+	result := make(dbmodel.SwcDataV1, 0, len(swcData)+len(createdSwcData))
+	result = append(result, swcData...)
+	return append(result, createdSwcData...)
+}
+
+func ApplySwcNodeUpdate(swcData dbmodel.SwcDataV1, updatedSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+	result := make(dbmodel.SwcDataV1, len(swcData))
+	copy(result, swcData)
+
+	nodeIndexByUuid := make(map[string]int, len(result))
+	for idx := range result {
+		nodeIndexByUuid[result[idx].Base.Uuid] = idx
+	}
+
+	for _, updatedNode := range updatedSwcData {
+		idx, ok := nodeIndexByUuid[updatedNode.Base.Uuid]
+		if !ok {
+			continue
+		}
+		node := &result[idx]
+		n := node.SwcNodeInternalData.N
+		parent := node.SwcNodeInternalData.Parent
+		node.SwcNodeInternalData = updatedNode.SwcNodeInternalData
+		if updatedNode.SwcNodeInternalData.N == 0 {
+			node.SwcNodeInternalData.N = n
+		}
+		if updatedNode.SwcNodeInternalData.Parent == 0 {
+			node.SwcNodeInternalData.Parent = parent
+		}
+		node.Creator = updatedNode.Creator
+		node.LastModifiedTime = updatedNode.LastModifiedTime
+		node.CheckerUserUuid = updatedNode.CheckerUserUuid
+		node.DeviceType = updatedNode.DeviceType
+	}
+
+	return result
+}
+
+func ApplySwcNodeDelete(swcData dbmodel.SwcDataV1, deletedSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+	deletedUuids := make(map[string]bool, len(deletedSwcData))
+	for _, deletedNode := range deletedSwcData {
+		deletedUuids[deletedNode.Base.Uuid] = true
+	}
+
+	result := make(dbmodel.SwcDataV1, 0, len(swcData))
+	for _, swcNodeData := range swcData {
+		if !deletedUuids[swcNodeData.Base.Uuid] {
+			result = append(result, swcNodeData)
+		}
+	}
+	return result
+}
+
+func ApplySwcNParentUpdate(swcData dbmodel.SwcDataV1, nodeNParent []dbmodel.NodeNParentV1) dbmodel.SwcDataV1 {
+	result := make(dbmodel.SwcDataV1, len(swcData))
+	copy(result, swcData)
+
+	nodeIndexByUuid := make(map[string]int, len(result))
+	for idx := range result {
+		nodeIndexByUuid[result[idx].Base.Uuid] = idx
+	}
+
+	for _, nodeNP := range nodeNParent {
+		if idx, ok := nodeIndexByUuid[nodeNP.Uuid]; ok {
+			result[idx].SwcNodeInternalData.N = nodeNP.N
+			result[idx].SwcNodeInternalData.Parent = nodeNP.Parent
+		}
+	}
+
+	return result
+}
+
+// TopologicalRenumberSwcNodes assigns n = 1..len(swcData) so that every parent comes before its children, walking
+// each tree depth first from its root. Nodes whose parent doesn't exist become roots. It fails on duplicate n and on
+// cycles because there is no well defined parent order for them. The second return value is the number of nodes
+// that were detached from a missing parent.
+func TopologicalRenumberSwcNodes(swcData dbmodel.SwcDataV1) ([]dbmodel.NodeNParentV1, int, error) {
+	for _, issue := range ValidateSwcTopology(swcData) {
+		if issue.IssueType == SwcTopologyIssue_DuplicateN || issue.IssueType == SwcTopologyIssue_Cycle {
+			return nil, 0, errors.New("Cannot renumber swc, " + issue.IssueType + "(n " + strconv.Itoa(int(issue.N)) + ", uuid " + issue.NodeUuid + "): " + issue.Description)
+		}
+	}
+
+	sortedIndexes := make([]int, len(swcData))
+	for idx := range sortedIndexes {
+		sortedIndexes[idx] = idx
+	}
+	sort.SliceStable(sortedIndexes, func(i, j int) bool {
+		return swcData[sortedIndexes[i]].SwcNodeInternalData.N < swcData[sortedIndexes[j]].SwcNodeInternalData.N
+	})
+
+	nodeIndexByN := make(map[int32]int, len(swcData))
+	for idx, swcNodeData := range swcData {
+		nodeIndexByN[swcNodeData.SwcNodeInternalData.N] = idx
+	}
+
+	childrenIndexes := make([][]int, len(swcData))
+	var rootIndexes []int
+	detachedNodeNumber := 0
+	for _, idx := range sortedIndexes {
+		nodeData := swcData[idx].SwcNodeInternalData
+		if IsSwcRootNode(&nodeData) {
+			rootIndexes = append(rootIndexes, idx)
+			continue
+		}
+		parentIdx, ok := nodeIndexByN[nodeData.Parent]
+		if !ok {
+			rootIndexes = append(rootIndexes, idx)
+			detachedNodeNumber++
+			continue
+		}
+		childrenIndexes[parentIdx] = append(childrenIndexes[parentIdx], idx)
+	}
+
+	newN := make([]int32, len(swcData))
+	nodeNParent := make([]dbmodel.NodeNParentV1, 0, len(swcData))
+	counter := int32(0)
+
+	stack := make([]int, 0, len(swcData))
+	for rootIdx := len(rootIndexes) - 1; rootIdx >= 0; rootIdx-- {
+		stack = append(stack, rootIndexes[rootIdx])
+	}
+	parentOf := make([]int, len(swcData))
+	for _, idx := range rootIndexes {
+		parentOf[idx] = -1
+	}
+
+	for len(stack) > 0 {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		counter++
+		newN[idx] = counter
+		parent := int32(-1)
+		if parentOf[idx] != -1 {
+			parent = newN[parentOf[idx]]
+		}
+		nodeNParent = append(nodeNParent, dbmodel.NodeNParentV1{
+			Uuid:   swcData[idx].Base.Uuid,
+			N:      counter,
+			Parent: parent,
+		})
+
+		children := childrenIndexes[idx]
+		for childIdx := len(children) - 1; childIdx >= 0; childIdx-- {
+			parentOf[children[childIdx]] = idx
+			stack = append(stack, children[childIdx])
+		}
+	}
+
+	if len(nodeNParent) != len(swcData) {
+		return nil, 0, errors.New("Cannot renumber swc, " + strconv.Itoa(len(swcData)-len(nodeNParent)) + " nodes are not reachable from any root")
+	}
+
+	return nodeNParent, detachedNodeNumber, nil
+}
+
+// OffsetSwcNodeN returns swcData with offset added to every n and to every parent that is not a root marker, so the
+// nodes can be appended after a swc whose largest n is offset.
+func OffsetSwcNodeN(swcData dbmodel.SwcDataV1, offset int32) dbmodel.SwcDataV1 {
+	result := make(dbmodel.SwcDataV1, len(swcData))
+	copy(result, swcData)
+	for idx := range result {
+		result[idx].SwcNodeInternalData.N += offset
+		if !IsSwcRootNode(&result[idx].SwcNodeInternalData) {
+			result[idx].SwcNodeInternalData.Parent += offset
+		}
+	}
+	return result
+}
+
+func MaxSwcNodeN(swcData dbmodel.SwcDataV1) int32 {
+	maxN := int32(0)
+	for idx := range swcData {
+		maxN = max(maxN, swcData[idx].SwcNodeInternalData.N)
+	}
+	return maxN
+}
+
+func IsSwcTopologyValidationEnforced(swcMetaInfo *dbmodel.SwcMetaInfoV1) bool {
+	if swcMetaInfo.EnforceTopologyValidation {
+		return true
+	}
+	if swcMetaInfo.BelongingProjectUuid == "" {
+		return false
+	}
+
+	var projectMetaInfo dbmodel.ProjectMetaInfoV1
+	projectMetaInfo.Base.Uuid = swcMetaInfo.BelongingProjectUuid
+	if result := dal.QueryProject(&projectMetaInfo, dal.GetDbInstance()); !result.Status {
+		return false
+	}
+	return projectMetaInfo.EnforceTopologyValidation
+}
+
+// VerifySwcTopologyEdit rejects an edit which would introduce new topology issues when validation is enforced
+// for the swc or its project. apply receives the current nodes and must return the nodes after the edit. Call it with
+// the transaction context of the write, so the nodes checked are the nodes the write changes.
+func VerifySwcTopologyEdit(ctx context.Context, swcMetaInfo *dbmodel.SwcMetaInfoV1, apply func(swcData dbmodel.SwcDataV1) dbmodel.SwcDataV1) (dal.ReturnWrapper, []SwcTopologyIssue) {
+	if !IsSwcTopologyValidationEnforced(swcMetaInfo) {
+		return dal.ReturnWrapper{Status: true, Message: "Topology validation is not enforced"}, nil
+	}
+
+	var swcData dbmodel.SwcDataV1
+	if result := dal.QueryAllSwcDataWithContext(ctx, swcMetaInfo.Base.Uuid, &swcData, dal.GetDbInstance()); !result.Status {
+		return result, nil
+	}
+
+	newIssues := FindNewSwcTopologyIssues(swcData, apply(swcData))
+	if len(newIssues) != 0 {
+		return dal.ReturnWrapper{Status: false, Message: "Edit rejected, " + SwcTopologyIssuesToMessage(newIssues)}, newIssues
+	}
+	return dal.ReturnWrapper{Status: true, Message: "Topology validation passed"}, nil
+}
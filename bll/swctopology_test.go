@@ -0,0 +1,224 @@
+package bll
+
+import (
+	"DBMS/dbmodel"
+	"reflect"
+	"testing"
+)
+
+func TestValidateSwcTopology(t *testing.T) {
+	tests := []struct {
+		name    string
+		swcData dbmodel.SwcDataV1
+		// want maps a node uuid to the issue types reported for it
+		want map[string][]string
+	}{
+		{
+			name:    "empty swc",
+			swcData: nil,
+			want:    map[string][]string{},
+		},
+		{
+			name: "valid tree",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(2, 1, 3, 1, 0, 0),
+				newTestSwcNode(3, 1, 3, 0, 1, 0),
+			},
+			want: map[string][]string{},
+		},
+		{
+			name: "duplicate n",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(2, 1, 3, 1, 0, 0),
+				{Base: dbmodel.MetaInfoBase{Uuid: "duplicate"}, SwcNodeInternalData: dbmodel.SwcNodeInternalDataV1{N: 2, Parent: 1}},
+			},
+			want: map[string][]string{"duplicate": {SwcTopologyIssue_DuplicateN}},
+		},
+		{
+			name: "missing parent",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(2, 5, 3, 1, 0, 0),
+			},
+			want: map[string][]string{"node-2": {SwcTopologyIssue_MissingParent}},
+		},
+		{
+			name: "multiple roots",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(2, -1, 1, 1, 0, 0),
+				newTestSwcNode(3, 2, 3, 2, 0, 0),
+			},
+			want: map[string][]string{
+				"node-1": {SwcTopologyIssue_MultipleRoots},
+				"node-2": {SwcTopologyIssue_MultipleRoots},
+			},
+		},
+		{
+			name: "cycle with a tail",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(2, 3, 3, 1, 0, 0),
+				newTestSwcNode(3, 4, 3, 2, 0, 0),
+				newTestSwcNode(4, 2, 3, 3, 0, 0),
+				newTestSwcNode(5, 4, 3, 4, 0, 0),
+			},
+			want: map[string][]string{
+				"node-2": {SwcTopologyIssue_Cycle},
+				"node-3": {SwcTopologyIssue_Cycle},
+				"node-4": {SwcTopologyIssue_Cycle},
+			},
+		},
+		{
+			name: "self parent",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(2, 2, 3, 1, 0, 0),
+			},
+			want: map[string][]string{"node-2": {SwcTopologyIssue_Cycle}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := map[string][]string{}
+			for _, issue := range ValidateSwcTopology(test.swcData) {
+				got[issue.NodeUuid] = append(got[issue.NodeUuid], issue.IssueType)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ValidateSwcTopology issues = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindNewSwcTopologyIssues(t *testing.T) {
+	before := dbmodel.SwcDataV1{
+		newTestSwcNode(1, -1, 1, 0, 0, 0),
+		newTestSwcNode(2, 9, 3, 1, 0, 0),
+	}
+	after := ApplySwcNodeCreate(before, dbmodel.SwcDataV1{newTestSwcNode(3, 8, 3, 2, 0, 0)})
+
+	issues := FindNewSwcTopologyIssues(before, after)
+	if len(issues) != 1 || issues[0].NodeUuid != "node-3" || issues[0].IssueType != SwcTopologyIssue_MissingParent {
+		t.Fatalf("FindNewSwcTopologyIssues = %+v, want only the missing parent of node-3", issues)
+	}
+	if issues := FindNewSwcTopologyIssues(after, before); len(issues) != 0 {
+		t.Errorf("fixing an issue reported %+v", issues)
+	}
+}
+
+func TestOffsetSwcNodeN(t *testing.T) {
+	swcData := dbmodel.SwcDataV1{
+		newTestSwcNode(1, -1, 1, 0, 0, 0),
+		newTestSwcNode(2, 1, 3, 1, 0, 0),
+		newTestSwcNode(3, 2, 3, 2, 0, 0),
+	}
+
+	offsetSwcData := OffsetSwcNodeN(swcData, 10)
+	want := []struct{ n, parent int32 }{{11, -1}, {12, 11}, {13, 12}}
+	for idx, node := range offsetSwcData {
+		if node.SwcNodeInternalData.N != want[idx].n || node.SwcNodeInternalData.Parent != want[idx].parent {
+			t.Errorf("node %d has n %d parent %d, want n %d parent %d", idx, node.SwcNodeInternalData.N, node.SwcNodeInternalData.Parent, want[idx].n, want[idx].parent)
+		}
+	}
+	if swcData[1].SwcNodeInternalData.N != 2 {
+		t.Errorf("OffsetSwcNodeN modified its input")
+	}
+	if maxN := MaxSwcNodeN(offsetSwcData); maxN != 13 {
+		t.Errorf("MaxSwcNodeN = %d, want 13", maxN)
+	}
+	if maxN := MaxSwcNodeN(nil); maxN != 0 {
+		t.Errorf("MaxSwcNodeN of no nodes = %d, want 0", maxN)
+	}
+}
+
+func TestTopologicalRenumberSwcNodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		swcData dbmodel.SwcDataV1
+		want    []dbmodel.NodeNParentV1
+		// wantDetached is the number of nodes detached from a missing parent
+		wantDetached int
+		wantErr      bool
+	}{
+		{
+			name: "sparse n depth first",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(30, 10, 3, 0, 1, 0),
+				newTestSwcNode(10, -1, 1, 0, 0, 0),
+				newTestSwcNode(20, 10, 3, 1, 0, 0),
+				newTestSwcNode(40, 20, 3, 2, 0, 0),
+			},
+			want: []dbmodel.NodeNParentV1{
+				{Uuid: "node-10", N: 1, Parent: -1},
+				{Uuid: "node-20", N: 2, Parent: 1},
+				{Uuid: "node-40", N: 3, Parent: 2},
+				{Uuid: "node-30", N: 4, Parent: 1},
+			},
+		},
+		{
+			name: "child before parent",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, 2, 3, 1, 0, 0),
+				newTestSwcNode(2, -1, 1, 0, 0, 0),
+			},
+			want: []dbmodel.NodeNParentV1{
+				{Uuid: "node-2", N: 1, Parent: -1},
+				{Uuid: "node-1", N: 2, Parent: 1},
+			},
+		},
+		{
+			name: "missing parent becomes a root",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(5, 4, 3, 1, 0, 0),
+				newTestSwcNode(6, 5, 3, 2, 0, 0),
+			},
+			want: []dbmodel.NodeNParentV1{
+				{Uuid: "node-1", N: 1, Parent: -1},
+				{Uuid: "node-5", N: 2, Parent: -1},
+				{Uuid: "node-6", N: 3, Parent: 2},
+			},
+			wantDetached: 1,
+		},
+		{
+			name: "duplicate n",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(1, -1, 1, 1, 0, 0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "cycle",
+			swcData: dbmodel.SwcDataV1{
+				newTestSwcNode(1, -1, 1, 0, 0, 0),
+				newTestSwcNode(2, 3, 3, 1, 0, 0),
+				newTestSwcNode(3, 2, 3, 2, 0, 0),
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodeNParent, detached, err := TopologicalRenumberSwcNodes(test.swcData)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("TopologicalRenumberSwcNodes error = %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if detached != test.wantDetached {
+				t.Errorf("detached %d nodes, want %d", detached, test.wantDetached)
+			}
+			if !reflect.DeepEqual(nodeNParent, test.want) {
+				t.Errorf("TopologicalRenumberSwcNodes = %+v, want %+v", nodeNParent, test.want)
+			}
+			if issues := ValidateSwcTopology(ApplySwcNParentUpdate(test.swcData, nodeNParent)); test.wantDetached == 0 && len(issues) != 0 {
+				t.Errorf("renumbered swc has topology issues %+v", issues)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package bll
+
+import (
+	"DBMS/dal"
+	"DBMS/dbmodel"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SwcUndoableIncrementOperations are the operation types a user can undo and redo.
+var SwcUndoableIncrementOperations = map[string]bool{
+	dal.IncrementOp_Create:        true,
+	dal.IncrementOp_Delete:        true,
+	dal.IncrementOp_Update:        true,
+	dal.IncrementOp_UpdateNParent: true,
+}
+
+// SwcUndoPlan is the operation which undoes or redoes Target. ExistingNodeUuids are the nodes Operation changes or
+// deletes, the nodes it creates are left out.
+type SwcUndoPlan struct {
+	Target            dbmodel.SwcIncrementOperationV1
+	Operation         dbmodel.SwcIncrementOperationV1
+	ExistingNodeUuids []string
+}
+
+func removeSwcUndoStackEntry(stack []dbmodel.SwcIncrementOperationV1, operationUuid string) ([]dbmodel.SwcIncrementOperationV1, *dbmodel.SwcIncrementOperationV1) {
+	for idx := len(stack) - 1; idx >= 0; idx-- {
+		if stack[idx].Base.Uuid == operationUuid {
+			entry := stack[idx]
+			return append(stack[:idx], stack[idx+1:]...), &entry
+		}
+	}
+	return stack, nil
+}
+
+// BuildSwcUndoStacks rebuilds the undo and redo stack of a user from the increment operations the user made, oldest
+// first. A new operation clears the redo stack, a redo can be undone again. Operations which cannot be undone, like
+// ClearAll or a batch, also clear the undo stack as nothing before them can be undone safely.
+func BuildSwcUndoStacks(operations dbmodel.SwcIncrementOperationListV1) ([]dbmodel.SwcIncrementOperationV1, []dbmodel.SwcIncrementOperationV1) {
+	var undoStack, redoStack []dbmodel.SwcIncrementOperationV1
+	for _, operation := range operations {
+		switch {
+		case operation.UndoOperationUuid != "":
+			var undone *dbmodel.SwcIncrementOperationV1
+			undoStack, undone = removeSwcUndoStackEntry(undoStack, operation.UndoOperationUuid)
+			if undone != nil {
+				redoStack = append(redoStack, *undone)
+			}
+		case operation.RedoOperationUuid != "":
+			redoStack, _ = removeSwcUndoStackEntry(redoStack, operation.RedoOperationUuid)
+			undoStack = append(undoStack, operation)
+		case SwcUndoableIncrementOperations[operation.IncrementOperation]:
+			undoStack = append(undoStack, operation)
+			redoStack = nil
+		default:
+			undoStack = nil
+			redoStack = nil
+		}
+	}
+	return undoStack, redoStack
+}
+
+func swcIncrementOperationNodeUuids(operation *dbmodel.SwcIncrementOperationV1) []string {
+	return append(SwcNodeUuids(operation.SwcData), NodeNParentUuids(operation.NodeNParent)...)
+}
+
+// collectSwcUndoTargetChanges replays the current increment operation list from its start snapshot for the nodes of
+// target, and returns what target did to them and the nodes someone other than userName changed afterwards.
+func collectSwcUndoTargetChanges(swcMetaInfo *dbmodel.SwcMetaInfoV1, target *dbmodel.SwcIncrementOperationV1, userName string) ([]SwcNodeChange, []string, dal.ReturnWrapper) {
+	var startSnapshot string
+	for _, incrementOperation := range swcMetaInfo.SwcIncrementOperationList {
+		if incrementOperation.IncrementOperationCollectionName == swcMetaInfo.CurrentIncrementOperationCollectionName {
+			startSnapshot = incrementOperation.StartSnapshot
+		}
+	}
+	snapshotFound := false
+	for _, snapshot := range swcMetaInfo.SwcSnapshotList {
+		if startSnapshot != "" && snapshot.SwcSnapshotCollectionName == startSnapshot {
+			snapshotFound = true
+		}
+	}
+	if !snapshotFound {
+		return nil, nil, dal.ReturnWrapper{Status: false, Message: "The start snapshot of the current increment operation list no longer exists, cannot undo!"}
+	}
+
+	nodeUuids := swcIncrementOperationNodeUuids(target)
+	trackedNodeUuids := make(map[string]bool, len(nodeUuids))
+	for _, nodeUuid := range nodeUuids {
+		trackedNodeUuids[nodeUuid] = true
+	}
+	tracked := func(nodeUuid string) bool {
+		return trackedNodeUuids[nodeUuid]
+	}
+
+	var snapshotNodes dbmodel.SwcDataV1
+	if result := dal.QuerySwcSnapshotNodesByUuid(startSnapshot, nodeUuids, &snapshotNodes, dal.GetDbInstance()); !result.Status {
+		return nil, nil, result
+	}
+	nodes := make(map[string]dbmodel.SwcNodeDataV1, len(snapshotNodes))
+	for _, node := range snapshotNodes {
+		nodes[node.Base.Uuid] = node
+	}
+
+	var operations dbmodel.SwcIncrementOperationListV1
+	if result := dal.QuerySwcIncrementOperationTouchingNodes(swcMetaInfo.CurrentIncrementOperationCollectionName, nodeUuids, &operations, dal.GetDbInstance()); !result.Status {
+		return nil, nil, result
+	}
+
+	var targetChanges []SwcNodeChange
+	var conflictNodeUuids []string
+	conflicted := map[string]bool{}
+	targetSeen := false
+	for idx := range operations {
+		isTarget := operations[idx].Base.Uuid == target.Base.Uuid
+		ApplySwcNodeChanges(nodes, &operations[idx], tracked, func(change SwcNodeChange) {
+			if isTarget {
+				targetChanges = append(targetChanges, change)
+			} else if targetSeen && change.Actor != userName && !conflicted[change.NodeUuid] {
+				conflicted[change.NodeUuid] = true
+				conflictNodeUuids = append(conflictNodeUuids, change.NodeUuid)
+			}
+		})
+		if isTarget {
+			targetSeen = true
+		}
+	}
+	if !targetSeen {
+		return nil, nil, dal.ReturnWrapper{Status: false, Message: "Cannot find increment operation " + target.Base.Uuid + "!"}
+	}
+
+	return targetChanges, conflictNodeUuids, dal.ReturnWrapper{Status: true, Message: "Collect node changes successfully!"}
+}
+
+// PlanSwcUndo finds the latest operation userName can undo, or redo when redo is set, in the current increment
+// operation list of the swc and computes the operation which reverts, or repeats, it. The plan is nil when there is
+// nothing to undo or redo. The returned node uuids are the nodes of that operation someone else changed since, the
+// plan is nil then as well.
+func PlanSwcUndo(swcMetaInfo *dbmodel.SwcMetaInfoV1, userName string, redo bool, editTime time.Time) (*SwcUndoPlan, []string, dal.ReturnWrapper) {
+	if swcMetaInfo.CurrentIncrementOperationCollectionName == "" {
+		return nil, nil, dal.ReturnWrapper{Status: true, Message: "Swc has no increment operation list, nothing to undo!"}
+	}
+
+	var userOperations dbmodel.SwcIncrementOperationListV1
+	if result := dal.QuerySwcIncrementOperationByFilter(swcMetaInfo.CurrentIncrementOperationCollectionName, dal.SwcIncrementOperationFilter{Creator: userName}, nil, 0, &userOperations, dal.GetDbInstance()); !result.Status {
+		return nil, nil, result
+	}
+	undoStack, redoStack := BuildSwcUndoStacks(userOperations)
+	stack := undoStack
+	if redo {
+		stack = redoStack
+	}
+	if len(stack) == 0 {
+		if redo {
+			return nil, nil, dal.ReturnWrapper{Status: true, Message: "Nothing to redo!"}
+		}
+		return nil, nil, dal.ReturnWrapper{Status: true, Message: "Nothing to undo!"}
+	}
+	target := stack[len(stack)-1]
+
+	changes, conflictNodeUuids, result := collectSwcUndoTargetChanges(swcMetaInfo, &target, userName)
+	if !result.Status {
+		return nil, nil, result
+	}
+	if len(conflictNodeUuids) != 0 {
+		return nil, conflictNodeUuids, dal.ReturnWrapper{Status: false, Message: "Nodes of operation " + target.Base.Uuid + " have been changed by other users since!"}
+	}
+
+	plan := &SwcUndoPlan{Target: target, ExistingNodeUuids: []string{}}
+	operation := &plan.Operation
+	operation.Base.Id = primitive.NewObjectID()
+	operation.Base.Uuid = uuid.NewString()
+	operation.Base.DataAccessModelVersion = "V1"
+	operation.CreateTime = editTime
+	operation.IncrementOperation = target.IncrementOperation
+	if redo {
+		operation.RedoOperationUuid = target.Base.Uuid
+	} else {
+		operation.UndoOperationUuid = target.Base.Uuid
+		switch target.IncrementOperation {
+		case dal.IncrementOp_Create:
+			operation.IncrementOperation = dal.IncrementOp_Delete
+		case dal.IncrementOp_Delete:
+			operation.IncrementOperation = dal.IncrementOp_Create
+		}
+	}
+
+	for _, change := range changes {
+		from, to := change.After, change.Before
+		if redo {
+			from, to = change.Before, change.After
+		}
+		switch operation.IncrementOperation {
+		case dal.IncrementOp_Create:
+			if to != nil {
+				node := *to
+				node.LastModifiedTime = editTime
+				operation.SwcData = append(operation.SwcData, node)
+			}
+		case dal.IncrementOp_Delete:
+			if from != nil {
+				operation.SwcData = append(operation.SwcData, *from)
+				plan.ExistingNodeUuids = append(plan.ExistingNodeUuids, change.NodeUuid)
+			}
+		case dal.IncrementOp_Update:
+			if from != nil && to != nil {
+				node := *to
+				node.Creator = userName
+				node.LastModifiedTime = editTime
+				operation.SwcData = append(operation.SwcData, node)
+				plan.ExistingNodeUuids = append(plan.ExistingNodeUuids, change.NodeUuid)
+			}
+		case dal.IncrementOp_UpdateNParent:
+			if from != nil && to != nil {
+				operation.NodeNParent = append(operation.NodeNParent, dbmodel.NodeNParentV1{
+					Uuid:   change.NodeUuid,
+					N:      to.SwcNodeInternalData.N,
+					Parent: to.SwcNodeInternalData.Parent,
+				})
+				plan.ExistingNodeUuids = append(plan.ExistingNodeUuids, change.NodeUuid)
+			}
+		}
+	}
+
+	return plan, nil, dal.ReturnWrapper{Status: true, Message: "Plan undo successfully!"}
+}
+
+// ApplySwcUndoPlan applies and records the operation of plan in one transaction. The write conflicts when a node of
+// the plan changed after expectedRevision, the revision the plan was computed at, and is rejected with the topology
+// issues when VerifySwcTopologyEdit refuses the operation on the nodes read in the transaction.
+func ApplySwcUndoPlan(swcMetaInfo *dbmodel.SwcMetaInfoV1, plan *SwcUndoPlan, executor SwcIncrementOperationExecutor, expectedRevision int64) (int64, *SwcRevisionConflict, []SwcTopologyIssue, dal.ReturnWrapper) {
+	var revision int64
+	var revisionConflict *SwcRevisionConflict
+	var topologyIssues []SwcTopologyIssue
+	result := dal.RunInTransaction(dal.GetDbInstance(), func(sessionContext context.Context) dal.ReturnWrapper {
+		var result dal.ReturnWrapper
+		if result, topologyIssues = VerifySwcTopologyEdit(sessionContext, swcMetaInfo, func(currentSwcData dbmodel.SwcDataV1) dbmodel.SwcDataV1 {
+			return ReplaySwcIncrementOperation(currentSwcData, &plan.Operation)
+		}); !result.Status {
+			return result
+		}
+		revision, revisionConflict, result = RunSwcRevisionWrite(sessionContext, swcMetaInfo.Base.Uuid, &expectedRevision, plan.ExistingNodeUuids, func(newRevision int64) dal.ReturnWrapper {
+			// copy so a retried transaction starts again from the plan
+			operation := plan.Operation
+			if operation.IncrementOperation == dal.IncrementOp_Create {
+				operation.SwcData = append(dbmodel.SwcDataV1{}, plan.Operation.SwcData...)
+				for idx := range operation.SwcData {
+					operation.SwcData[idx].Version = newRevision
+				}
+			}
+			if len(operation.SwcData) != 0 || len(operation.NodeNParent) != 0 {
+				if result := dal.ApplySwcIncrementOperationWithContext(sessionContext, swcMetaInfo.Base.Uuid, &operation, dal.GetDbInstance()); !result.Status {
+					return result
+				}
+			}
+			if result := RecordSwcIncrementOperation(sessionContext, swcMetaInfo, executor, newRevision, operation); !result.Status {
+				return result
+			}
+			return dal.ReturnWrapper{Status: true, Message: "Apply operation " + operation.Base.Uuid + " successfully!"}
+		})
+		return result
+	})
+	return revision, revisionConflict, topologyIssues, result
+}
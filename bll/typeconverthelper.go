@@ -142,6 +142,7 @@ func ProjectMetaInfoV1ProtobufToDbmodel(protoMessage *message.ProjectMetaInfoV1)
 	}
 
 	dbmodelMessage.WorkMode = protoMessage.WorkMode
+	dbmodelMessage.EnforceTopologyValidation = protoMessage.EnforceTopologyValidation
 
 	if protoMessage.Permission != nil {
 		if protoMessage.Permission.Owner != nil {
@@ -194,6 +195,14 @@ func ProjectMetaInfoV1DbmodelToProtobuf(dbmodelMessage *dbmodel.ProjectMetaInfoV
 	protoMessage.LastModifiedTime = timestamppb.New(dbmodelMessage.LastModifiedTime)
 	protoMessage.SwcList = dbmodelMessage.SwcList
 	protoMessage.WorkMode = dbmodelMessage.WorkMode
+	protoMessage.EnforceTopologyValidation = dbmodelMessage.EnforceTopologyValidation
+
+	// deletion state is only changed by DeleteProject and RestoreProject, so it is not read back from the client
+	protoMessage.IsDeleted = dbmodelMessage.IsDeleted
+	protoMessage.DeletedBy = dbmodelMessage.DeletedBy
+	if !dbmodelMessage.DeleteTime.IsZero() {
+		protoMessage.DeleteTime = timestamppb.New(dbmodelMessage.DeleteTime)
+	}
 
 	protoMessage.Permission = &message.PermissionMetaInfoV1{}
 	protoMessage.Permission.Owner = &message.UserPermissionAclV1{}
@@ -251,10 +260,12 @@ func SwcMetaInfoV1ProtobufToDbmodel(protoMessage *message.SwcMetaInfoV1) *dbmode
 	dbmodelMessage.Creator = protoMessage.Creator
 	dbmodelMessage.SwcType = protoMessage.SwcType
 	dbmodelMessage.BelongingProjectUuid = protoMessage.BelongingProjectUuid
+	dbmodelMessage.EnforceTopologyValidation = protoMessage.EnforceTopologyValidation
 
 	if protoMessage.CreateTime != nil {
 		dbmodelMessage.CreateTime = protoMessage.CreateTime.AsTime()
 	}
+
 	if protoMessage.LastModifiedTime != nil {
 		dbmodelMessage.LastModifiedTime = protoMessage.LastModifiedTime.AsTime()
 	}
@@ -271,6 +282,8 @@ func SwcMetaInfoV1ProtobufToDbmodel(protoMessage *message.SwcMetaInfoV1) *dbmode
 			snapshotMetaInfo.Creator = snapshotProto.Creator
 			snapshotMetaInfo.SwcSnapshotCollectionName = snapshotProto.SwcSnapshotCollectionName
 			snapshotMetaInfo.CreateTime = snapshotProto.CreateTime.AsTime()
+			snapshotMetaInfo.Label = snapshotProto.Label
+			snapshotMetaInfo.Protected = snapshotProto.Protected
 
 			dbmodelMessage.SwcSnapshotList = append(dbmodelMessage.SwcSnapshotList, snapshotMetaInfo)
 		}
@@ -287,6 +300,7 @@ func SwcMetaInfoV1ProtobufToDbmodel(protoMessage *message.SwcMetaInfoV1) *dbmode
 
 			snapshotMetaInfo.StartSnapshot = snapshotProto.StartSnapshot
 			snapshotMetaInfo.IncrementOperationCollectionName = snapshotProto.IncrementOperationCollectionName
+			snapshotMetaInfo.StartRevision = snapshotProto.StartRevision
 			snapshotMetaInfo.CreateTime = snapshotProto.CreateTime.AsTime()
 
 			dbmodelMessage.SwcIncrementOperationList = append(dbmodelMessage.SwcIncrementOperationList, snapshotMetaInfo)
@@ -363,10 +377,26 @@ func SwcMetaInfoV1DbmodelToProtobuf(dbmodelMessage *dbmodel.SwcMetaInfoV1) *mess
 	protoMessage.Creator = dbmodelMessage.Creator
 	protoMessage.SwcType = dbmodelMessage.SwcType
 	protoMessage.BelongingProjectUuid = dbmodelMessage.BelongingProjectUuid
+	protoMessage.EnforceTopologyValidation = dbmodelMessage.EnforceTopologyValidation
 
 	protoMessage.CreateTime = timestamppb.New(dbmodelMessage.CreateTime)
 	protoMessage.LastModifiedTime = timestamppb.New(dbmodelMessage.LastModifiedTime)
 
+	protoMessage.SourceSwcUuid = dbmodelMessage.SourceSwcUuid
+	if !dbmodelMessage.SourceSwcTime.IsZero() {
+		protoMessage.SourceSwcTime = timestamppb.New(dbmodelMessage.SourceSwcTime)
+	}
+
+	// deletion state is only changed by DeleteSwc and RestoreSwc, so it is not read back from the client
+	protoMessage.IsDeleted = dbmodelMessage.IsDeleted
+	protoMessage.DeletedBy = dbmodelMessage.DeletedBy
+	if !dbmodelMessage.DeleteTime.IsZero() {
+		protoMessage.DeleteTime = timestamppb.New(dbmodelMessage.DeleteTime)
+	}
+
+	// the revision is only changed by node data writes, so it is not read back from the client either
+	protoMessage.Revision = dbmodelMessage.Revision
+
 	for _, snapshotMetaInfo := range dbmodelMessage.SwcSnapshotList {
 		var snapshotMetaInfoDbModel message.SwcSnapshotMetaInfoV1
 		snapshotMetaInfoDbModel.Base = &message.MetaInfoBase{}
@@ -376,6 +406,8 @@ func SwcMetaInfoV1DbmodelToProtobuf(dbmodelMessage *dbmodel.SwcMetaInfoV1) *mess
 		snapshotMetaInfoDbModel.CreateTime = timestamppb.New(snapshotMetaInfo.CreateTime)
 		snapshotMetaInfoDbModel.SwcSnapshotCollectionName = snapshotMetaInfo.SwcSnapshotCollectionName
 		snapshotMetaInfoDbModel.Creator = snapshotMetaInfo.Creator
+		snapshotMetaInfoDbModel.Label = snapshotMetaInfo.Label
+		snapshotMetaInfoDbModel.Protected = snapshotMetaInfo.Protected
 		protoMessage.SwcSnapshotMetaInfoList = append(protoMessage.SwcSnapshotMetaInfoList, &snapshotMetaInfoDbModel)
 	}
 
@@ -388,6 +420,7 @@ func SwcMetaInfoV1DbmodelToProtobuf(dbmodelMessage *dbmodel.SwcMetaInfoV1) *mess
 		incrementOpearationMetaInfoDbModel.CreateTime = timestamppb.New(incrementOperationMetaInfo.CreateTime)
 		incrementOpearationMetaInfoDbModel.StartSnapshot = incrementOperationMetaInfo.StartSnapshot
 		incrementOpearationMetaInfoDbModel.IncrementOperationCollectionName = incrementOperationMetaInfo.IncrementOperationCollectionName
+		incrementOpearationMetaInfoDbModel.StartRevision = incrementOperationMetaInfo.StartRevision
 		protoMessage.SwcIncrementOperationMetaInfoList = append(protoMessage.SwcIncrementOperationMetaInfoList, &incrementOpearationMetaInfoDbModel)
 	}
 
@@ -447,6 +480,7 @@ func SwcNodeDataV1ProtobufToDbmodel(protoMessage *message.SwcNodeDataV1) *dbmode
 	}
 	dbmodelMessage.CheckerUserUuid = protoMessage.CheckerUserUuid
 	dbmodelMessage.DeviceType = protoMessage.DeviceType
+	dbmodelMessage.Version = protoMessage.Version
 
 	if protoMessage.SwcNodeInternalData != nil {
 		dbmodelMessage.SwcNodeInternalData.N = protoMessage.SwcNodeInternalData.N
@@ -480,6 +514,7 @@ func SwcNodeDataV1DbmodelToProtobuf(dbmodelMessage *dbmodel.SwcNodeDataV1) *mess
 
 	protoMessage.CheckerUserUuid = dbmodelMessage.CheckerUserUuid
 	protoMessage.DeviceType = dbmodelMessage.DeviceType
+	protoMessage.Version = dbmodelMessage.Version
 
 	protoMessage.SwcNodeInternalData = &message.SwcNodeInternalDataV1{}
 	protoMessage.SwcNodeInternalData.N = dbmodelMessage.SwcNodeInternalData.N
@@ -573,6 +608,8 @@ func SwcSnapshotMetaInfoV1MetaInfoV1ProtobufToDbmodel(protoMessage *message.SwcS
 
 	dbmodelMessage.SwcSnapshotCollectionName = protoMessage.SwcSnapshotCollectionName
 	dbmodelMessage.Creator = protoMessage.Creator
+	dbmodelMessage.Label = protoMessage.Label
+	dbmodelMessage.Protected = protoMessage.Protected
 
 	if protoMessage.CreateTime != nil {
 		dbmodelMessage.CreateTime = protoMessage.CreateTime.AsTime()
@@ -591,6 +628,8 @@ func SwcSnapshotMetaInfoV1MetaInfoV1DbmodelToProtobuf(dbmodelMessage *dbmodel.Sw
 	protoMessage.CreateTime = timestamppb.New(dbmodelMessage.CreateTime)
 	protoMessage.SwcSnapshotCollectionName = dbmodelMessage.SwcSnapshotCollectionName
 	protoMessage.Creator = dbmodelMessage.Creator
+	protoMessage.Label = dbmodelMessage.Label
+	protoMessage.Protected = dbmodelMessage.Protected
 
 	return &protoMessage
 }
@@ -605,6 +644,7 @@ func SwcIncrementOperationMetaInfoV1MetaInfoV1ProtobufToDbmodel(protoMessage *me
 
 	dbmodelMessage.StartSnapshot = protoMessage.StartSnapshot
 	dbmodelMessage.IncrementOperationCollectionName = protoMessage.IncrementOperationCollectionName
+	dbmodelMessage.StartRevision = protoMessage.StartRevision
 
 	if protoMessage.CreateTime != nil {
 		dbmodelMessage.CreateTime = protoMessage.CreateTime.AsTime()
@@ -623,6 +663,7 @@ func SwcIncrementOperationMetaInfoV1MetaInfoV1DbmodelToProtobuf(dbmodelMessage *
 	protoMessage.CreateTime = timestamppb.New(dbmodelMessage.CreateTime)
 	protoMessage.StartSnapshot = dbmodelMessage.StartSnapshot
 	protoMessage.IncrementOperationCollectionName = dbmodelMessage.IncrementOperationCollectionName
+	protoMessage.StartRevision = dbmodelMessage.StartRevision
 
 	return &protoMessage
 }
@@ -663,6 +704,10 @@ func SwcIncrementOperationV1MetaInfoV1ProtobufToDbmodel(protoMessage *message.Sw
 	dbmodelMessage.SwcData = dbSwcData
 	dbmodelMessage.NodeNParent = dbNParent
 
+	for _, groupedOperation := range protoMessage.GetGroupedOperations() {
+		dbmodelMessage.GroupedOperations = append(dbmodelMessage.GroupedOperations, *SwcIncrementOperationV1MetaInfoV1ProtobufToDbmodel(groupedOperation))
+	}
+
 	return &dbmodelMessage
 }
 
@@ -674,6 +719,14 @@ func SwcIncrementOperationListV1DbmodelToProtobuf(dbmodelMessage *dbmodel.SwcInc
 	protoMessage.Base.DataAccessModelVersion = dbmodelMessage.Base.DataAccessModelVersion
 	protoMessage.CreateTime = timestamppb.New(dbmodelMessage.CreateTime)
 	protoMessage.IncrementOperation = message.IncrementOperationV1(message.IncrementOperationV1_value[dbmodelMessage.IncrementOperation])
+	protoMessage.Revision = dbmodelMessage.Revision
+	protoMessage.Creator = dbmodelMessage.Creator
+	protoMessage.CreatorUuid = dbmodelMessage.CreatorUuid
+	protoMessage.ClientAddress = dbmodelMessage.ClientAddress
+	protoMessage.DeviceType = dbmodelMessage.DeviceType
+	protoMessage.ApiVersion = dbmodelMessage.ApiVersion
+	protoMessage.UndoOperationUuid = dbmodelMessage.UndoOperationUuid
+	protoMessage.RedoOperationUuid = dbmodelMessage.RedoOperationUuid
 
 	if dbmodelMessage.SwcData != nil {
 		var pbSwcData message.SwcDataV1
@@ -696,5 +749,127 @@ func SwcIncrementOperationListV1DbmodelToProtobuf(dbmodelMessage *dbmodel.SwcInc
 		protoMessage.NodeNParent = pbNodeNParentData
 	}
 
+	for idx := range dbmodelMessage.GroupedOperations {
+		protoMessage.GroupedOperations = append(protoMessage.GroupedOperations, SwcIncrementOperationListV1DbmodelToProtobuf(&dbmodelMessage.GroupedOperations[idx]))
+	}
+
+	return &protoMessage
+}
+
+func SwcTopologyIssueToProtobuf(issue *SwcTopologyIssue) *message.SwcTopologyIssueV1 {
+	var protoMessage message.SwcTopologyIssueV1
+	protoMessage.IssueType = issue.IssueType
+	protoMessage.NodeUuid = issue.NodeUuid
+	protoMessage.N = issue.N
+	protoMessage.Parent = issue.Parent
+	protoMessage.Description = issue.Description
+	return &protoMessage
+}
+
+func SwcBoundingBoxToProtobuf(boundingBox *SwcBoundingBox) *message.BoundingBoxV1 {
+	var protoMessage message.BoundingBoxV1
+	protoMessage.MinX = boundingBox.MinX
+	protoMessage.MinY = boundingBox.MinY
+	protoMessage.MinZ = boundingBox.MinZ
+	protoMessage.MaxX = boundingBox.MaxX
+	protoMessage.MaxY = boundingBox.MaxY
+	protoMessage.MaxZ = boundingBox.MaxZ
 	return &protoMessage
 }
+
+func SwcMorphometryToProtobuf(morphometry *SwcMorphometry) *message.SwcMorphometryV1 {
+	var protoMessage message.SwcMorphometryV1
+	protoMessage.NodeNumber = morphometry.NodeNumber
+	protoMessage.TreeNumber = morphometry.TreeNumber
+	protoMessage.BranchPointNumber = morphometry.BranchPointNumber
+	protoMessage.TipNumber = morphometry.TipNumber
+	protoMessage.TotalLength = morphometry.TotalLength
+	protoMessage.MaxPathDistance = morphometry.MaxPathDistance
+	protoMessage.MaxEuclideanDistance = morphometry.MaxEuclideanDistance
+	protoMessage.BoundingBox = SwcBoundingBoxToProtobuf(&morphometry.BoundingBox)
+
+	for _, typeLength := range morphometry.TypeLengthList {
+		protoMessage.TypeLengthList = append(protoMessage.TypeLengthList, &message.SwcTypeLengthV1{
+			Type:   typeLength.Type,
+			Length: typeLength.Length,
+		})
+	}
+
+	protoMessage.ShollStepSize = morphometry.ShollStepSize
+	for _, shollIntersection := range morphometry.ShollProfile {
+		protoMessage.ShollProfile = append(protoMessage.ShollProfile, &message.ShollIntersectionV1{
+			Radius:        shollIntersection.Radius,
+			Intersections: shollIntersection.Intersections,
+		})
+	}
+
+	return &protoMessage
+}
+
+func SwcDataV1DbmodelToProtobuf(swcData dbmodel.SwcDataV1) *message.SwcDataV1 {
+	var protoMessage message.SwcDataV1
+	for idx := range swcData {
+		protoMessage.SwcData = append(protoMessage.SwcData, SwcNodeDataV1DbmodelToProtobuf(&swcData[idx]))
+	}
+	return &protoMessage
+}
+
+func SwcNodeDiffToProtobuf(nodeDiff *SwcNodeDiff) *message.SwcNodeDiffV1 {
+	var protoMessage message.SwcNodeDiffV1
+	protoMessage.NodeUuid = nodeDiff.NodeUuid
+	for _, fieldDiff := range nodeDiff.FieldDiffs {
+		protoMessage.FieldDiffs = append(protoMessage.FieldDiffs, &message.SwcNodeFieldDiffV1{
+			FieldName: fieldDiff.FieldName,
+			OldValue:  fieldDiff.OldValue,
+			NewValue:  fieldDiff.NewValue,
+		})
+	}
+	protoMessage.OldNode = SwcNodeDataV1DbmodelToProtobuf(&nodeDiff.OldNode)
+	protoMessage.NewNode = SwcNodeDataV1DbmodelToProtobuf(&nodeDiff.NewNode)
+	return &protoMessage
+}
+
+func SwcNodeChangeToProtobuf(change *SwcNodeChange) *message.SwcNodeHistoryEntryV1 {
+	var protoMessage message.SwcNodeHistoryEntryV1
+	protoMessage.IncrementOperationUuid = change.IncrementOperationUuid
+	protoMessage.IncrementOperation = message.IncrementOperationV1(message.IncrementOperationV1_value[change.IncrementOperation])
+	protoMessage.Revision = change.Revision
+	protoMessage.Actor = change.Actor
+	protoMessage.CreateTime = timestamppb.New(change.CreateTime)
+	if change.Before != nil {
+		protoMessage.Before = SwcNodeDataV1DbmodelToProtobuf(change.Before)
+	}
+	if change.After != nil {
+		protoMessage.After = SwcNodeDataV1DbmodelToProtobuf(change.After)
+	}
+	return &protoMessage
+}
+
+func SwcNodeBlameToProtobuf(blame *SwcNodeBlame) *message.SwcNodeBlameV1 {
+	var protoMessage message.SwcNodeBlameV1
+	protoMessage.NodeUuid = blame.NodeUuid
+	protoMessage.Actor = blame.Actor
+	protoMessage.IncrementOperation = message.IncrementOperationV1(message.IncrementOperationV1_value[blame.IncrementOperation])
+	protoMessage.Revision = blame.Revision
+	protoMessage.ModifiedTime = timestamppb.New(blame.ModifiedTime)
+	protoMessage.FromHistory = blame.FromHistory
+	return &protoMessage
+}
+
+func SwcRegionHitToProtobuf(hit *SwcRegionHit) *message.SwcRegionHitV1 {
+	return &message.SwcRegionHitV1{
+		SwcUuid:         hit.SwcUuid,
+		SwcName:         hit.SwcName,
+		HitNumber:       hit.HitNumber,
+		NearestNode:     SwcNodeDataV1DbmodelToProtobuf(&hit.NearestNode),
+		NearestDistance: hit.NearestDistance,
+	}
+}
+
+func SwcNearestNodeToProtobuf(nearestNode *SwcNearestNode) *message.SwcNearestNodeV1 {
+	return &message.SwcNearestNodeV1{
+		SwcUuid:  nearestNode.SwcUuid,
+		Node:     SwcNodeDataV1DbmodelToProtobuf(&nearestNode.Node),
+		Distance: nearestNode.Distance,
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"DBMS/SwcDbmsCommon/Generated/go/proto/service"
 	"DBMS/UnitTest"
+	"DBMS/apihandler"
 	"DBMS/bll"
 	"DBMS/config"
 	"DBMS/logger"
@@ -33,6 +34,16 @@ func startHttpReverseProxyServer() error {
 		return err
 	}
 
+	// Plain file download routes, e.g. GET /SwcFile/{SwcUuid}/swc?UserName=xxx&UserToken=xxx
+	err = mux.HandlePath("GET", "/SwcFile/{SwcUuid}/swc", apihandler.ExportSwcFileHandler(bll.SwcFileFormat))
+	if err != nil {
+		return err
+	}
+	err = mux.HandlePath("GET", "/SwcFile/{SwcUuid}/eswc", apihandler.ExportSwcFileHandler(bll.EswcFileFormat))
+	if err != nil {
+		return err
+	}
+
 	// Start HTTP server (and proxy calls to gRPC server endpoint)
 	httpAddress := ":" + strconv.Itoa(int(config.AppConfig.ReverseProxyPort))
 	return http.ListenAndServe(httpAddress, mux)
@@ -54,6 +65,10 @@ func main() {
 	bll.Initialize()
 	bll.CronAutoSaveDailyStatistics()
 	bll.CronHeartBeatValidationAndRefresh()
+	bll.CronAutoSnapshotAndCompaction()
+	bll.CronOrphanCollectionGc()
+	bll.CronPurgeDeletedItems()
+	bll.StartSwcChangeStreamWatcher()
 	bll.NewGrpcServer()
 
 	return
@@ -62,6 +77,10 @@ func main() {
 	bll.Initialize()
 	bll.CronAutoSaveDailyStatistics()
 	bll.CronHeartBeatValidationAndRefresh()
+	bll.CronAutoSnapshotAndCompaction()
+	bll.CronOrphanCollectionGc()
+	bll.CronPurgeDeletedItems()
+	bll.StartSwcChangeStreamWatcher()
 	bll.NewGrpcServer()
 	return
 
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"strings"
 )
 
 const ApiVersion = "2024.05.06"
@@ -20,6 +21,16 @@ type Config struct {
 	MongodbPort      int32
 	MongodbUser      string
 	MongodbPassword  string
+
+	AutoSnapshotIncrementOperationNumber   int32
+	AutoSnapshotIncrementOperationAgeHours int32
+	SnapshotDailyRetentionDays             int32
+	SnapshotWeeklyRetentionDays            int32
+	GarbageCollectionRemoveOrphans         bool
+	DeletedItemPurgeDays                   int32
+	SwcChangeUseMongoChangeStream          bool
+	// TrustedProxyAddresses lists the ips or cidrs of the proxies whose x-forwarded-for is believed
+	TrustedProxyAddresses []string
 }
 
 var AppConfig Config
@@ -32,6 +43,14 @@ func SetDafaultAppConfig() {
 	AppConfig.MongodbPort = 27017
 	AppConfig.MongodbUser = "defaultuser"
 	AppConfig.MongodbPassword = "defaultpassword"
+	AppConfig.AutoSnapshotIncrementOperationNumber = 1000
+	AppConfig.AutoSnapshotIncrementOperationAgeHours = 24
+	AppConfig.SnapshotDailyRetentionDays = 30
+	AppConfig.SnapshotWeeklyRetentionDays = 0
+	AppConfig.GarbageCollectionRemoveOrphans = false
+	AppConfig.DeletedItemPurgeDays = 30
+	AppConfig.SwcChangeUseMongoChangeStream = false
+	AppConfig.TrustedProxyAddresses = []string{}
 }
 
 func ReadConfig() bool {
@@ -66,6 +85,14 @@ func ReadConfig() bool {
 	logger.GetLogger().Println("MongodbPort:" + strconv.Itoa(int(AppConfig.MongodbPort)))
 	logger.GetLogger().Println("MongodbUser:" + AppConfig.MongodbUser)
 	logger.GetLogger().Println("MongodbPassword:" + AppConfig.MongodbPassword)
+	logger.GetLogger().Println("AutoSnapshotIncrementOperationNumber:" + strconv.Itoa(int(AppConfig.AutoSnapshotIncrementOperationNumber)))
+	logger.GetLogger().Println("AutoSnapshotIncrementOperationAgeHours:" + strconv.Itoa(int(AppConfig.AutoSnapshotIncrementOperationAgeHours)))
+	logger.GetLogger().Println("SnapshotDailyRetentionDays:" + strconv.Itoa(int(AppConfig.SnapshotDailyRetentionDays)))
+	logger.GetLogger().Println("SnapshotWeeklyRetentionDays:" + strconv.Itoa(int(AppConfig.SnapshotWeeklyRetentionDays)))
+	logger.GetLogger().Println("GarbageCollectionRemoveOrphans:" + strconv.FormatBool(AppConfig.GarbageCollectionRemoveOrphans))
+	logger.GetLogger().Println("DeletedItemPurgeDays:" + strconv.Itoa(int(AppConfig.DeletedItemPurgeDays)))
+	logger.GetLogger().Println("SwcChangeUseMongoChangeStream:" + strconv.FormatBool(AppConfig.SwcChangeUseMongoChangeStream))
+	logger.GetLogger().Println("TrustedProxyAddresses:" + strings.Join(AppConfig.TrustedProxyAddresses, ","))
 	logger.GetLogger().Println("ApiVersion:" + ApiVersion)
 	logger.GetLogger().Println("ServerAppVersion:" + ServerAppVersion)
 
@@ -102,6 +102,42 @@ func EnsureUniqueUUIDIndex(collection *mongo.Collection) error {
 	return nil
 }
 
+// EnsureSwcNodeSpatialIndex creates the compound index on the node coordinates the spatial node queries use.
+func EnsureSwcNodeSpatialIndex(collection *mongo.Collection) error {
+	indexName := "SwcData_x_y_z"
+	ctx := context.Background()
+
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var index bson.M
+		if err := cursor.Decode(&index); err != nil {
+			return fmt.Errorf("failed to decode index: %v", err)
+		}
+
+		if indexInfo, ok := index["name"].(string); ok && indexInfo == indexName {
+			return nil
+		}
+	}
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{"SwcData.x", 1}, {"SwcData.y", 1}, {"SwcData.z", 1}},
+		Options: options.Index().SetName(indexName),
+	}
+
+	_, err = collection.Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		return fmt.Errorf("failed to create index: %v", err)
+	}
+
+	logger.GetLogger().Printf("Successfully created index '%s' on node coordinates. Collection Name '%s'", indexName, collection.Name())
+	return nil
+}
+
 func CreateProject(projectMetaInfo dbmodel.ProjectMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	var projectCollection = databaseInfo.MetaInfoDb.Collection(ProjectMetaInfoCollectionString)
 
@@ -155,13 +191,22 @@ func ModifyProject(projectMetaInfo dbmodel.ProjectMetaInfoV1, databaseInfo Mongo
 
 }
 
+// QueryProject finds a project which is not in the recycle bin.
 func QueryProject(projectMetaInfo *dbmodel.ProjectMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return queryProject(projectMetaInfo, bson.M{"uuid": projectMetaInfo.Base.Uuid, "IsDeleted": bson.M{"$ne": true}}, databaseInfo)
+}
+
+func QueryProjectIncludingDeleted(projectMetaInfo *dbmodel.ProjectMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return queryProject(projectMetaInfo, bson.M{"uuid": projectMetaInfo.Base.Uuid}, databaseInfo)
+}
+
+func queryProject(projectMetaInfo *dbmodel.ProjectMetaInfoV1, filter bson.M, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	var projectCollection = databaseInfo.MetaInfoDb.Collection(ProjectMetaInfoCollectionString)
 	_ = EnsureUniqueUUIDIndex(projectCollection)
 
 	result := projectCollection.FindOne(
 		context.TODO(),
-		bson.D{{"uuid", projectMetaInfo.Base.Uuid}})
+		filter)
 
 	if result.Err() != nil {
 		return ReturnWrapper{false, "Cannot find target project!"}
@@ -194,6 +239,71 @@ func QueryAllProject(projectMetaInfoList *[]dbmodel.ProjectMetaInfoV1, databaseI
 	return ReturnWrapper{true, "Query all Project Success"}
 }
 
+func QueryDeletedProject(projectMetaInfoList *[]dbmodel.ProjectMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var projectCollection = databaseInfo.MetaInfoDb.Collection(ProjectMetaInfoCollectionString)
+
+	cursor, err := projectCollection.Find(
+		context.TODO(),
+		bson.M{"IsDeleted": true})
+
+	if err != nil {
+		return ReturnWrapper{false, "Query deleted Project failed!"}
+	}
+
+	if err = cursor.All(context.TODO(), projectMetaInfoList); err != nil {
+		logger.GetLogger().Println(err.Error())
+		return ReturnWrapper{false, "Query deleted Project failed!"}
+	}
+
+	return ReturnWrapper{true, "Query deleted Project Success"}
+}
+
+func PullSwcFromAllProject(swcUuid string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return PullSwcFromAllProjectWithContext(context.TODO(), swcUuid, databaseInfo)
+}
+
+func PullSwcFromAllProjectWithContext(ctx context.Context, swcUuid string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var projectCollection = databaseInfo.MetaInfoDb.Collection(ProjectMetaInfoCollectionString)
+
+	_, err := projectCollection.UpdateMany(ctx, bson.M{"SwcList": swcUuid}, bson.M{"$pull": bson.M{"SwcList": swcUuid}})
+	if err != nil {
+		return ReturnWrapper{false, err.Error()}
+	}
+	return ReturnWrapper{true, "Remove swc " + swcUuid + " from project successfully!"}
+}
+
+// AddSwcToProjectWithContext adds the swc to the SwcList of the project unless it is already there. found is false
+// when there is no such project.
+func AddSwcToProjectWithContext(ctx context.Context, projectUuid string, swcUuid string, found *bool, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var projectCollection = databaseInfo.MetaInfoDb.Collection(ProjectMetaInfoCollectionString)
+
+	result, err := projectCollection.UpdateOne(ctx, bson.M{"uuid": projectUuid}, bson.M{"$addToSet": bson.M{"SwcList": swcUuid}})
+	if err != nil {
+		return ReturnWrapper{false, "Add swc " + swcUuid + " to project failed! Error:" + err.Error()}
+	}
+	*found = result.MatchedCount != 0
+	return ReturnWrapper{true, "Add swc " + swcUuid + " to project successfully!"}
+}
+
+// SetProjectDeleted moves the project into the recycle bin or back out of it. Only the recycle bin fields are
+// written, so changes made to the project meanwhile are kept.
+func SetProjectDeleted(projectUuid string, isDeleted bool, deletedBy string, deleteTime time.Time, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var projectCollection = databaseInfo.MetaInfoDb.Collection(ProjectMetaInfoCollectionString)
+
+	result, err := projectCollection.UpdateOne(context.TODO(), bson.M{"uuid": projectUuid}, bson.M{"$set": bson.M{
+		"IsDeleted":  isDeleted,
+		"DeletedBy":  deletedBy,
+		"DeleteTime": deleteTime,
+	}})
+	if err != nil {
+		return ReturnWrapper{false, "Update project info failed! Error:" + err.Error()}
+	}
+	if result.MatchedCount == 0 {
+		return ReturnWrapper{false, "Update project info failed! Project " + projectUuid + " not found!"}
+	}
+	return ReturnWrapper{true, "Update project info success!"}
+}
+
 func CreateUser(userMetaInfo dbmodel.UserMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	var userCollection = databaseInfo.MetaInfoDb.Collection(UserMetaInfoCollectionString)
 
@@ -446,10 +556,14 @@ func CreateSwc(swcMetaInfo dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseIn
 }
 
 func DeleteSwc(swcMetaInfo dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return DeleteSwcWithContext(context.TODO(), swcMetaInfo, databaseInfo)
+}
+
+func DeleteSwcWithContext(ctx context.Context, swcMetaInfo dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
-	_ = EnsureUniqueUUIDIndex(swcCollection)
+	_ = ensureUniqueUUIDIndex(ctx, swcCollection)
 
-	result := swcCollection.FindOneAndDelete(context.TODO(), bson.D{
+	result := swcCollection.FindOneAndDelete(ctx, bson.D{
 		{"uuid", swcMetaInfo.Base.Uuid},
 	})
 
@@ -461,13 +575,29 @@ func DeleteSwc(swcMetaInfo dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseIn
 }
 
 func ModifySwc(swcMetaInfo dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return ModifySwcWithContext(context.TODO(), swcMetaInfo, databaseInfo)
+}
+
+func ModifySwcWithContext(ctx context.Context, swcMetaInfo dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
-	_ = EnsureUniqueUUIDIndex(swcCollection)
+	_ = ensureUniqueUUIDIndex(ctx, swcCollection)
 
-	result := swcCollection.FindOneAndReplace(
-		context.TODO(),
-		bson.D{{"uuid", swcMetaInfo.Base.Uuid}},
-		swcMetaInfo)
+	// the revision is only advanced by IncreaseSwcRevision, writing back a meta info read earlier must not roll it back
+	rawSwcMetaInfo, err := bson.Marshal(swcMetaInfo)
+	if err != nil {
+		return ReturnWrapper{false, "Update swc failed! Error:" + err.Error()}
+	}
+	var updateFields bson.M
+	if err = bson.Unmarshal(rawSwcMetaInfo, &updateFields); err != nil {
+		return ReturnWrapper{false, "Update swc failed! Error:" + err.Error()}
+	}
+	delete(updateFields, "_id")
+	delete(updateFields, "Revision")
+
+	result := swcCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"uuid": swcMetaInfo.Base.Uuid},
+		bson.M{"$set": updateFields})
 
 	if result.Err() != nil {
 		return ReturnWrapper{false, "Update swc failed! Error:" + result.Err().Error()}
@@ -477,13 +607,79 @@ func ModifySwc(swcMetaInfo dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseIn
 
 }
 
+// SetSwcDeletedWithContext moves the swc into the recycle bin or back out of it. Only the recycle bin fields are
+// written, so snapshots and increment operation lists added meanwhile are kept.
+func SetSwcDeletedWithContext(ctx context.Context, swcUuid string, isDeleted bool, deletedBy string, deleteTime time.Time, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	result, err := swcCollection.UpdateOne(ctx, bson.M{"uuid": swcUuid}, bson.M{"$set": bson.M{
+		"IsDeleted":  isDeleted,
+		"DeletedBy":  deletedBy,
+		"DeleteTime": deleteTime,
+	}})
+	if err != nil {
+		return ReturnWrapper{false, "Update swc failed! Error:" + err.Error()}
+	}
+	if result.MatchedCount == 0 {
+		return ReturnWrapper{false, "Update swc failed! Swc " + swcUuid + " not found!"}
+	}
+	return ReturnWrapper{true, "Update swc success!"}
+}
+
+// SetSwcSnapshotLabel sets the label of one snapshot of the swc, leaving the rest of the swc meta info untouched.
+func SetSwcSnapshotLabel(swcUuid string, snapshotCollectionName string, label string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return setSwcSnapshotField(swcUuid, snapshotCollectionName, "Label", label, databaseInfo)
+}
+
+// SetSwcSnapshotProtected sets whether the snapshot policy may prune one snapshot of the swc.
+func SetSwcSnapshotProtected(swcUuid string, snapshotCollectionName string, protected bool, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return setSwcSnapshotField(swcUuid, snapshotCollectionName, "Protected", protected, databaseInfo)
+}
+
+func setSwcSnapshotField(swcUuid string, snapshotCollectionName string, field string, value interface{}, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	filter := bson.M{"uuid": swcUuid, "SwcSnapshotList.SwcSnapshotCollectionName": snapshotCollectionName}
+	update := bson.M{"$set": bson.M{"SwcSnapshotList.$[s]." + field: value}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"s.SwcSnapshotCollectionName": snapshotCollectionName}},
+	})
+	result, err := swcCollection.UpdateOne(context.TODO(), filter, update, opts)
+	if err != nil {
+		return ReturnWrapper{false, "Update swc snapshot failed! Error:" + err.Error()}
+	}
+	if result.MatchedCount == 0 {
+		return ReturnWrapper{false, "Cannot find snapshot " + snapshotCollectionName + " in swc " + swcUuid}
+	}
+	return ReturnWrapper{true, "Update swc snapshot success!"}
+}
+
+// SetSwcBelongingProjectWithContext sets the project the swc belongs to, "" for a free swc.
+func SetSwcBelongingProjectWithContext(ctx context.Context, swcUuid string, projectUuid string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	if _, err := swcCollection.UpdateOne(ctx, bson.M{"uuid": swcUuid}, bson.M{"$set": bson.M{"BelongingProjectUuid": projectUuid}}); err != nil {
+		return ReturnWrapper{false, "Update swc failed! Error:" + err.Error()}
+	}
+	return ReturnWrapper{true, "Update swc success!"}
+}
+
+// QuerySwc finds a swc which is not in the recycle bin.
 func QuerySwc(swcMetaInfo *dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return querySwc(swcMetaInfo, bson.M{"uuid": swcMetaInfo.Base.Uuid, "IsDeleted": bson.M{"$ne": true}}, databaseInfo)
+}
+
+func QuerySwcIncludingDeleted(swcMetaInfo *dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return querySwc(swcMetaInfo, bson.M{"uuid": swcMetaInfo.Base.Uuid}, databaseInfo)
+}
+
+func querySwc(swcMetaInfo *dbmodel.SwcMetaInfoV1, filter bson.M, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
 	_ = EnsureUniqueUUIDIndex(swcCollection)
 
 	result := swcCollection.FindOne(
 		context.TODO(),
-		bson.D{{"uuid", swcMetaInfo.Base.Uuid}})
+		filter)
 
 	if result.Err() != nil {
 		return ReturnWrapper{false, "Cannot find target swc!"}
@@ -516,12 +712,31 @@ func QueryAllSwc(swcMetaInfoList *[]dbmodel.SwcMetaInfoV1, databaseInfo MongoDbD
 	return ReturnWrapper{true, "Query all swc Success"}
 }
 
+func QueryDeletedSwc(swcMetaInfoList *[]dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	cursor, err := swcCollection.Find(
+		context.TODO(),
+		bson.M{"IsDeleted": true})
+
+	if err != nil {
+		return ReturnWrapper{false, "Query deleted swc failed!"}
+	}
+
+	if err = cursor.All(context.TODO(), swcMetaInfoList); err != nil {
+		logger.GetLogger().Println(err.Error())
+		return ReturnWrapper{false, "Query deleted swc failed!"}
+	}
+
+	return ReturnWrapper{true, "Query deleted swc Success"}
+}
+
 func QueryAllFreeSwc(swcMetaInfoList *[]dbmodel.SwcMetaInfoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
 
 	cursor, err := swcCollection.Find(
 		context.TODO(),
-		bson.M{"BelongingProjectUuid": ""})
+		bson.M{"BelongingProjectUuid": "", "IsDeleted": bson.M{"$ne": true}})
 
 	if err != nil {
 		return ReturnWrapper{false, "Query all free swc failed!"}
@@ -630,6 +845,10 @@ func QueryAllDailyStatistics(dailyStatisticsList *[]dbmodel.DailyStatisticsMetaI
 }
 
 func CreateSwcData(swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return CreateSwcDataWithContext(context.TODO(), swcUuid, swcData, databaseInfo)
+}
+
+func CreateSwcDataWithContext(ctx context.Context, swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	collection := databaseInfo.SwcDb.Collection(swcUuid)
 
 	var interfaceSlice []interface{}
@@ -637,7 +856,7 @@ func CreateSwcData(swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo Mong
 		interfaceSlice = append(interfaceSlice, v)
 	}
 	logger.GetLogger().Println("Inserting ", len(interfaceSlice), " nodes into ", swcUuid)
-	result, err := collection.InsertMany(context.TODO(), interfaceSlice)
+	result, err := collection.InsertMany(ctx, interfaceSlice)
 	if err != nil {
 		if result != nil {
 			return ReturnWrapper{false,
@@ -665,8 +884,12 @@ func DeleteSwcDataCollection(swcUuid string, databaseInfo MongoDbDataBaseInfo) R
 }
 
 func DeleteSwcData(swcUuid string, swcData dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return DeleteSwcDataWithContext(context.TODO(), swcUuid, swcData, databaseInfo)
+}
+
+func DeleteSwcDataWithContext(ctx context.Context, swcUuid string, swcData dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	collection := databaseInfo.SwcDb.Collection(swcUuid)
-	_ = EnsureUniqueUUIDIndex(collection)
+	_ = ensureUniqueUUIDIndex(ctx, collection)
 
 	uuidList := bson.A{}
 
@@ -680,7 +903,7 @@ func DeleteSwcData(swcUuid string, swcData dbmodel.SwcDataV1, databaseInfo Mongo
 	}
 
 	//// Check if all nodes exist
-	//count, err := collection.CountDocuments(context.TODO(), filterInterface)
+	//count, err := collection.CountDocuments(ctx, filterInterface)
 	//if err != nil {
 	//	return ReturnWrapper{false, "Failed to count nodes: " + err.Error()}
 	//}
@@ -690,7 +913,7 @@ func DeleteSwcData(swcUuid string, swcData dbmodel.SwcDataV1, databaseInfo Mongo
 	//}
 
 	logger.GetLogger().Println("Delete ", len(filterInterface), " nodes at ", swcUuid)
-	result, err := collection.DeleteMany(context.TODO(), filterInterface)
+	result, err := collection.DeleteMany(ctx, filterInterface)
 	if err != nil {
 		logger.GetLogger().Println(err.Error())
 		if result != nil {
@@ -705,102 +928,16 @@ func DeleteSwcData(swcUuid string, swcData dbmodel.SwcDataV1, databaseInfo Mongo
 
 	logger.GetLogger().Println("Real Delete nodes in DB: " + strconv.Itoa(int(result.DeletedCount)))
 
-	//logger.GetLogger().Println("Start adjuest n and parent")
-
-	//// adjust remaining node's n parent
-	//cur, err := collection.Find(context.TODO(), bson.D{{}})
-	//if err != nil {
-	//	logger.GetLogger().Fatal(err)
-	//}
-	//
-	//var lastNode *dbmodel.SwcNodeDataV1
-	//counter := 1
-	//
-	//// Prepare a slice to hold the write models for the bulk operation
-	//var writes []mongo.WriteModel
-	//
-	//logger.GetLogger().Println("For loop start")
-	//
-	//// Iterate over the cursor and update the documents
-	//for cur.Next(context.TODO()) {
-	//	var node dbmodel.SwcNodeDataV1
-	//	err := cur.Decode(&node)
-	//	if err != nil {
-	//		logger.GetLogger().Fatal(err)
-	//	}
-	//
-	//	// Update the node
-	//	node.SwcNodeInternalData.N = int32(counter)
-	//	counter++
-	//
-	//	// Update the current node's n
-	//	update := bson.D{
-	//		{"$set", bson.D{
-	//			{"SwcData.n", node.SwcNodeInternalData.N},
-	//		}},
-	//	}
-	//	model := mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": node.Base.Id}).SetUpdate(update)
-	//	writes = append(writes, model)
-	//
-	//	// Update the last node's parent to the current node's n
-	//	if lastNode != nil {
-	//		if lastNode.SwcNodeInternalData.Parent != -1 {
-	//			update1 := bson.D{
-	//				{"$set", bson.D{
-	//					{"SwcData.parent", node.SwcNodeInternalData.N},
-	//				}},
-	//			}
-	//			model1 := mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": lastNode.Base.Id}).SetUpdate(update1)
-	//			writes = append(writes, model1)
-	//		}
-	//	}
-	//
-	//	// Save the current node for the next iteration
-	//	lastNode = &node
-	//}
-	//
-	//logger.GetLogger().Println("For loop end")
-	//
-	//// Update the last node's parent to -1
-	//if lastNode != nil {
-	//	logger.GetLogger().Println("lastNode != nil")
-	//	update := bson.D{
-	//		{"$set", bson.D{
-	//			{"SwcData.parent", -1},
-	//		}},
-	//	}
-	//	model := mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": lastNode.Base.Id}).SetUpdate(update)
-	//	writes = append(writes, model)
-	//}
-	//
-	//logger.GetLogger().Println("Execute the bulk operation")
-	//
-	//if len(writes) == 0 {
-	//	return ReturnWrapper{true, "Delete many node Success! BulkWrite is empty."}
-	//}
-	//
-	//// Execute the bulk operation
-	//_, err = collection.BulkWrite(context.TODO(), writes)
-	//if err != nil {
-	//	logger.GetLogger().Println("Execute the bulk operation Failed")
-	//	logger.GetLogger().Fatal(err)
-	//}
-	//
-	//logger.GetLogger().Println("Execute the bulk operation Success")
-	//
-	//// Close the cursor
-	//if cur != nil {
-	//	_ = cur.Close(context.TODO())
-	//}
-	//
-	//logger.GetLogger().Println("Close the cursor And Return")
-
 	return ReturnWrapper{true, "Delete many node Success! BulkWrite is not empty."}
 }
 
 func ModifySwcData(swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return ModifySwcDataWithContext(context.TODO(), swcUuid, swcData, databaseInfo)
+}
+
+func ModifySwcDataWithContext(ctx context.Context, swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	collection := databaseInfo.SwcDb.Collection(swcUuid)
-	_ = EnsureUniqueUUIDIndex(collection)
+	_ = ensureUniqueUUIDIndex(ctx, collection)
 
 	logger.GetLogger().Printf("Modifying %d nodes at %s", len(*swcData), swcUuid)
 
@@ -844,7 +981,7 @@ func ModifySwcData(swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo Mong
 
 	// 执行批量写入操作
 	opts := options.BulkWrite().SetOrdered(false)
-	result, err := collection.BulkWrite(context.TODO(), operations, opts)
+	result, err := collection.BulkWrite(ctx, operations, opts)
 	if err != nil {
 		logger.GetLogger().Printf("Bulk write error: %v", err)
 		return ReturnWrapper{false, "Modify swc node failed! Error during bulk write."}
@@ -934,14 +1071,18 @@ func QuerySwcDataByUserAndTime(
 }
 
 func QueryAllSwcData(swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return QueryAllSwcDataWithContext(context.TODO(), swcUuid, swcData, databaseInfo)
+}
+
+func QueryAllSwcDataWithContext(ctx context.Context, swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	collection := databaseInfo.SwcDb.Collection(swcUuid)
 
-	cursor, err := collection.Find(context.TODO(), bson.D{})
+	cursor, err := collection.Find(ctx, bson.D{})
 	if err != nil {
 		return ReturnWrapper{false, "Query many node failed!"}
 	}
 
-	if err = cursor.All(context.TODO(), swcData); err != nil {
+	if err = cursor.All(ctx, swcData); err != nil {
 		return ReturnWrapper{false, "Query many node failed!"}
 	}
 
@@ -951,23 +1092,51 @@ func QueryAllSwcData(swcUuid string, swcData *dbmodel.SwcDataV1, databaseInfo Mo
 }
 
 func CreateSnapshot(swcUuid string, snapshotName string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return CreateSnapshotWithContext(context.TODO(), swcUuid, snapshotName, databaseInfo)
+}
+
+func CreateSnapshotWithContext(ctx context.Context, swcUuid string, snapshotName string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	srcCollection := databaseInfo.SwcDb.Collection(swcUuid)
 	dstCollection := databaseInfo.SnapshotDb.Collection(snapshotName)
 
-	cursor, err := srcCollection.Find(context.Background(), bson.D{{}})
+	if result := CopyCollectionWithContext(ctx, srcCollection, dstCollection); !result.Status {
+		return result
+	}
+
+	return ReturnWrapper{true, "Create Snapshot Success!"}
+}
+
+// CopyCollection inserts every document of srcCollection into dstCollection in batches, documents are copied as is
+// including _id.
+func CopyCollection(srcCollection *mongo.Collection, dstCollection *mongo.Collection) ReturnWrapper {
+	return CopyCollectionWithContext(context.TODO(), srcCollection, dstCollection)
+}
+
+func CopyCollectionWithContext(ctx context.Context, srcCollection *mongo.Collection, dstCollection *mongo.Collection) ReturnWrapper {
+	return copyCollectionDocuments(ctx, srcCollection, dstCollection, func(cursor *mongo.Cursor) (interface{}, error) {
+		var result bson.D
+		err := cursor.Decode(&result)
+		return result, err
+	})
+}
+
+// copyCollectionDocuments inserts every document of srcCollection into dstCollection in batches, decode reads the
+// current document of the cursor and returns what is inserted for it.
+func copyCollectionDocuments(ctx context.Context, srcCollection *mongo.Collection, dstCollection *mongo.Collection, decode func(cursor *mongo.Cursor) (interface{}, error)) ReturnWrapper {
+	cursor, err := srcCollection.Find(ctx, bson.M{})
 	if err != nil {
 		return ReturnWrapper{
 			Status:  false,
 			Message: err.Error(),
 		}
 	}
+	defer cursor.Close(ctx)
 
 	var results []interface{}
 	batchSize := 100000
 
-	for cursor.Next(context.Background()) {
-		var result bson.D
-		err := cursor.Decode(&result)
+	for cursor.Next(ctx) {
+		result, err := decode(cursor)
 		if err != nil {
 			return ReturnWrapper{
 				Status:  false,
@@ -977,7 +1146,7 @@ func CreateSnapshot(swcUuid string, snapshotName string, databaseInfo MongoDbDat
 		results = append(results, result)
 
 		if len(results) >= batchSize {
-			_, err = dstCollection.InsertMany(context.Background(), results)
+			_, err = dstCollection.InsertMany(ctx, results)
 			if err != nil {
 				return ReturnWrapper{
 					Status:  false,
@@ -990,7 +1159,7 @@ func CreateSnapshot(swcUuid string, snapshotName string, databaseInfo MongoDbDat
 
 	// 插入剩余的文档
 	if len(results) > 0 {
-		_, err = dstCollection.InsertMany(context.Background(), results)
+		_, err = dstCollection.InsertMany(ctx, results)
 		if err != nil {
 			return ReturnWrapper{
 				Status:  false,
@@ -999,13 +1168,77 @@ func CreateSnapshot(swcUuid string, snapshotName string, databaseInfo MongoDbDat
 		}
 	}
 
-	return ReturnWrapper{true, "Create Snapshot Success!"}
+	return ReturnWrapper{true, "Copy collection success!"}
+}
+
+func copySwcNodes(srcCollection *mongo.Collection, dstCollection *mongo.Collection, mapNode func(node *dbmodel.SwcNodeDataV1)) ReturnWrapper {
+	return copyCollectionDocuments(context.TODO(), srcCollection, dstCollection, func(cursor *mongo.Cursor) (interface{}, error) {
+		var node dbmodel.SwcNodeDataV1
+		if err := cursor.Decode(&node); err != nil {
+			return nil, err
+		}
+		mapNode(&node)
+		return node, nil
+	})
+}
+
+// CopySwcData copies the nodes of srcSwcUuid to dstSwcUuid, mapNode is called on every node before it is inserted.
+func CopySwcData(srcSwcUuid string, dstSwcUuid string, mapNode func(node *dbmodel.SwcNodeDataV1), databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return copySwcNodes(databaseInfo.SwcDb.Collection(srcSwcUuid), databaseInfo.SwcDb.Collection(dstSwcUuid), mapNode)
+}
+
+// CopySwcSnapshot copies the nodes of a snapshot, mapNode is called on every node before it is inserted.
+func CopySwcSnapshot(srcSnapshotName string, dstSnapshotName string, mapNode func(node *dbmodel.SwcNodeDataV1), databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return copySwcNodes(databaseInfo.SnapshotDb.Collection(srcSnapshotName), databaseInfo.SnapshotDb.Collection(dstSnapshotName), mapNode)
+}
+
+// SwcIncrementOperationCopyPrefix names the collections an increment operation list is copied into before it is
+// renamed to its destination, WatchSwcIncrementOperation skips the inserts into them.
+const SwcIncrementOperationCopyPrefix = "Copying_"
+
+// CopySwcIncrementOperation copies the increment operations into a staging collection and renames it to
+// dstCollectionName, so the copied operations are not seen as new ones by WatchSwcIncrementOperation. mapOperation is
+// called on every operation before it is inserted.
+func CopySwcIncrementOperation(srcCollectionName string, dstCollectionName string, mapOperation func(operation *dbmodel.SwcIncrementOperationV1), databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	stagingCollectionName := SwcIncrementOperationCopyPrefix + dstCollectionName
+	result := copyCollectionDocuments(context.TODO(), databaseInfo.IncrementOperationDb.Collection(srcCollectionName), databaseInfo.IncrementOperationDb.Collection(stagingCollectionName), func(cursor *mongo.Cursor) (interface{}, error) {
+		var operation dbmodel.SwcIncrementOperationV1
+		if err := cursor.Decode(&operation); err != nil {
+			return nil, err
+		}
+		mapOperation(&operation)
+		return operation, nil
+	})
+	if !result.Status {
+		_ = databaseInfo.IncrementOperationDb.Collection(stagingCollectionName).Drop(context.TODO())
+		return result
+	}
+
+	databaseName := databaseInfo.IncrementOperationDb.Name()
+	command := bson.D{
+		{Key: "renameCollection", Value: databaseName + "." + stagingCollectionName},
+		{Key: "to", Value: databaseName + "." + dstCollectionName},
+	}
+	if err := databaseInfo.IncrementOperationDb.Client().Database("admin").RunCommand(context.TODO(), command).Err(); err != nil {
+		_ = databaseInfo.IncrementOperationDb.Collection(stagingCollectionName).Drop(context.TODO())
+		return ReturnWrapper{false, "Rename increment operation collection " + stagingCollectionName + " failed! Error:" + err.Error()}
+	}
+
+	return ReturnWrapper{true, "Copy increment operation success!"}
+}
+
+func CopyAttachment(srcCollectionName string, dstCollectionName string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return CopyCollection(databaseInfo.AttachmentDb.Collection(srcCollectionName), databaseInfo.AttachmentDb.Collection(dstCollectionName))
 }
 
 func CreateIncrementOperation(incrementOperationCollectionName string, operation dbmodel.SwcIncrementOperationV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return CreateIncrementOperationWithContext(context.TODO(), incrementOperationCollectionName, operation, databaseInfo)
+}
+
+func CreateIncrementOperationWithContext(ctx context.Context, incrementOperationCollectionName string, operation dbmodel.SwcIncrementOperationV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	currentCollection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
 
-	_, err := currentCollection.InsertOne(context.TODO(), operation)
+	_, err := currentCollection.InsertOne(ctx, operation)
 	if err != nil {
 		return ReturnWrapper{false, err.Error()}
 	}
@@ -1029,51 +1262,312 @@ func QuerySwcSnapshot(snapshotName string, swcData *dbmodel.SwcDataV1, databaseI
 }
 
 func QuerySwcIncrementOperation(incrementOperationCollectionName string, operations *dbmodel.SwcIncrementOperationListV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return QuerySwcIncrementOperationWithContext(context.TODO(), incrementOperationCollectionName, operations, databaseInfo)
+}
+
+func QuerySwcIncrementOperationWithContext(ctx context.Context, incrementOperationCollectionName string, operations *dbmodel.SwcIncrementOperationListV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
 
-	cursor, err := collection.Find(context.TODO(), bson.D{})
+	cursor, err := collection.Find(ctx, bson.D{})
 	if err != nil {
 		return ReturnWrapper{false, "Query many node failed!"}
 	}
 
-	if err = cursor.All(context.TODO(), operations); err != nil {
+	if err = cursor.All(ctx, operations); err != nil {
 		return ReturnWrapper{false, "Query many node failed!"}
 	}
 
 	return ReturnWrapper{true, "Query many node Success"}
 }
 
-func CreateSwcAttachmentAno(swcUuid string, anoAttachment *dbmodel.SwcAttachmentAnoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
-	attachmentCollection := "Attachment_Ano_" + swcUuid
-	collection := databaseInfo.AttachmentDb.Collection(attachmentCollection)
-	_ = collection.Drop(context.TODO())
-	_, err := collection.InsertOne(context.TODO(), anoAttachment)
-	if err != nil {
-		return ReturnWrapper{false, err.Error()}
+func swcIncrementOperationFilterQuery(filter SwcIncrementOperationFilter, after *SwcIncrementOperationPosition) bson.D {
+	query := bson.D{}
+	for _, field := range []bson.E{
+		{Key: "CreatorUuid", Value: filter.CreatorUuid},
+		{Key: "ClientAddress", Value: filter.ClientAddress},
+		{Key: "DeviceType", Value: filter.DeviceType},
+		{Key: "ApiVersion", Value: filter.ApiVersion},
+		{Key: "Creator", Value: filter.Creator},
+	} {
+		if field.Value != "" {
+			query = append(query, field)
+		}
+	}
+	if len(filter.IncrementOperations) != 0 {
+		query = append(query, bson.E{Key: "IncrementOperation", Value: bson.M{"$in": filter.IncrementOperations}})
 	}
 
-	return ReturnWrapper{true, "Create Ano Attachment successfully!"}
+	createTime := bson.M{}
+	if !filter.StartTime.IsZero() {
+		createTime["$gte"] = filter.StartTime
+	}
+	if !filter.EndTime.IsZero() {
+		createTime["$lt"] = filter.EndTime
+	}
+	if len(createTime) != 0 {
+		query = append(query, bson.E{Key: "CreateTime", Value: createTime})
+	}
+
+	if after != nil {
+		query = append(query, bson.E{Key: "$or", Value: bson.A{
+			bson.M{"Revision": bson.M{"$gt": after.Revision}},
+			bson.M{"Revision": after.Revision, "_id": bson.M{"$gt": after.Id}},
+		}})
+	}
+	return query
 }
 
-func DeleteSwcAttachmentAno(swcUuid string, attachmentUuid string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
-	attachmentCollection := "Attachment_Ano_" + swcUuid
-	collection := databaseInfo.AttachmentDb.Collection(attachmentCollection)
+// QuerySwcIncrementOperationByFilter queries at most limit increment operations matching filter which come after the
+// position after, by revision and then by insertion. A limit of 0 returns all of them.
+func QuerySwcIncrementOperationByFilter(incrementOperationCollectionName string, filter SwcIncrementOperationFilter, after *SwcIncrementOperationPosition, limit int64, operations *dbmodel.SwcIncrementOperationListV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
 
-	result := collection.FindOneAndDelete(context.TODO(), bson.D{
-		{"uuid", attachmentUuid}})
+	findOptions := options.Find().SetSort(bson.D{{Key: "Revision", Value: 1}, {Key: "_id", Value: 1}})
+	if limit > 0 {
+		findOptions.SetLimit(limit)
+	}
+	cursor, err := collection.Find(context.TODO(), swcIncrementOperationFilterQuery(filter, after), findOptions)
+	if err != nil {
+		return ReturnWrapper{false, "Query many node failed!"}
+	}
 
-	_ = collection.Drop(context.Background())
+	if err = cursor.All(context.TODO(), operations); err != nil {
+		return ReturnWrapper{false, "Query many node failed!"}
+	}
 
-	if result.Err() != nil {
-		return ReturnWrapper{false, result.Err().Error()}
-	} else {
-		return ReturnWrapper{true, "Delete Ano Attachment successfully!"}
+	return ReturnWrapper{true, "Query many node Success"}
+}
+
+// IterateSwcIncrementOperationByFilter hands the increment operations matching filter to handle one by one in the
+// same order as QuerySwcIncrementOperationByFilter, without loading them all at once. It stops when handle returns
+// false or ctx is done.
+func IterateSwcIncrementOperationByFilter(ctx context.Context, incrementOperationCollectionName string, filter SwcIncrementOperationFilter, handle func(operation dbmodel.SwcIncrementOperationV1) bool, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "Revision", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, swcIncrementOperationFilterQuery(filter, nil), findOptions)
+	if err != nil {
+		return ReturnWrapper{false, "Query increment operation failed! " + err.Error()}
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(ctx) {
+		var operation dbmodel.SwcIncrementOperationV1
+		if err = cursor.Decode(&operation); err != nil {
+			return ReturnWrapper{false, "Decode increment operation failed! " + err.Error()}
+		}
+		if !handle(operation) {
+			return ReturnWrapper{false, "Iterate increment operation stopped!"}
+		}
 	}
+	if err = cursor.Err(); err != nil {
+		return ReturnWrapper{false, "Query increment operation failed! " + err.Error()}
+	}
+
+	return ReturnWrapper{true, "Iterate increment operation successfully!"}
 }
 
-func UpdateSwcAttachmentAno(swcUuid string, attachmentUuid string, anoAttachment *dbmodel.SwcAttachmentAnoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
-	attachmentCollection := "Attachment_Ano_" + swcUuid
-	collection := databaseInfo.AttachmentDb.Collection(attachmentCollection)
+func CountSwcIncrementOperation(incrementOperationCollectionName string, count *int64, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
+
+	number, err := collection.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		return ReturnWrapper{false, err.Error()}
+	}
+	*count = number
+
+	return ReturnWrapper{true, "Count increment operation success!"}
+}
+
+func DeleteSwcSnapshot(snapshotName string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.SnapshotDb.Collection(snapshotName)
+
+	err := collection.Drop(context.TODO())
+	if err != nil {
+		return ReturnWrapper{false, err.Error()}
+	}
+	return ReturnWrapper{true, "Delete snapshot " + snapshotName + " successfully!"}
+}
+
+func DeleteSwcIncrementOperationCollection(incrementOperationCollectionName string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
+
+	err := collection.Drop(context.TODO())
+	if err != nil {
+		return ReturnWrapper{false, err.Error()}
+	}
+	return ReturnWrapper{true, "Delete increment operation collection " + incrementOperationCollectionName + " successfully!"}
+}
+
+// QuerySwcCurrentIncrementOperationWithContext reads the current increment operation collection name of the swc. Read
+// it inside the transaction which records to it, so a list rotated meanwhile is not written to.
+func QuerySwcCurrentIncrementOperationWithContext(ctx context.Context, swcUuid string, incrementOperationCollectionName *string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	var swcMetaInfo dbmodel.SwcMetaInfoV1
+	opts := options.FindOne().SetProjection(bson.M{"CurrentIncrementOperationCollectionName": 1})
+	if err := swcCollection.FindOne(ctx, bson.M{"uuid": swcUuid}, opts).Decode(&swcMetaInfo); err != nil {
+		return ReturnWrapper{false, "Query swc current increment operation failed! Error:" + err.Error()}
+	}
+	*incrementOperationCollectionName = swcMetaInfo.CurrentIncrementOperationCollectionName
+
+	return ReturnWrapper{true, "Query swc current increment operation success!"}
+}
+
+// AddSwcSnapshotWithContext appends the snapshot and the increment operation list starting from it to the swc meta
+// info and makes the list current. Only these fields are written, so concurrent edits of the other meta info fields
+// are kept. The swc must still be at revision with previousIncrementOperationCollectionName as current list, the
+// state the snapshot was taken from.
+func AddSwcSnapshotWithContext(ctx context.Context, swcUuid string, snapshot dbmodel.SwcSnapshotMetaInfoV1, incrementOperation dbmodel.SwcIncrementOperationMetaInfoV1, previousIncrementOperationCollectionName string, revision int64, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	filter := bson.M{
+		"uuid":     swcUuid,
+		"Revision": revision,
+		"CurrentIncrementOperationCollectionName": previousIncrementOperationCollectionName,
+	}
+	update := bson.M{
+		"$push": bson.M{
+			"SwcSnapshotList":           snapshot,
+			"SwcIncrementOperationList": incrementOperation,
+		},
+		"$set": bson.M{"CurrentIncrementOperationCollectionName": incrementOperation.IncrementOperationCollectionName},
+	}
+	result, err := swcCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return ReturnWrapper{false, "Add swc snapshot failed! Error:" + err.Error()}
+	}
+	if result.MatchedCount == 0 {
+		return ReturnWrapper{false, "Add swc snapshot failed! Swc " + swcUuid + " has changed since revision " + strconv.FormatInt(revision, 10)}
+	}
+
+	return ReturnWrapper{true, "Add swc snapshot success!"}
+}
+
+// SetSwcVersionControlWithContext replaces the snapshot list, the increment operation lists, the current list and the
+// removed history of the swc, as a revert to an earlier time does, leaving every other field as it is. The current
+// increment operation list must still be previousIncrementOperationCollectionName.
+func SetSwcVersionControlWithContext(ctx context.Context, swcMetaInfo dbmodel.SwcMetaInfoV1, previousIncrementOperationCollectionName string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	// later snapshots push to these lists, they must not be stored as null
+	update := bson.M{
+		"$set": bson.M{
+			"SwcSnapshotList":                         append([]dbmodel.SwcSnapshotMetaInfoV1{}, swcMetaInfo.SwcSnapshotList...),
+			"SwcIncrementOperationList":               append([]dbmodel.SwcIncrementOperationMetaInfoV1{}, swcMetaInfo.SwcIncrementOperationList...),
+			"CurrentIncrementOperationCollectionName": swcMetaInfo.CurrentIncrementOperationCollectionName,
+			"SwcRemovedHistoryList":                   append([]dbmodel.SwcHistoryRangeV1{}, swcMetaInfo.SwcRemovedHistoryList...),
+		},
+	}
+	filter := bson.M{"uuid": swcMetaInfo.Base.Uuid, "CurrentIncrementOperationCollectionName": previousIncrementOperationCollectionName}
+	result, err := swcCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return ReturnWrapper{false, "Set swc version control failed! Error:" + err.Error()}
+	}
+	if result.MatchedCount == 0 {
+		return ReturnWrapper{false, "Set swc version control failed! The current increment operation list of swc " + swcMetaInfo.Base.Uuid + " has changed"}
+	}
+
+	return ReturnWrapper{true, "Set swc version control success!"}
+}
+
+// DeleteSwcIncrementOperationAfterWithContext removes the operations of the list recorded after endTime.
+func DeleteSwcIncrementOperationAfterWithContext(ctx context.Context, incrementOperationCollectionName string, endTime time.Time, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
+	if _, err := collection.DeleteMany(ctx, bson.M{"CreateTime": bson.M{"$gt": primitive.NewDateTimeFromTime(endTime)}}); err != nil {
+		return ReturnWrapper{false, "Delete increment operations after " + endTime.String() + " failed! Error:" + err.Error()}
+	}
+	return ReturnWrapper{true, "Delete increment operations after " + endTime.String() + " success!"}
+}
+
+// RemoveSwcSnapshotsWithContext pulls the given snapshots and increment operation lists from the swc meta info and
+// records the time ranges they covered in SwcRemovedHistoryList, leaving every other field as it is. The current increment operation list must still be
+// currentIncrementOperationCollectionName, the list the removal was chosen for.
+func RemoveSwcSnapshotsWithContext(ctx context.Context, swcUuid string, snapshotNames []string, incrementOperationCollectionNames []string, removedHistory []dbmodel.SwcHistoryRangeV1, currentIncrementOperationCollectionName string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	// $push fails on a list written as null by an older meta info
+	_, err := swcCollection.UpdateOne(ctx,
+		bson.M{"uuid": swcUuid, "SwcRemovedHistoryList": bson.M{"$type": "null"}},
+		bson.M{"$set": bson.M{"SwcRemovedHistoryList": bson.A{}}})
+	if err != nil {
+		return ReturnWrapper{false, "Remove swc snapshots failed! Error:" + err.Error()}
+	}
+
+	// $in and $each need an array, a nil slice would be stored as null
+	snapshotNames = append([]string{}, snapshotNames...)
+	incrementOperationCollectionNames = append([]string{}, incrementOperationCollectionNames...)
+	removedHistory = append([]dbmodel.SwcHistoryRangeV1{}, removedHistory...)
+	update := bson.M{
+		"$pull": bson.M{
+			"SwcSnapshotList":           bson.M{"SwcSnapshotCollectionName": bson.M{"$in": snapshotNames}},
+			"SwcIncrementOperationList": bson.M{"IncrementOperationCollectionName": bson.M{"$in": incrementOperationCollectionNames}},
+		},
+		"$push": bson.M{
+			"SwcRemovedHistoryList": bson.M{"$each": removedHistory},
+		},
+	}
+	filter := bson.M{"uuid": swcUuid, "CurrentIncrementOperationCollectionName": currentIncrementOperationCollectionName}
+	result, err := swcCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return ReturnWrapper{false, "Remove swc snapshots failed! Error:" + err.Error()}
+	}
+	if result.MatchedCount == 0 {
+		return ReturnWrapper{false, "Remove swc snapshots failed! The current increment operation list of swc " + swcUuid + " has changed"}
+	}
+
+	return ReturnWrapper{true, "Remove swc snapshots success!"}
+}
+
+func ListCollectionNames(database *mongo.Database, collectionNames *[]string) ReturnWrapper {
+	names, err := database.ListCollectionNames(context.TODO(), bson.M{})
+	if err != nil {
+		return ReturnWrapper{false, err.Error()}
+	}
+	*collectionNames = names
+
+	return ReturnWrapper{true, "List collection names of " + database.Name() + " success!"}
+}
+
+func DropCollection(database *mongo.Database, collectionName string) ReturnWrapper {
+	err := database.Collection(collectionName).Drop(context.TODO())
+	if err != nil {
+		return ReturnWrapper{false, err.Error()}
+	}
+	return ReturnWrapper{true, "Drop collection " + database.Name() + "." + collectionName + " successfully!"}
+}
+
+func CreateSwcAttachmentAno(swcUuid string, anoAttachment *dbmodel.SwcAttachmentAnoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	attachmentCollection := "Attachment_Ano_" + swcUuid
+	collection := databaseInfo.AttachmentDb.Collection(attachmentCollection)
+	_ = collection.Drop(context.TODO())
+	_, err := collection.InsertOne(context.TODO(), anoAttachment)
+	if err != nil {
+		return ReturnWrapper{false, err.Error()}
+	}
+
+	return ReturnWrapper{true, "Create Ano Attachment successfully!"}
+}
+
+func DeleteSwcAttachmentAno(swcUuid string, attachmentUuid string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	attachmentCollection := "Attachment_Ano_" + swcUuid
+	collection := databaseInfo.AttachmentDb.Collection(attachmentCollection)
+
+	result := collection.FindOneAndDelete(context.TODO(), bson.D{
+		{"uuid", attachmentUuid}})
+
+	_ = collection.Drop(context.Background())
+
+	if result.Err() != nil {
+		return ReturnWrapper{false, result.Err().Error()}
+	} else {
+		return ReturnWrapper{true, "Delete Ano Attachment successfully!"}
+	}
+}
+
+func UpdateSwcAttachmentAno(swcUuid string, attachmentUuid string, anoAttachment *dbmodel.SwcAttachmentAnoV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	attachmentCollection := "Attachment_Ano_" + swcUuid
+	collection := databaseInfo.AttachmentDb.Collection(attachmentCollection)
 
 	result := collection.FindOneAndUpdate(
 		context.TODO(),
@@ -1179,88 +1673,32 @@ func QuerySwcAttachmentApo(swcUuid string, apoAttachmentCollectionName string, a
 	return ReturnWrapper{true, "Query Apo Attachment Success"}
 }
 
-func RevertSwcNodeData(swcUuid string, swcSnapshotCollectionName string, incrementOperationCollectionName string, endTime time.Time, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
-	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
-	delFilter := bson.D{{"CreateTime", bson.D{{"$gt", primitive.NewDateTimeFromTime(endTime)}}}}
-	delres, err := collection.DeleteMany(context.TODO(), delFilter)
-	if err != nil {
-		return ReturnWrapper{false, err.Error()}
-	}
-	println(delres.DeletedCount)
-
-	swcCollection := databaseInfo.SwcDb.Collection(swcUuid)
-	err = swcCollection.Drop(context.TODO())
-	if err != nil {
-		return ReturnWrapper{false, err.Error()}
-	}
-
-	srcCollection := databaseInfo.SnapshotDb.Collection(swcSnapshotCollectionName)
-	dstCollection := databaseInfo.SwcDb.Collection(swcUuid)
-
-	cursor, err := srcCollection.Find(context.Background(), bson.D{{}})
-	if err != nil {
-		return ReturnWrapper{
-			Status:  false,
-			Message: err.Error(),
-		}
-	}
-
-	var results []interface{}
-	batchSize := 100000
-
-	for cursor.Next(context.Background()) {
-		var result bson.D
-		err := cursor.Decode(&result)
-		if err != nil {
-			return ReturnWrapper{
-				Status:  false,
-				Message: err.Error(),
+// ApplySwcIncrementOperationWithContext applies one recorded increment operation to the node collection of the swc.
+// The grouped operations of a batch are applied in order, stopping at the first failure.
+func ApplySwcIncrementOperationWithContext(ctx context.Context, swcUuid string, operation *dbmodel.SwcIncrementOperationV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	switch operation.IncrementOperation {
+	case IncrementOp_Create:
+		return CreateSwcDataWithContext(ctx, swcUuid, &operation.SwcData, databaseInfo)
+	case IncrementOp_Delete:
+		return DeleteSwcDataWithContext(ctx, swcUuid, operation.SwcData, databaseInfo)
+	case IncrementOp_Update:
+		return ModifySwcDataWithContext(ctx, swcUuid, &operation.SwcData, databaseInfo)
+	case IncrementOp_UpdateNParent:
+		result, _, _, _, _, _, _, _ := UpdateSwcNParentWithContext(ctx, swcUuid, &operation.NodeNParent, databaseInfo)
+		return result
+	case IncrementOp_ClearAll:
+		return ClearAllNodeWithContext(ctx, swcUuid, databaseInfo)
+	case IncrementOp_OverwriteAll:
+		return CreateSwcDataWithContext(ctx, swcUuid, &operation.SwcData, databaseInfo)
+	case IncrementOp_Batch:
+		for idx := range operation.GroupedOperations {
+			if result := ApplySwcIncrementOperationWithContext(ctx, swcUuid, &operation.GroupedOperations[idx], databaseInfo); !result.Status {
+				return result
 			}
 		}
-		results = append(results, result)
-
-		if len(results) >= batchSize {
-			_, err = dstCollection.InsertMany(context.Background(), results)
-			if err != nil {
-				return ReturnWrapper{
-					Status:  false,
-					Message: err.Error(),
-				}
-			}
-			results = results[:0] // 清空切片，准备下一批次
-		}
-	}
-
-	// 插入剩余的文档
-	if len(results) > 0 {
-		_, err = dstCollection.InsertMany(context.Background(), results)
-		if err != nil {
-			return ReturnWrapper{
-				Status:  false,
-				Message: err.Error(),
-			}
-		}
-	}
-	var operations dbmodel.SwcIncrementOperationListV1
-	QuerySwcIncrementOperation(incrementOperationCollectionName, &operations, GetDbInstance())
-	for _, operation := range operations {
-		switch operation.IncrementOperation {
-		case IncrementOp_Create:
-			CreateSwcData(swcUuid, &operation.SwcData, GetDbInstance())
-		case IncrementOp_Delete:
-			DeleteSwcData(swcUuid, operation.SwcData, GetDbInstance())
-		case IncrementOp_Update:
-			ModifySwcData(swcUuid, &operation.SwcData, GetDbInstance())
-		case IncrementOp_UpdateNParent:
-			UpdateSwcNParent(swcUuid, &operation.NodeNParent, GetDbInstance())
-		case IncrementOp_ClearAll:
-			ClearAllNode(swcUuid, GetDbInstance())
-		case IncrementOp_OverwriteAll:
-			CreateSwcData(swcUuid, &operation.SwcData, GetDbInstance())
-		}
+		return ReturnWrapper{true, "Apply batch operation successfully!"}
 	}
-
-	return ReturnWrapper{true, "Delete IncrementOperation after given time successfully!"}
+	return ReturnWrapper{false, "Unknown increment operation " + operation.IncrementOperation + "!"}
 }
 
 func GetNewUserIdAndIncrease(databaseInfo MongoDbDataBaseInfo) (ReturnWrapper, int32) {
@@ -1395,7 +1833,7 @@ func GetProjectsDefinedSomaSwc(projectUuids []string, databaseInfo MongoDbDataBa
 	var result []string
 
 	// 首先查询所有指定项目下的SWC
-	cursor, err := swcCollection.Find(context.TODO(), bson.M{"BelongingProjectUuid": bson.M{"$in": projectUuids}})
+	cursor, err := swcCollection.Find(context.TODO(), bson.M{"BelongingProjectUuid": bson.M{"$in": projectUuids}, "IsDeleted": bson.M{"$ne": true}})
 	if err != nil {
 		return ReturnWrapper{false, "查询项目SWC失败: " + err.Error()}, nil
 	}
@@ -1429,6 +1867,10 @@ func GetProjectsDefinedSomaSwc(projectUuids []string, databaseInfo MongoDbDataBa
 }
 
 func UpdateSwcNParent(swcUuid string, nodeNParent *[]dbmodel.NodeNParentV1, databaseInfo MongoDbDataBaseInfo) (ReturnWrapper, int, int, int, int, []string, []string, []string) {
+	return UpdateSwcNParentWithContext(context.TODO(), swcUuid, nodeNParent, databaseInfo)
+}
+
+func UpdateSwcNParentWithContext(ctx context.Context, swcUuid string, nodeNParent *[]dbmodel.NodeNParentV1, databaseInfo MongoDbDataBaseInfo) (ReturnWrapper, int, int, int, int, []string, []string, []string) {
 	// Get the collection for the given swcUuid
 	collection := databaseInfo.SwcDb.Collection(swcUuid)
 
@@ -1436,13 +1878,13 @@ func UpdateSwcNParent(swcUuid string, nodeNParent *[]dbmodel.NodeNParentV1, data
 	var updateNodes, notExistNodes, dbNotExistNodes []string
 
 	// Get all nodes in the collection
-	cursor, err := collection.Find(context.TODO(), bson.D{})
+	cursor, err := collection.Find(ctx, bson.D{})
 	if err != nil {
 		return ReturnWrapper{false, "Failed to retrieve nodes: " + err.Error()}, updateCount, noUpdateCount, incomingNotExistCount, dbNotExistCount, updateNodes, notExistNodes, dbNotExistNodes
 	}
 
 	var nodesInDb []dbmodel.SwcNodeDataV1
-	if err = cursor.All(context.TODO(), &nodesInDb); err != nil {
+	if err = cursor.All(ctx, &nodesInDb); err != nil {
 		return ReturnWrapper{false, "Failed to retrieve nodes: " + err.Error()}, updateCount, noUpdateCount, incomingNotExistCount, dbNotExistCount, updateNodes, notExistNodes, dbNotExistNodes
 	}
 
@@ -1491,7 +1933,7 @@ func UpdateSwcNParent(swcUuid string, nodeNParent *[]dbmodel.NodeNParentV1, data
 	}
 
 	// Execute the bulk operation
-	_, err = collection.BulkWrite(context.TODO(), writes)
+	_, err = collection.BulkWrite(ctx, writes)
 	if err != nil {
 		return ReturnWrapper{false, "Failed to update nodes: " + err.Error()}, updateCount, noUpdateCount, incomingNotExistCount, dbNotExistCount, updateNodes, notExistNodes, dbNotExistNodes
 	}
@@ -1507,11 +1949,357 @@ func UpdateSwcNParent(swcUuid string, nodeNParent *[]dbmodel.NodeNParentV1, data
 }
 
 func ClearAllNode(swcUuid string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	return ClearAllNodeWithContext(context.TODO(), swcUuid, databaseInfo)
+}
+
+func ClearAllNodeWithContext(ctx context.Context, swcUuid string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
 	// Get the collection for the given swcUuid
 	collection := databaseInfo.SwcDb.Collection(swcUuid)
-	err := collection.Drop(context.Background())
+	err := clearCollection(ctx, collection)
 	if err != nil {
 		return ReturnWrapper{false, err.Error()}
 	}
 	return ReturnWrapper{true, "Delete all nodes successfully!"}
 }
+
+func QuerySwcRevisionWithContext(ctx context.Context, swcUuid string, revision *int64, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	var swcMetaInfo dbmodel.SwcMetaInfoV1
+	opts := options.FindOne().SetProjection(bson.M{"Revision": 1})
+	if err := swcCollection.FindOne(ctx, bson.M{"uuid": swcUuid}, opts).Decode(&swcMetaInfo); err != nil {
+		return ReturnWrapper{false, "Query swc revision failed! Error:" + err.Error()}
+	}
+	*revision = swcMetaInfo.Revision
+
+	return ReturnWrapper{true, "Query swc revision success!"}
+}
+
+// IncreaseSwcRevisionWithContext advances the revision of the swc by one and returns the new revision. When
+// currentRevision is set the swc must still be at it, so two writers which read the same revision cannot both claim
+// the next one.
+func IncreaseSwcRevisionWithContext(ctx context.Context, swcUuid string, currentRevision *int64, newRevision *int64, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	filter := bson.M{"uuid": swcUuid}
+	if currentRevision != nil {
+		filter["Revision"] = *currentRevision
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"Revision": 1})
+
+	var swcMetaInfo dbmodel.SwcMetaInfoV1
+	err := swcCollection.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"Revision": 1}}, opts).Decode(&swcMetaInfo)
+	if errors.Is(err, mongo.ErrNoDocuments) && currentRevision != nil {
+		return ReturnWrapper{false, "Increase swc revision failed! Swc " + swcUuid + " is no longer at revision " + strconv.FormatInt(*currentRevision, 10)}
+	}
+	if err != nil {
+		return ReturnWrapper{false, "Increase swc revision failed! Error:" + err.Error()}
+	}
+	*newRevision = swcMetaInfo.Revision
+
+	return ReturnWrapper{true, "Increase swc revision success!"}
+}
+
+func QuerySwcDataByUuidWithContext(ctx context.Context, swcUuid string, nodeUuids []string, swcData *dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.SwcDb.Collection(swcUuid)
+
+	cursor, err := collection.Find(ctx, bson.M{"uuid": bson.M{"$in": nodeUuids}})
+	if err != nil {
+		return ReturnWrapper{false, "Query many node failed!"}
+	}
+
+	if err = cursor.All(ctx, swcData); err != nil {
+		return ReturnWrapper{false, "Query many node failed!"}
+	}
+
+	return ReturnWrapper{true, "Query many node Success"}
+}
+
+// QuerySwcMaxNWithContext finds the largest n of the swc, 0 when it has no node.
+func QuerySwcMaxNWithContext(ctx context.Context, swcUuid string, maxN *int32, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.SwcDb.Collection(swcUuid)
+
+	var swcNodeData dbmodel.SwcNodeDataV1
+	err := collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"SwcData.n": -1}).SetProjection(bson.M{"SwcData.n": 1})).Decode(&swcNodeData)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		*maxN = 0
+		return ReturnWrapper{true, "Swc " + swcUuid + " has no node"}
+	}
+	if err != nil {
+		return ReturnWrapper{false, "Query max n of swc " + swcUuid + " failed! Error:" + err.Error()}
+	}
+	*maxN = swcNodeData.SwcNodeInternalData.N
+
+	return ReturnWrapper{true, "Query max n success!"}
+}
+
+// SetSwcNodeVersionWithContext sets the version of the given nodes, or of every node of the swc when nodeUuids is nil.
+func SetSwcNodeVersionWithContext(ctx context.Context, swcUuid string, nodeUuids []string, version int64, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.SwcDb.Collection(swcUuid)
+
+	filter := bson.M{}
+	if nodeUuids != nil {
+		if len(nodeUuids) == 0 {
+			return ReturnWrapper{true, "No nodes to set version"}
+		}
+		filter = bson.M{"uuid": bson.M{"$in": nodeUuids}}
+	}
+
+	if _, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"Version": version}}); err != nil {
+		return ReturnWrapper{false, "Set swc node version failed! Error:" + err.Error()}
+	}
+
+	return ReturnWrapper{true, "Set swc node version success!"}
+}
+
+func QuerySwcUuidByIncrementOperationCollection(collectionName string, swcUuid *string, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	var swcCollection = databaseInfo.MetaInfoDb.Collection(SwcMetaInfoCollectionString)
+
+	filter := bson.M{"$or": bson.A{
+		bson.M{"CurrentIncrementOperationCollectionName": collectionName},
+		bson.M{"SwcIncrementOperationList.IncrementOperationCollectionName": collectionName},
+	}}
+	var swcMetaInfo dbmodel.SwcMetaInfoV1
+	opts := options.FindOne().SetProjection(bson.M{"uuid": 1})
+	if err := swcCollection.FindOne(context.TODO(), filter, opts).Decode(&swcMetaInfo); err != nil {
+		return ReturnWrapper{false, "Query swc of increment operation collection " + collectionName + " failed! Error:" + err.Error()}
+	}
+	*swcUuid = swcMetaInfo.Base.Uuid
+
+	return ReturnWrapper{true, "Query swc of increment operation collection success!"}
+}
+
+// WatchSwcIncrementOperation follows the increment operations inserted into any increment operation collection with a
+// change stream and calls handle for each of them, and drop for every increment operation collection dropped or
+// renamed away. Inserts into the staging collections of CopySwcIncrementOperation are skipped. It blocks until the
+// change stream fails or ctx is done. resumeToken is resumed from when it is set and updated after every handled
+// event, so the caller can pick up where it left.
+func WatchSwcIncrementOperation(ctx context.Context, resumeToken *bson.Raw, handle func(collectionName string, operation dbmodel.SwcIncrementOperationV1), drop func(collectionName string), databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	pipeline := mongo.Pipeline{bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+		bson.M{
+			"operationType": "insert",
+			"ns.coll":       bson.M{"$not": primitive.Regex{Pattern: "^" + SwcIncrementOperationCopyPrefix}},
+		},
+		bson.M{"operationType": bson.M{"$in": bson.A{"drop", "rename"}}},
+	}}}}}
+	opts := options.ChangeStream()
+	if *resumeToken != nil {
+		opts.SetResumeAfter(*resumeToken)
+	}
+
+	changeStream, err := databaseInfo.IncrementOperationDb.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return ReturnWrapper{false, "Watch increment operation failed! Error:" + err.Error()}
+	}
+	defer changeStream.Close(context.TODO())
+
+	for changeStream.Next(ctx) {
+		var event struct {
+			OperationType string `bson:"operationType"`
+			Ns            struct {
+				Coll string `bson:"coll"`
+			} `bson:"ns"`
+			FullDocument dbmodel.SwcIncrementOperationV1 `bson:"fullDocument"`
+		}
+		if err := changeStream.Decode(&event); err != nil {
+			logger.GetLogger().Println("Decode increment operation change failed! Error:" + err.Error())
+		} else if event.OperationType == "insert" {
+			handle(event.Ns.Coll, event.FullDocument)
+		} else {
+			drop(event.Ns.Coll)
+		}
+		*resumeToken = changeStream.ResumeToken()
+	}
+
+	if err := changeStream.Err(); err != nil {
+		return ReturnWrapper{false, "Watch increment operation failed! Error:" + err.Error()}
+	}
+	return ReturnWrapper{true, "Watch increment operation stopped!"}
+}
+
+func QuerySwcIncrementOperationAfterRevision(incrementOperationCollectionName string, revision int64, operations *dbmodel.SwcIncrementOperationListV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
+
+	opts := options.Find().SetSort(bson.D{{Key: "Revision", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(context.TODO(), bson.M{"Revision": bson.M{"$gt": revision}}, opts)
+	if err != nil {
+		return ReturnWrapper{false, "Query increment operation failed! Error:" + err.Error()}
+	}
+
+	if err = cursor.All(context.TODO(), operations); err != nil {
+		return ReturnWrapper{false, "Query increment operation failed! Error:" + err.Error()}
+	}
+
+	return ReturnWrapper{true, "Query increment operation success!"}
+}
+
+// QueryLastSwcIncrementOperationAtTime finds the last increment operation created at or before endTime, operations is
+// left empty when there is none.
+func QueryLastSwcIncrementOperationAtTime(incrementOperationCollectionName string, endTime time.Time, operations *dbmodel.SwcIncrementOperationListV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
+
+	opts := options.Find().SetSort(bson.D{{Key: "CreateTime", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(1)
+	cursor, err := collection.Find(context.TODO(), bson.M{"CreateTime": bson.M{"$lte": primitive.NewDateTimeFromTime(endTime)}}, opts)
+	if err != nil {
+		return ReturnWrapper{false, "Query increment operation failed! Error:" + err.Error()}
+	}
+
+	if err = cursor.All(context.TODO(), operations); err != nil {
+		return ReturnWrapper{false, "Query increment operation failed! Error:" + err.Error()}
+	}
+
+	return ReturnWrapper{true, "Query increment operation success!"}
+}
+
+func QuerySwcSnapshotNodesByUuid(snapshotName string, nodeUuids []string, swcData *dbmodel.SwcDataV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.SnapshotDb.Collection(snapshotName)
+
+	cursor, err := collection.Find(context.TODO(), bson.M{"uuid": bson.M{"$in": nodeUuids}})
+	if err != nil {
+		return ReturnWrapper{false, "Query snapshot node failed! Error:" + err.Error()}
+	}
+
+	if err = cursor.All(context.TODO(), swcData); err != nil {
+		return ReturnWrapper{false, "Query snapshot node failed! Error:" + err.Error()}
+	}
+
+	return ReturnWrapper{true, "Query snapshot node success!"}
+}
+
+// QuerySwcIncrementOperationTouchingNodes returns the increment operations which may change one of the nodes, in the
+// order they were made: the ones naming a node, directly or in a batch, and every ClearAll.
+func QuerySwcIncrementOperationTouchingNodes(incrementOperationCollectionName string, nodeUuids []string, operations *dbmodel.SwcIncrementOperationListV1, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.IncrementOperationDb.Collection(incrementOperationCollectionName)
+
+	inNodeUuids := bson.M{"$in": nodeUuids}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"SwcNodeData.uuid": inNodeUuids},
+		bson.M{"NodeNParent.uuid": inNodeUuids},
+		bson.M{"GroupedOperations.SwcNodeData.uuid": inNodeUuids},
+		bson.M{"GroupedOperations.NodeNParent.uuid": inNodeUuids},
+		bson.M{"IncrementOperation": IncrementOp_ClearAll},
+	}}
+	opts := options.Find().SetSort(bson.D{{Key: "Revision", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return ReturnWrapper{false, "Query increment operation failed! Error:" + err.Error()}
+	}
+
+	if err = cursor.All(context.TODO(), operations); err != nil {
+		return ReturnWrapper{false, "Query increment operation failed! Error:" + err.Error()}
+	}
+
+	return ReturnWrapper{true, "Query increment operation success!"}
+}
+
+// QuerySwcDataInBoundingBox queries the nodes of the swc inside the box of query. When query has a limit, at most
+// limit nodes are returned and truncated tells whether there were more.
+func QuerySwcDataInBoundingBox(swcUuid string, query SwcNodeBoundingBoxQuery, swcData *dbmodel.SwcDataV1, truncated *bool, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.SwcDb.Collection(swcUuid)
+	_ = EnsureSwcNodeSpatialIndex(collection)
+
+	filter := bson.D{
+		{"SwcData.x", bson.M{"$gte": query.MinX, "$lte": query.MaxX}},
+		{"SwcData.y", bson.M{"$gte": query.MinY, "$lte": query.MaxY}},
+		{"SwcData.z", bson.M{"$gte": query.MinZ, "$lte": query.MaxZ}},
+	}
+	if len(query.Types) != 0 {
+		filter = append(filter, bson.E{Key: "SwcData.type", Value: bson.M{"$in": query.Types}})
+	}
+
+	findOptions := options.Find()
+	if query.Limit > 0 {
+		// one more than asked for tells whether the result was cut off
+		findOptions.SetLimit(query.Limit + 1)
+	}
+	cursor, err := collection.Find(context.TODO(), filter, findOptions)
+	if err != nil {
+		return ReturnWrapper{false, "Query nodes in bounding box failed! Error:" + err.Error()}
+	}
+
+	if err = cursor.All(context.TODO(), swcData); err != nil {
+		return ReturnWrapper{false, "Query nodes in bounding box failed! Error:" + err.Error()}
+	}
+
+	*truncated = query.Limit > 0 && int64(len(*swcData)) > query.Limit
+	if *truncated {
+		*swcData = (*swcData)[:query.Limit]
+	}
+
+	return ReturnWrapper{true, "Query nodes in bounding box success!"}
+}
+
+// swcNodeBoundingBoxSummary is the output of swcDataBoundingBoxPipeline.
+type swcNodeBoundingBoxSummary struct {
+	MinX       float32 `bson:"MinX"`
+	MinY       float32 `bson:"MinY"`
+	MinZ       float32 `bson:"MinZ"`
+	MaxX       float32 `bson:"MaxX"`
+	MaxY       float32 `bson:"MaxY"`
+	MaxZ       float32 `bson:"MaxZ"`
+	NodeNumber int64   `bson:"NodeNumber"`
+}
+
+func swcDataBoundingBoxPipeline() mongo.Pipeline {
+	return mongo.Pipeline{
+		{{"$group", bson.D{
+			{"_id", nil},
+			{"MinX", bson.M{"$min": "$SwcData.x"}},
+			{"MinY", bson.M{"$min": "$SwcData.y"}},
+			{"MinZ", bson.M{"$min": "$SwcData.z"}},
+			{"MaxX", bson.M{"$max": "$SwcData.x"}},
+			{"MaxY", bson.M{"$max": "$SwcData.y"}},
+			{"MaxZ", bson.M{"$max": "$SwcData.z"}},
+			{"NodeNumber", bson.M{"$sum": 1}},
+		}}},
+	}
+}
+
+// QuerySwcDataBoundingBox computes the box around all nodes of the swc and the number of nodes. The box is left
+// unchanged when the swc has no nodes.
+func QuerySwcDataBoundingBox(swcUuid string, boundingBox *SwcNodeBoundingBox, nodeNumber *int64, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.SwcDb.Collection(swcUuid)
+
+	cursor, err := collection.Aggregate(context.TODO(), swcDataBoundingBoxPipeline())
+	if err != nil {
+		return ReturnWrapper{false, "Query swc bounding box failed! Error:" + err.Error()}
+	}
+
+	var summaries []swcNodeBoundingBoxSummary
+	if err = cursor.All(context.TODO(), &summaries); err != nil {
+		return ReturnWrapper{false, "Query swc bounding box failed! Error:" + err.Error()}
+	}
+
+	*nodeNumber = 0
+	if len(summaries) != 0 {
+		summary := summaries[0]
+		*boundingBox = SwcNodeBoundingBox{summary.MinX, summary.MinY, summary.MinZ, summary.MaxX, summary.MaxY, summary.MaxZ}
+		*nodeNumber = summary.NodeNumber
+	}
+
+	return ReturnWrapper{true, "Query swc bounding box success!"}
+}
+
+// QuerySwcNodeParentsIn returns which of the node numbers ns are the parent of at least one node of the swc.
+func QuerySwcNodeParentsIn(swcUuid string, ns []int32, parents *[]int32, databaseInfo MongoDbDataBaseInfo) ReturnWrapper {
+	collection := databaseInfo.SwcDb.Collection(swcUuid)
+
+	values, err := collection.Distinct(context.TODO(), "SwcData.parent", bson.M{"SwcData.parent": bson.M{"$in": ns}})
+	if err != nil {
+		return ReturnWrapper{false, "Query node parents failed! Error:" + err.Error()}
+	}
+
+	*parents = (*parents)[:0]
+	for _, value := range values {
+		switch parent := value.(type) {
+		case int32:
+			*parents = append(*parents, parent)
+		case int64:
+			*parents = append(*parents, int32(parent))
+		case float64:
+			*parents = append(*parents, int32(parent))
+		}
+	}
+
+	return ReturnWrapper{true, "Query node parents success!"}
+}
@@ -0,0 +1,45 @@
+package dal
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestSwcNodeBoundingBoxSummaryDecode feeds a document with the keys the $group stage outputs, holding the value types
+// the server returns, through the decoder used by QuerySwcDataBoundingBox.
+func TestSwcNodeBoundingBoxSummaryDecode(t *testing.T) {
+	values := map[string]interface{}{
+		"_id":        nil,
+		"MinX":       float64(-1.5),
+		"MinY":       float64(2),
+		"MinZ":       float64(3.25),
+		"MaxX":       float64(10),
+		"MaxY":       float64(20.5),
+		"MaxZ":       float64(30),
+		"NodeNumber": int32(42),
+	}
+
+	group := swcDataBoundingBoxPipeline()[0][0].Value.(bson.D)
+	var document bson.D
+	for _, field := range group {
+		value, ok := values[field.Key]
+		if !ok {
+			t.Fatalf("no test value for $group output %s", field.Key)
+		}
+		document = append(document, bson.E{Key: field.Key, Value: value})
+	}
+	raw, err := bson.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var summary swcNodeBoundingBoxSummary
+	if err = bson.Unmarshal(raw, &summary); err != nil {
+		t.Fatal(err)
+	}
+	want := swcNodeBoundingBoxSummary{MinX: -1.5, MinY: 2, MinZ: 3.25, MaxX: 10, MaxY: 20.5, MaxZ: 30, NodeNumber: 42}
+	if summary != want {
+		t.Errorf("decoded summary = %+v, want %+v", summary, want)
+	}
+}
@@ -0,0 +1,119 @@
+package dal
+
+import (
+	"DBMS/logger"
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var transactionSupportedOnce sync.Once
+var transactionSupported bool
+
+// IsTransactionSupported reports whether the connected deployment is a replica set or a sharded cluster. Standalone
+// servers do not support multi-document transactions. The answer is looked up once and cached.
+func IsTransactionSupported(databaseInfo MongoDbDataBaseInfo) bool {
+	transactionSupportedOnce.Do(func() {
+		var hello bson.M
+		err := databaseInfo.MetaInfoDb.Client().Database("admin").RunCommand(context.TODO(), bson.M{"hello": 1}).Decode(&hello)
+		if err != nil {
+			logger.GetLogger().Println("Check transaction support failed, writes will not use transactions! Error: " + err.Error())
+			return
+		}
+		_, isReplicaSet := hello["setName"]
+		isShardedCluster := hello["msg"] == "isdbgrid"
+		transactionSupported = isReplicaSet || isShardedCluster
+		logger.GetLogger().Println("MongoDB transaction supported:", transactionSupported)
+	})
+	return transactionSupported
+}
+
+// RunInTransaction runs fn inside a multi-document transaction, so every write fn makes through ctx is committed
+// together or not at all. The transaction is aborted when fn returns a failed ReturnWrapper, and the whole of fn is
+// retried by the driver on transient errors, so fn must not keep side effects outside the database. On a standalone
+// server fn runs without a transaction.
+func RunInTransaction(databaseInfo MongoDbDataBaseInfo, fn func(ctx context.Context) ReturnWrapper) ReturnWrapper {
+	var callbacks *afterCommitCallbacks
+	if !IsTransactionSupported(databaseInfo) {
+		callbacks = &afterCommitCallbacks{}
+		result := fn(context.WithValue(context.TODO(), afterCommitKey{}, callbacks))
+		if result.Status {
+			callbacks.run()
+		}
+		return result
+	}
+
+	session, err := databaseInfo.MetaInfoDb.Client().StartSession()
+	if err != nil {
+		return ReturnWrapper{false, "Start session failed! Error:" + err.Error()}
+	}
+	defer session.EndSession(context.TODO())
+
+	var result ReturnWrapper
+	_, err = session.WithTransaction(context.TODO(), func(sessionContext mongo.SessionContext) (interface{}, error) {
+		// a retried attempt starts with no callbacks, the ones of the aborted attempt must not run
+		callbacks = &afterCommitCallbacks{}
+		result = fn(context.WithValue(sessionContext, afterCommitKey{}, callbacks))
+		if !result.Status {
+			return nil, errors.New(result.Message)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		if result.Status {
+			return ReturnWrapper{false, "Commit transaction failed! Error:" + err.Error()}
+		}
+		return result
+	}
+
+	callbacks.run()
+	return result
+}
+
+type afterCommitKey struct{}
+
+type afterCommitCallbacks struct {
+	callbacks []func()
+}
+
+func (afterCommitCallbacks *afterCommitCallbacks) run() {
+	for _, callback := range afterCommitCallbacks.callbacks {
+		callback()
+	}
+}
+
+// RunAfterCommit defers fn until the RunInTransaction call ctx comes from has committed, fn is dropped when it fails.
+// Outside of RunInTransaction fn runs right away.
+func RunAfterCommit(ctx context.Context, fn func()) {
+	if callbacks, ok := ctx.Value(afterCommitKey{}).(*afterCommitCallbacks); ok {
+		callbacks.callbacks = append(callbacks.callbacks, fn)
+		return
+	}
+	fn()
+}
+
+func isInTransaction(ctx context.Context) bool {
+	return mongo.SessionFromContext(ctx) != nil
+}
+
+// ensureUniqueUUIDIndex is EnsureUniqueUUIDIndex for writes which may run in a transaction. Index builds wait for the
+// collection locks a transaction holds, so inside a transaction the index is left to the next write outside of one.
+func ensureUniqueUUIDIndex(ctx context.Context, collection *mongo.Collection) error {
+	if isInTransaction(ctx) {
+		return nil
+	}
+	return EnsureUniqueUUIDIndex(collection)
+}
+
+// clearCollection removes every document of the collection. Collections cannot be dropped inside a transaction, so
+// there the documents are deleted one by one instead.
+func clearCollection(ctx context.Context, collection *mongo.Collection) error {
+	if isInTransaction(ctx) {
+		_, err := collection.DeleteMany(ctx, bson.M{})
+		return err
+	}
+	return collection.Drop(ctx)
+}
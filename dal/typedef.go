@@ -1,6 +1,11 @@
 package dal
 
-import "go.mongodb.org/mongo-driver/mongo"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
 type ReturnWrapper struct {
 	Status  bool
@@ -82,4 +87,39 @@ const (
 	IncrementOp_UpdateNParent string = "UpdateNParent"
 	IncrementOp_ClearAll      string = "ClearAll"
 	IncrementOp_OverwriteAll  string = "OverwriteAll"
+	IncrementOp_Batch         string = "Batch"
 )
+
+// SwcIncrementOperationFilter selects increment operations, empty fields match any value. StartTime is inclusive and
+// EndTime exclusive.
+type SwcIncrementOperationFilter struct {
+	CreatorUuid         string
+	ClientAddress       string
+	DeviceType          string
+	ApiVersion          string
+	Creator             string
+	IncrementOperations []string
+	StartTime           time.Time
+	EndTime             time.Time
+}
+
+// SwcIncrementOperationPosition is the position of an increment operation in the order increment operations are
+// returned in, by revision and then by insertion.
+type SwcIncrementOperationPosition struct {
+	Revision int64
+	Id       primitive.ObjectID
+}
+
+// SwcNodeBoundingBox is an axis aligned box around nodes, bounds included.
+type SwcNodeBoundingBox struct {
+	MinX, MinY, MinZ float32
+	MaxX, MaxY, MaxZ float32
+}
+
+// SwcNodeBoundingBoxQuery selects the nodes inside a box. Empty Types match every node type and a Limit of 0 returns
+// every node.
+type SwcNodeBoundingBoxQuery struct {
+	SwcNodeBoundingBox
+	Types []int32
+	Limit int64
+}
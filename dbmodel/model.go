@@ -110,6 +110,12 @@ type ProjectMetaInfoV1 struct {
 	SwcList          []string             `bson:"SwcList"`
 	WorkMode         string               `bson:"WorkMode"`
 	Permission       PermissionMetaInfoV1 `bson:"Permission"`
+
+	EnforceTopologyValidation bool `bson:"EnforceTopologyValidation"`
+
+	IsDeleted  bool      `bson:"IsDeleted"`
+	DeletedBy  string    `bson:"DeletedBy"`
+	DeleteTime time.Time `bson:"DeleteTime"`
 }
 
 type SwcSnapshotMetaInfoV1 struct {
@@ -117,6 +123,8 @@ type SwcSnapshotMetaInfoV1 struct {
 	SwcSnapshotCollectionName string       `bson:"SwcSnapshotCollectionName"`
 	CreateTime                time.Time    `bson:"CreateTime"`
 	Creator                   string       `bson:"Creator"`
+	Label                     string       `bson:"Label"`
+	Protected                 bool         `bson:"Protected"`
 }
 
 type SwcIncrementOperationMetaInfoV1 struct {
@@ -124,6 +132,13 @@ type SwcIncrementOperationMetaInfoV1 struct {
 	StartSnapshot                    string       `bson:"StartSnapshot"`
 	CreateTime                       time.Time    `bson:"CreateTime"`
 	IncrementOperationCollectionName string       `bson:"IncrementOperationCollectionName"`
+	StartRevision                    int64        `bson:"StartRevision"`
+}
+
+// SwcHistoryRangeV1 is a time range whose increment operations were removed, EndTime is exclusive.
+type SwcHistoryRangeV1 struct {
+	StartTime time.Time `bson:"StartTime"`
+	EndTime   time.Time `bson:"EndTime"`
 }
 
 type NodeNParentV1 struct {
@@ -133,11 +148,20 @@ type NodeNParentV1 struct {
 }
 
 type SwcIncrementOperationV1 struct {
-	Base               MetaInfoBase    `bson:"Base,inline"`
-	CreateTime         time.Time       `bson:"CreateTime"`
-	IncrementOperation string          `bson:"IncrementOperation"`
-	SwcData            SwcDataV1       `bson:"SwcNodeData"`
-	NodeNParent        []NodeNParentV1 `bson:"NodeNParent"`
+	Base               MetaInfoBase              `bson:"Base,inline"`
+	CreateTime         time.Time                 `bson:"CreateTime"`
+	IncrementOperation string                    `bson:"IncrementOperation"`
+	SwcData            SwcDataV1                 `bson:"SwcNodeData"`
+	NodeNParent        []NodeNParentV1           `bson:"NodeNParent"`
+	GroupedOperations  []SwcIncrementOperationV1 `bson:"GroupedOperations"`
+	Revision           int64                     `bson:"Revision"`
+	Creator            string                    `bson:"Creator"`
+	CreatorUuid        string                    `bson:"CreatorUuid"`
+	ClientAddress      string                    `bson:"ClientAddress"`
+	DeviceType         string                    `bson:"DeviceType"`
+	ApiVersion         string                    `bson:"ApiVersion"`
+	UndoOperationUuid  string                    `bson:"UndoOperationUuid"`
+	RedoOperationUuid  string                    `bson:"RedoOperationUuid"`
 }
 
 type SwcIncrementOperationListV1 = []SwcIncrementOperationV1
@@ -153,11 +177,23 @@ type SwcMetaInfoV1 struct {
 	SwcSnapshotList                         []SwcSnapshotMetaInfoV1           `bson:"SwcSnapshotList"`
 	SwcIncrementOperationList               []SwcIncrementOperationMetaInfoV1 `bson:"SwcIncrementOperationList"`
 	CurrentIncrementOperationCollectionName string                            `bson:"CurrentIncrementOperationCollectionName"`
+	SwcRemovedHistoryList                   []SwcHistoryRangeV1               `bson:"SwcRemovedHistoryList"`
 	SwcAttachmentAnoMetaInfo                SwcAttachmentAnoMetaInfoV1        `bson:"SwcAttachmentAno"`
 	SwcAttachmentApoMetaInfo                SwcAttachmentApoMetaInfoV1        `bson:"SwcAttachmentApo"`
 	SwcAttachmentSwcUuid                    string                            `bson:"SwcAttachmentSwcUuid"`
 	Permission                              PermissionMetaInfoV1              `bson:"Permission"`
 	BelongingProjectUuid                    string                            `bson:"BelongingProjectUuid"`
+
+	EnforceTopologyValidation bool `bson:"EnforceTopologyValidation"`
+
+	SourceSwcUuid string    `bson:"SourceSwcUuid"`
+	SourceSwcTime time.Time `bson:"SourceSwcTime"`
+
+	IsDeleted  bool      `bson:"IsDeleted"`
+	DeletedBy  string    `bson:"DeletedBy"`
+	DeleteTime time.Time `bson:"DeleteTime"`
+
+	Revision int64 `bson:"Revision"`
 }
 
 type SwcNodeInternalDataV1 struct {
@@ -183,6 +219,7 @@ type SwcNodeDataV1 struct {
 	LastModifiedTime    time.Time             `bson:"LastModifiedTime"`
 	CheckerUserUuid     string                `bson:"CheckerUserUuid"`
 	DeviceType          string                `bson:"DeviceType"`
+	Version             int64                 `bson:"Version"`
 }
 
 type SwcDataV1 = []SwcNodeDataV1
@@ -5,4 +5,11 @@ const (
 	ErrorUserTokenVerifyFailed = "ErrorUserTokenVerifyFailed"
 	ErrorCannotFindUser        = "ErrorCannotFindUser"
 	ErrorUserPasswordIncorrect = "ErrorUserPasswordIncorrect"
+
+	ErrorSwcTopologyValidationFailed = "ErrorSwcTopologyValidationFailed"
+	ErrorSwcEditBatchInvalid         = "ErrorSwcEditBatchInvalid"
+	ErrorSwcRevisionConflict         = "ErrorSwcRevisionConflict"
+	ErrorSwcUndoConflict             = "ErrorSwcUndoConflict"
+	ErrorSwcNothingToUndo            = "ErrorSwcNothingToUndo"
+	ErrorSwcHistoryNotAvailable      = "ErrorSwcHistoryNotAvailable"
 )